@@ -0,0 +1,256 @@
+// File Guide for dev/ai agents:
+// Purpose: Execute `thoth lua test` cases through the real Lua stages and compare results with expectations.
+// Responsibilities:
+// - Load each suite's config once via validate-config to obtain Lua code and sandbox settings.
+// - Build a one-case envelope, run the hook's stage, and extract the hook output or error.
+// - Diff actual against expected output with the diff-meta engine and render text or JSON reports.
+// Architecture notes:
+// - Cases go through stage.Run rather than a bespoke evaluator so wrapping, globals, thoth.* helpers, and sandbox limits match production exactly.
+// - Values are normalized through JSON on both sides so Lua numbers and suite numbers compare by value, not Go type.
+package lua
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/flarebyte/thoth-ostraca/internal/stage"
+)
+
+type caseResult struct {
+	Suite   string             `json:"suite"`
+	Name    string             `json:"name"`
+	Hook    string             `json:"hook"`
+	Passed  bool               `json:"passed"`
+	Error   string             `json:"error,omitempty"`
+	Failure string             `json:"failure,omitempty"`
+	Changes []stage.DiffChange `json:"changes,omitempty"`
+}
+
+type testReport struct {
+	Cases  []caseResult `json:"cases"`
+	Passed int          `json:"passed"`
+	Failed int          `json:"failed"`
+}
+
+func runSuites(ctx context.Context, paths []string, defaultConfig string) (testReport, error) {
+	report := testReport{Cases: []caseResult{}}
+	configs := map[string]*stage.Meta{}
+	for _, p := range paths {
+		s, err := loadSuite(p)
+		if err != nil {
+			return testReport{}, err
+		}
+		cfgPath := suiteConfigPath(s, defaultConfig)
+		base, ok := configs[cfgPath]
+		if !ok {
+			base, err = loadConfigMeta(ctx, cfgPath)
+			if err != nil {
+				return testReport{}, err
+			}
+			configs[cfgPath] = base
+		}
+		for _, tc := range s.Cases {
+			res := runCase(ctx, base, tc)
+			res.Suite = p
+			if res.Passed {
+				report.Passed++
+			} else {
+				report.Failed++
+			}
+			report.Cases = append(report.Cases, res)
+		}
+	}
+	return report, nil
+}
+
+func loadConfigMeta(ctx context.Context, cfgPath string) (*stage.Meta, error) {
+	if cfgPath == "" {
+		return &stage.Meta{}, nil
+	}
+	in := stage.Envelope{Records: []stage.Record{}, Meta: &stage.Meta{ConfigPath: cfgPath}}
+	out, err := stage.Run(ctx, "validate-config", in, stage.Deps{Stderr: os.Stderr})
+	if err != nil {
+		return nil, err
+	}
+	return out.Meta, nil
+}
+
+func runCase(ctx context.Context, base *stage.Meta, tc testCase) caseResult {
+	res := caseResult{Name: tc.Name, Hook: tc.Hook}
+	in, err := caseEnvelope(base, tc)
+	if err != nil {
+		res.Failure = err.Error()
+		return res
+	}
+	actual, errMsg := runHook(ctx, tc.Hook, in)
+	res.Error = errMsg
+	switch {
+	case tc.ExpectError != "" && errMsg == "":
+		res.Failure = fmt.Sprintf("expected error containing %q, got none", tc.ExpectError)
+	case tc.ExpectError != "" && !strings.Contains(errMsg, tc.ExpectError):
+		res.Failure = fmt.Sprintf("expected error containing %q", tc.ExpectError)
+	case tc.ExpectError == "" && errMsg != "":
+		res.Failure = "unexpected error"
+	case tc.ExpectError == "":
+		var expected any
+		if err := json.Unmarshal(tc.Expect, &expected); err != nil {
+			res.Failure = "invalid expect: " + err.Error()
+			return res
+		}
+		actual, err = normalizeJSON(actual)
+		if err != nil {
+			res.Failure = "invalid output: " + err.Error()
+			return res
+		}
+		res.Changes = stage.DiffValues(expected, actual)
+		if len(res.Changes) > 0 {
+			res.Failure = "output mismatch"
+		}
+	}
+	res.Passed = res.Failure == ""
+	return res
+}
+
+func caseEnvelope(base *stage.Meta, tc testCase) (stage.Envelope, error) {
+	meta := *base
+	lm := stage.LuaMeta{}
+	if base.Lua != nil {
+		lm = *base.Lua
+	}
+	if tc.Inline != "" {
+		switch tc.Hook {
+		case "filter":
+			lm.FilterInline = tc.Inline
+		case "map":
+			lm.MapInline = tc.Inline
+		case "postMap":
+			lm.PostMapInline = tc.Inline
+		case "reduce":
+			lm.ReduceInline = tc.Inline
+			lm.Reducers = nil
+		}
+	}
+	meta.Lua = &lm
+	meta.Reduced = nil
+
+	var raws []json.RawMessage
+	if tc.Hook == "reduce" {
+		raws = tc.Records
+	} else {
+		raw := tc.Input
+		if len(raw) == 0 {
+			raw = json.RawMessage(`{}`)
+		}
+		raws = []json.RawMessage{raw}
+	}
+	records := make([]stage.Record, 0, len(raws))
+	for i, raw := range raws {
+		var rec stage.Record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			return stage.Envelope{}, fmt.Errorf("invalid record %d: %v", i, err)
+		}
+		records = append(records, rec)
+	}
+	return stage.Envelope{Records: records, Meta: &meta}, nil
+}
+
+// runHook runs the hook's stage and returns the hook output or the first
+// error the stage reported.
+func runHook(ctx context.Context, hook string, in stage.Envelope) (any, string) {
+	out, err := stage.Run(ctx, hookStages[hook], in, stage.Deps{Stderr: os.Stderr})
+	if err != nil {
+		return nil, err.Error()
+	}
+	if len(out.Errors) > 0 {
+		return nil, out.Errors[0].Message
+	}
+	for _, rec := range out.Records {
+		if rec.Error != nil {
+			return nil, rec.Error.Message
+		}
+	}
+	switch hook {
+	case "filter":
+		return len(out.Records) == 1, ""
+	case "reduce":
+		return out.Meta.Reduced, ""
+	}
+	if len(out.Records) != 1 {
+		return nil, fmt.Sprintf("expected 1 output record, got %d", len(out.Records))
+	}
+	if hook == "map" {
+		return out.Records[0].Mapped, ""
+	}
+	return out.Records[0].Post, ""
+}
+
+func normalizeJSON(v any) (any, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func writeReport(w io.Writer, report testReport, asJSON bool) error {
+	if asJSON {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	}
+	var sb strings.Builder
+	for _, c := range report.Cases {
+		status := "PASS"
+		if !c.Passed {
+			status = "FAIL"
+		}
+		fmt.Fprintf(&sb, "%s %s: %s [%s]\n", status, c.Suite, c.Name, c.Hook)
+		if c.Passed {
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s\n", c.Failure)
+		if c.Error != "" {
+			fmt.Fprintf(&sb, "  error: %s\n", c.Error)
+		}
+		for _, ch := range c.Changes {
+			fmt.Fprintf(&sb, "  %s\n", formatChange(ch))
+		}
+	}
+	fmt.Fprintf(&sb, "%d passed, %d failed\n", report.Passed, report.Failed)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func formatChange(ch stage.DiffChange) string {
+	path := ch.Path
+	if path == "" {
+		path = "(root)"
+	}
+	switch ch.Kind {
+	case "added":
+		return fmt.Sprintf("%s: unexpected %s", path, compactJSON(ch.NewValue))
+	case "removed":
+		return fmt.Sprintf("%s: missing, expected %s", path, compactJSON(ch.OldValue))
+	default:
+		return fmt.Sprintf("%s: expected %s, got %s", path, compactJSON(ch.OldValue), compactJSON(ch.NewValue))
+	}
+}
+
+func compactJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
@@ -0,0 +1,131 @@
+package lua
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const harnessConfig = `{
+  configVersion: "1"
+  action: "input-pipeline"
+  discovery: { root: "." }
+  filter: { inline: "return string.sub(locator, -3) == \".go\"" }
+  map: { inline: "return { locator = locator, owner = meta and meta.owner }" }
+  postMap: { inline: "return { exit = shell and shell.exitCode, out = shell and shell.stdout }" }
+  reduce: { inline: "return (acc or 0) + 1" }
+}
+`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return p
+}
+
+func TestRunSuites_PassAndFailWithDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "pipeline.cue", harnessConfig)
+	suitePath := writeFile(t, dir, "hooks.luatest.yaml", `config: pipeline.cue
+cases:
+  - name: keeps go files
+    hook: filter
+    input: { locator: a.go }
+    expect: true
+  - name: drops other files
+    hook: filter
+    input: { locator: a.md }
+    expect: false
+  - name: maps owner
+    hook: map
+    input: { locator: a.go, meta: { owner: alice } }
+    expect: { locator: a.go, owner: bob }
+  - name: post sees shell
+    hook: postMap
+    input: { locator: a.go, shell: { exitCode: 0, stdout: "ok" } }
+    expect: { exit: 0, out: ok }
+  - name: counts records
+    hook: reduce
+    records: [{ locator: a.go }, { locator: b.go }]
+    expect: 2
+  - name: inline error
+    hook: map
+    inline: "error('boom')"
+    expectError: boom
+`)
+	report, err := runSuites(context.Background(), []string{suitePath}, "")
+	if err != nil {
+		t.Fatalf("runSuites: %v", err)
+	}
+	if report.Passed != 5 || report.Failed != 1 {
+		t.Fatalf("passed=%d failed=%d: %+v", report.Passed, report.Failed, report.Cases)
+	}
+	failed := report.Cases[2]
+	if failed.Passed || len(failed.Changes) != 1 || failed.Changes[0].Path != "owner" {
+		t.Fatalf("unexpected failure detail: %+v", failed)
+	}
+	var buf bytes.Buffer
+	if err := writeReport(&buf, report, false); err != nil {
+		t.Fatalf("writeReport: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"FAIL " + suitePath + ": maps owner [map]",
+		`owner: expected "bob", got "alice"`,
+		"5 passed, 1 failed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("report missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRunSuites_CUESuiteWithoutConfig(t *testing.T) {
+	dir := t.TempDir()
+	suitePath := writeFile(t, dir, "hooks.luatest.cue", `{
+  hook: "map"
+  cases: [{
+    name: "doubles"
+    inline: "return { n = meta.n * 2 }"
+    input: { locator: "x", meta: { n: 21 } }
+    expect: { n: 42 }
+  }, {
+    name: "unexpected error"
+    inline: "error('nope')"
+    expect: {}
+  }]
+}
+`)
+	report, err := runSuites(context.Background(), []string{suitePath}, "")
+	if err != nil {
+		t.Fatalf("runSuites: %v", err)
+	}
+	if report.Passed != 1 || report.Failed != 1 {
+		t.Fatalf("passed=%d failed=%d: %+v", report.Passed, report.Failed, report.Cases)
+	}
+	if !strings.Contains(report.Cases[1].Error, "nope") {
+		t.Fatalf("expected error to be reported, got %+v", report.Cases[1])
+	}
+}
+
+func TestLoadSuite_RejectsInvalidCases(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"unknown hook":   "cases: [{ hook: shell, expect: 1 }]",
+		"missing expect": "cases: [{ hook: map }]",
+		"records on map": "cases: [{ hook: map, records: [{}], expect: 1 }]",
+		"unknown field":  "cases: [{ hook: map, expected: 1 }]",
+	}
+	for name, content := range cases {
+		p := writeFile(t, dir, "s.yaml", content)
+		if _, err := loadSuite(p); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
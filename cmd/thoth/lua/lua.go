@@ -0,0 +1,60 @@
+// File Guide for dev/ai agents:
+// Purpose: Define the `thoth lua` command group and its `test` subcommand for unit-testing pipeline Lua hooks.
+// Responsibilities:
+// - Define `thoth lua test` flags for a default config and JSON reporting.
+// - Run every suite file given on the command line and print a pass/fail report.
+// - Return a non-zero exit when any case fails.
+// Architecture notes:
+// - Suite loading (suite.go) and case execution (harness.go) are package functions so tests can drive them without Cobra.
+// - Cases run through the registered lua-* stage runners so tests exercise the same sandbox, globals, and error shaping as `thoth run`.
+package lua
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagConfig string
+	flagJSON   bool
+)
+
+// Cmd implements `thoth lua`.
+var Cmd = &cobra.Command{
+	Use:           "lua",
+	Short:         "Work with pipeline Lua scripts",
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return cmd.Help()
+	},
+}
+
+var testCmd = &cobra.Command{
+	Use:           "test <suite-file>...",
+	Short:         "Run Lua hook test suites (YAML or CUE)",
+	Args:          cobra.MinimumNArgs(1),
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := runSuites(context.Background(), args, flagConfig)
+		if err != nil {
+			return err
+		}
+		if err := writeReport(cmd.OutOrStdout(), report, flagJSON); err != nil {
+			return err
+		}
+		if report.Failed > 0 {
+			return fmt.Errorf("lua test: %d of %d cases failed", report.Failed, report.Failed+report.Passed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	testCmd.Flags().StringVarP(&flagConfig, "config", "c", "", "Default config file (.cue) for suites without a config field")
+	testCmd.Flags().BoolVar(&flagJSON, "json", false, "Write the report as JSON")
+	Cmd.AddCommand(testCmd)
+}
@@ -0,0 +1,128 @@
+// File Guide for dev/ai agents:
+// Purpose: Load `thoth lua test` suite files written in YAML, JSON, or CUE into a typed case list.
+// Responsibilities:
+// - Decode suite files by extension into one generic value.
+// - Convert that value into suite and case structs, keeping expect presence distinct from an expected null.
+// - Validate hook names and required case fields with file-scoped error messages.
+// Architecture notes:
+// - All formats are normalized through JSON so CUE and YAML suites share one struct mapping and one set of numeric types.
+// - Input records use the stage.Record JSON shape, the same shape `thoth diagnose` dumps, so real envelopes can be pasted into cases.
+package lua
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	"gopkg.in/yaml.v3"
+)
+
+// hookStages maps suite hook names to the stage that runs them.
+var hookStages = map[string]string{
+	"filter":  "lua-filter",
+	"map":     "lua-map",
+	"postMap": "lua-postmap",
+	"reduce":  "lua-reduce",
+}
+
+type suite struct {
+	Path   string     `json:"-"`
+	Config string     `json:"config"`
+	Hook   string     `json:"hook"`
+	Cases  []testCase `json:"cases"`
+}
+
+type testCase struct {
+	Name        string            `json:"name"`
+	Hook        string            `json:"hook"`
+	Inline      string            `json:"inline"`
+	Input       json.RawMessage   `json:"input"`
+	Records     []json.RawMessage `json:"records"`
+	Expect      json.RawMessage   `json:"expect"`
+	ExpectError string            `json:"expectError"`
+}
+
+func loadSuite(path string) (suite, error) {
+	raw, err := decodeSuiteFile(path)
+	if err != nil {
+		return suite{}, err
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return suite{}, fmt.Errorf("invalid suite %s: %v", path, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	var s suite
+	if err := dec.Decode(&s); err != nil {
+		return suite{}, fmt.Errorf("invalid suite %s: %v", path, err)
+	}
+	s.Path = path
+	if len(s.Cases) == 0 {
+		return suite{}, fmt.Errorf("invalid suite %s: no cases", path)
+	}
+	for i := range s.Cases {
+		tc := &s.Cases[i]
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("case %d", i+1)
+		}
+		if tc.Hook == "" {
+			tc.Hook = s.Hook
+		}
+		if _, ok := hookStages[tc.Hook]; !ok {
+			return suite{}, fmt.Errorf("invalid suite %s: %s: hook must be one of filter, map, postMap, reduce", path, tc.Name)
+		}
+		if len(tc.Expect) == 0 && tc.ExpectError == "" {
+			return suite{}, fmt.Errorf("invalid suite %s: %s: missing expect or expectError", path, tc.Name)
+		}
+		if tc.Hook == "reduce" && len(tc.Input) > 0 {
+			return suite{}, fmt.Errorf("invalid suite %s: %s: reduce cases use records, not input", path, tc.Name)
+		}
+		if tc.Hook != "reduce" && len(tc.Records) > 0 {
+			return suite{}, fmt.Errorf("invalid suite %s: %s: records is only supported for reduce", path, tc.Name)
+		}
+	}
+	return s, nil
+}
+
+func decodeSuiteFile(path string) (any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cue":
+		v := cuecontext.New().CompileBytes(data, cue.Filename(path))
+		if err := v.Err(); err != nil {
+			return nil, fmt.Errorf("invalid suite %s: %v", path, err)
+		}
+		if err := v.Decode(&out); err != nil {
+			return nil, fmt.Errorf("invalid suite %s: %v", path, err)
+		}
+	case ".yaml", ".yml", ".json":
+		if err := yaml.Unmarshal(data, &out); err != nil {
+			return nil, fmt.Errorf("invalid suite %s: %v", path, err)
+		}
+	default:
+		return nil, fmt.Errorf("invalid suite %s: expected .yaml, .yml, .json, or .cue", path)
+	}
+	return out, nil
+}
+
+// suiteConfigPath resolves the suite config relative to the suite file,
+// falling back to the command-line default.
+func suiteConfigPath(s suite, fallback string) string {
+	if s.Config == "" {
+		return fallback
+	}
+	if filepath.IsAbs(s.Config) {
+		return s.Config
+	}
+	return filepath.Join(filepath.Dir(s.Path), s.Config)
+}
@@ -1,6 +1,7 @@
 // File Guide for dev/ai agents:
 // Purpose: Provide the real process entrypoint that executes the root command and normalizes top-level CLI exit behavior.
 // Responsibilities:
+// - Hand the process to the shell.limits rlimit shim when thoth was re-executed as one.
 // - Invoke the root command with process arguments.
 // - Render one short single-line error to stderr on failure.
 // - Exit with the mapped code when commands return an exitCoder.
@@ -14,6 +15,7 @@ import (
 	"strings"
 
 	"github.com/flarebyte/thoth-ostraca/cmd/thoth/root"
+	"github.com/flarebyte/thoth-ostraca/internal/stage"
 )
 
 type exitCoder interface {
@@ -21,6 +23,7 @@ type exitCoder interface {
 }
 
 func main() {
+	stage.MaybeRunRlimitShim()
 	if err := root.Execute(os.Args[1:]); err != nil {
 		// Print a short, single-line error to stderr on failures.
 		// Do not print usage or stack traces.
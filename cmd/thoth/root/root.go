@@ -2,7 +2,7 @@
 // Purpose: Define the root Cobra command and wire the top-level thoth subcommands together.
 // Responsibilities:
 // - Build the root command with its default help behavior.
-// - Register the diagnose, run, and version subcommands.
+// - Register the diagnose, lua, run, and version subcommands.
 // - Execute the configured root command with caller-provided args.
 // Architecture notes:
 // - The root command is intentionally thin and declarative so most behavior remains inside subcommand packages.
@@ -11,6 +11,7 @@ package root
 
 import (
 	"github.com/flarebyte/thoth-ostraca/cmd/thoth/diagnose"
+	"github.com/flarebyte/thoth-ostraca/cmd/thoth/lua"
 	"github.com/flarebyte/thoth-ostraca/cmd/thoth/run"
 	"github.com/flarebyte/thoth-ostraca/cmd/thoth/version"
 	"github.com/spf13/cobra"
@@ -33,6 +34,7 @@ func NewRootCmd() *cobra.Command {
 	cmd.AddCommand(version.VersionCmd)
 	cmd.AddCommand(run.Cmd)
 	cmd.AddCommand(diagnose.Cmd)
+	cmd.AddCommand(lua.Cmd)
 
 	return cmd
 }
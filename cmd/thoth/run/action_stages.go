@@ -53,16 +53,26 @@ func PreparedActionStages(action string, meta *stage.Meta) ([]string, error) {
 				"merge-meta",
 				"write-updated-meta-files",
 			)
+			if rollupEnabled(meta) {
+				stages = append(stages, "rollup")
+			}
 		}
 		stages = append(stages, "write-output")
 		return stages, nil
 	case "validate":
-		return []string{
+		stages := []string{
 			"discover-meta-files",
 			"parse-validate-yaml",
 			"validate-locators",
-			"write-output",
-		}, nil
+		}
+		if referencesEnabled(meta) {
+			stages = append(stages, "validate-references")
+		}
+		if rulesEnabled(meta) {
+			stages = append(stages, "validate-rules")
+		}
+		stages = append(stages, "write-output")
+		return stages, nil
 	case "create-meta":
 		stages := []string{"discover-input-files"}
 		if fileInfoEnabled(meta) {
@@ -100,9 +110,14 @@ func PreparedActionStages(action string, meta *stage.Meta) ([]string, error) {
 			"discover-meta-files",
 			"parse-validate-yaml",
 			"validate-locators",
-			"compute-meta-diff",
-			"write-output",
 		)
+		if referencesEnabled(meta) {
+			stages = append(stages, "validate-references")
+		}
+		if rulesEnabled(meta) {
+			stages = append(stages, "validate-rules")
+		}
+		stages = append(stages, "compute-meta-diff", "write-output")
 		return stages, nil
 	default:
 		return nil, fmt.Errorf("invalid action")
@@ -121,10 +136,27 @@ func persistMetaEnabled(meta *stage.Meta) bool {
 	return meta != nil && meta.PersistMeta != nil && meta.PersistMeta.Enabled
 }
 
+func referencesEnabled(meta *stage.Meta) bool {
+	return meta != nil &&
+		meta.Validation != nil &&
+		meta.Validation.References != nil &&
+		len(meta.Validation.References.Pointers) > 0
+}
+
+func rulesEnabled(meta *stage.Meta) bool {
+	return meta != nil && len(meta.Rules) > 0
+}
+
 func reduceEnabled(meta *stage.Meta) bool {
 	return meta != nil &&
 		meta.Lua != nil &&
-		meta.Lua.ReduceInline != ""
+		(meta.Lua.ReduceInline != "" || len(meta.Lua.Reducers) > 0)
+}
+
+func rollupEnabled(meta *stage.Meta) bool {
+	return meta != nil &&
+		meta.Lua != nil &&
+		meta.Lua.RollupInline != ""
 }
 
 func filterEnabled(meta *stage.Meta) bool {
@@ -2,7 +2,7 @@
 // Purpose: Decide the final CLI exit code for `thoth run` after the pipeline has produced its envelope and errors.
 // Responsibilities:
 // - Count successful and failed record results.
-// - Apply keep-going and diff-meta drift rules to determine whether the run should fail.
+// - Apply keep-going, diff-meta drift, and errors.failOn ("error" | "warning") rules to determine whether the run should fail.
 // - Return small typed exit errors with stable exit codes.
 // Architecture notes:
 // - Exit evaluation is separated from pipeline execution so output can still be written before the CLI decides whether the run counts as a failure.
@@ -56,6 +56,18 @@ func hasExecutionErrors(env stage.Envelope) bool {
 	return hasActionFailures(env)
 }
 
+// failOnErrors reports whether errors.failOn="error" fails the run on any
+// error or failed record, even when keep-going produced some successes.
+func failOnErrors(meta *stage.Meta) bool {
+	return meta != nil && meta.Errors != nil && meta.Errors.FailOn == "error"
+}
+
+// failOnWarnings reports whether errors.failOn="warning" promotes warnings
+// (and any error, regardless of keep-going) to a failing exit.
+func failOnWarnings(meta *stage.Meta) bool {
+	return meta != nil && meta.Errors != nil && meta.Errors.FailOn == "warning"
+}
+
 func driftDetectionEnabled(meta *stage.Meta) bool {
 	return actionName(meta) == "diff-meta" && meta != nil && meta.DiffMeta != nil && meta.DiffMeta.FailOnChange
 }
@@ -92,6 +104,17 @@ func hasMeaningfulAggregateOutput(env stage.Envelope) bool {
 }
 
 func evaluateRunExit(env stage.Envelope) error {
+	if failOnWarnings(env.Meta) {
+		if hasExecutionErrors(env) {
+			return runExitError{code: exitCodeExecErr, msg: "execution errors"}
+		}
+		if len(env.Warnings) > 0 {
+			return runExitError{code: exitCodeExecErr, msg: "warnings reported"}
+		}
+	}
+	if failOnErrors(env.Meta) && hasExecutionErrors(env) {
+		return runExitError{code: exitCodeExecErr, msg: "execution errors"}
+	}
 	if driftDetectionEnabled(env.Meta) {
 		if hasExecutionErrors(env) {
 			return runExitError{code: exitCodeExecErr, msg: "execution errors"}
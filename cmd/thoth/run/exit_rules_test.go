@@ -123,3 +123,56 @@ func TestEvaluateRunExit_DiffFailOnChange_NoDrift(t *testing.T) {
 func TestEvaluateRunExit_DiffFailOnChange_ExecutionErrorWins(t *testing.T) {
 	assertExitError(t, evaluateRunExit(diffFailOnChangeEnv(true)), "execution errors", exitCodeExecErr)
 }
+
+func TestEvaluateRunExit_WarningsPassByDefault(t *testing.T) {
+	env := stage.Envelope{
+		Meta:     keepGoingMeta("validate"),
+		Records:  []stage.Record{{Locator: "a"}},
+		Warnings: []stage.Error{{Stage: "validate-rules", Locator: "a", Message: "missing audience"}},
+	}
+	if err := evaluateRunExit(env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestEvaluateRunExit_FailOnWarning(t *testing.T) {
+	meta := keepGoingMeta("validate")
+	meta.Errors.FailOn = "warning"
+	env := stage.Envelope{
+		Meta:     meta,
+		Records:  []stage.Record{{Locator: "a"}},
+		Warnings: []stage.Error{{Stage: "lua-map", Locator: "a", Message: "soft"}},
+	}
+	assertExitError(t, evaluateRunExit(env), "warnings reported", exitCodeExecErr)
+
+	env.Warnings = nil
+	env.Errors = []stage.Error{{Stage: "lua-map", Locator: "b", Message: "boom"}}
+	assertExitError(t, evaluateRunExit(env), "execution errors", exitCodeExecErr)
+}
+
+func TestEvaluateRunExit_FailOnError(t *testing.T) {
+	meta := keepGoingMeta("pipeline")
+	meta.Errors.FailOn = "error"
+	env := stage.Envelope{
+		Meta:     meta,
+		Records:  []stage.Record{{Locator: "a"}, {Locator: "b", Error: &stage.RecError{Stage: "lua-map", Message: "boom"}}},
+		Warnings: []stage.Error{{Stage: "lua-map", Locator: "a", Message: "soft"}},
+	}
+	assertExitError(t, evaluateRunExit(env), "execution errors", exitCodeExecErr)
+
+	env.Records = []stage.Record{{Locator: "a"}}
+	if err := evaluateRunExit(env); err != nil {
+		t.Fatalf("warnings alone must not fail with failOn=error: %v", err)
+	}
+}
+
+func TestEvaluateRunExit_FailOnUnsetAllowsPartialSuccess(t *testing.T) {
+	env := stage.Envelope{
+		Meta:    keepGoingMeta("pipeline"),
+		Records: []stage.Record{{Locator: "a"}, {Locator: "b", Error: &stage.RecError{Stage: "lua-map", Message: "boom"}}},
+		Errors:  []stage.Error{{Stage: "lua-map", Locator: "b", Message: "boom"}},
+	}
+	if err := evaluateRunExit(env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
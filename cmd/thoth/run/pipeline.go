@@ -3,7 +3,8 @@
 // Responsibilities:
 // - Validate the config first and derive the requested action and runtime metadata.
 // - Dispatch to the correct action pipeline and stage order.
-// - Enforce buffered versus streaming output constraints for meta-file pipelines.
+// - Enforce buffered versus streaming output constraints for meta-file pipelines, including buffered stages for thoth.lookup/glob.
+// - Swap shell-exec and everything after it for the shell-plan stage when a dry-run plan is requested.
 // Architecture notes:
 // - Config validation always runs first so later stage selection can depend on normalized runtime metadata rather than reparsing config in multiple places.
 // - Streaming NDJSON is limited to the legacy meta pipeline because reduce and file-persistence paths need buffered envelope state.
@@ -13,18 +14,27 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"slices"
 
 	"github.com/flarebyte/thoth-ostraca/internal/stage"
 )
 
 // executePipeline runs the fixed Phase 1 pipeline for `thoth run`.
-func executePipeline(ctx context.Context, cfgPath string) (stage.Envelope, error) {
+func executePipeline(ctx context.Context, cfgPath string, traceLocators []string, planFormat string) (stage.Envelope, error) {
 	// Always start by validating config to determine action
 	in := stage.Envelope{Records: []stage.Record{}, Meta: &stage.Meta{ConfigPath: cfgPath}}
 	out, err := stage.Run(ctx, "validate-config", in, stage.Deps{Stderr: os.Stderr})
 	if err != nil {
 		return stage.Envelope{}, err
 	}
+	if len(traceLocators) > 0 {
+		out.Meta.TraceLocators = append([]string(nil), traceLocators...)
+	}
+	if planFormat != "" {
+		if err := requestShellPlan(out.Meta, planFormat); err != nil {
+			return stage.Envelope{}, err
+		}
+	}
 	ctx = stage.WithProgressReporter(
 		ctx,
 		newProgressReporter(out.Meta, os.Stderr),
@@ -33,6 +43,9 @@ func executePipeline(ctx context.Context, cfgPath string) (stage.Envelope, error
 	if out.Meta != nil && out.Meta.Config != nil && out.Meta.Config.Action != "" {
 		action = out.Meta.Config.Action
 	}
+	if shellDryRun(out.Meta) {
+		return executeShellPlan(ctx, out, action)
+	}
 	switch action {
 	case "pipeline", "nop":
 		return executeMetaPipeline(ctx, out)
@@ -59,10 +72,20 @@ func executeMetaPipeline(ctx context.Context, in stage.Envelope) (stage.Envelope
 
 	streamingRequested := outputLinesEnabled(out.Meta)
 	reduceEnabled := reduceInlineEnabled(out.Meta)
-	streamingAllowed := streamingRequested && !reduceEnabled
+	// Lookups must see the stage-start record set, which only buffered
+	// stages have; output still goes out as NDJSON lines.
+	lookupEnabled := stage.UsesRecordLookup(out.Meta)
+	streamingAllowed := streamingRequested && !reduceEnabled && !lookupEnabled
 
 	if !streamingAllowed {
-		if err := enforceBufferedRecordLimit(out); err != nil {
+		reason := ""
+		switch {
+		case streamingRequested && reduceEnabled:
+			reason = "reduce"
+		case streamingRequested && lookupEnabled:
+			reason = "thoth.lookup/thoth.glob"
+		}
+		if err := enforceBufferedRecordLimit(out, reason); err != nil {
 			return stage.Envelope{}, err
 		}
 		if streamingRequested && reduceEnabled {
@@ -91,12 +114,16 @@ func runStreamingNDJSONPipeline(ctx context.Context, in stage.Envelope) (stage.E
 	}()
 
 	perRecordStages := []string{"lua-filter", "lua-map", "shell-exec", "lua-postmap"}
-	cur := in
+	// Scripts using thoth.lookup run buffered; pinning the input set keeps
+	// any remaining call deterministic.
+	cur := stage.PinRecordSnapshot(in)
 	for _, rec := range in.Records {
 		recEnv := stage.Envelope{
-			Records: []stage.Record{rec},
-			Meta:    cur.Meta,
-			Errors:  append([]stage.Error(nil), cur.Errors...),
+			Records:  []stage.Record{rec},
+			Meta:     cur.Meta,
+			Errors:   append([]stage.Error(nil), cur.Errors...),
+			Warnings: append([]stage.Error(nil), cur.Warnings...),
+			Debug:    cur.Debug,
 		}
 		var err error
 		for _, name := range perRecordStages {
@@ -108,6 +135,8 @@ func runStreamingNDJSONPipeline(ctx context.Context, in stage.Envelope) (stage.E
 			}
 		}
 		cur.Errors = recEnv.Errors
+		cur.Warnings = recEnv.Warnings
+		cur.Debug = recEnv.Debug
 		if len(recEnv.Records) == 1 {
 			stream <- recEnv.Records[0]
 		}
@@ -126,7 +155,7 @@ func outputLinesEnabled(meta *stage.Meta) bool {
 }
 
 func reduceInlineEnabled(meta *stage.Meta) bool {
-	return meta != nil && meta.Lua != nil && meta.Lua.ReduceInline != ""
+	return meta != nil && meta.Lua != nil && (meta.Lua.ReduceInline != "" || len(meta.Lua.Reducers) > 0)
 }
 
 func forceBufferedOutput(env *stage.Envelope) {
@@ -143,10 +172,44 @@ func maxRecordsInMemory(meta *stage.Meta) int {
 	return 10000
 }
 
-func enforceBufferedRecordLimit(env stage.Envelope) error {
+// enforceBufferedRecordLimit rejects buffered runs over the record limit.
+// forcedBy names what forced buffering despite output.lines, if anything.
+func enforceBufferedRecordLimit(env stage.Envelope, forcedBy string) error {
 	limit := maxRecordsInMemory(env.Meta)
 	if len(env.Records) <= limit {
 		return nil
 	}
+	if forcedBy != "" {
+		return fmt.Errorf("buffered mode exceeds maxRecordsInMemory=%d; %s forces buffering even with output.lines=true", limit, forcedBy)
+	}
 	return fmt.Errorf("buffered mode exceeds maxRecordsInMemory=%d; set output.lines=true", limit)
 }
+
+// requestShellPlan applies `--plan` on top of the validated config.
+func requestShellPlan(meta *stage.Meta, format string) error {
+	if meta == nil || meta.Shell == nil || !meta.Shell.Enabled {
+		return fmt.Errorf("--plan requires shell.enabled=true")
+	}
+	meta.Shell.DryRun = true
+	meta.Shell.PlanFormat = format
+	return nil
+}
+
+func shellDryRun(meta *stage.Meta) bool {
+	return meta != nil && meta.Shell != nil && meta.Shell.Enabled && meta.Shell.DryRun
+}
+
+// executeShellPlan runs the action's stages up to shell-exec, then
+// shell-plan in its place; nothing after shell-exec runs.
+func executeShellPlan(ctx context.Context, in stage.Envelope, action string) (stage.Envelope, error) {
+	stages, err := PreparedActionStages(action, in.Meta)
+	if err != nil {
+		return stage.Envelope{}, err
+	}
+	idx := slices.Index(stages, "shell-exec")
+	if idx < 0 {
+		return stage.Envelope{}, fmt.Errorf("shell plan: action %s does not run shell-exec", action)
+	}
+	planStages := append(slices.Clone(stages[:idx]), "shell-plan")
+	return runStages(ctx, in, planStages)
+}
@@ -0,0 +1,125 @@
+package run
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/stage"
+)
+
+// writeRunConfig writes a config whose discovery root is the yaml1 fixture
+// repo and whose output goes to a temp file.
+func writeRunConfig(t *testing.T, body string) string {
+	t.Helper()
+	root, err := filepath.Abs(filepath.Join("..", "..", "..", "testdata", "repos", "yaml1"))
+	if err != nil {
+		t.Fatalf("abs: %v", err)
+	}
+	dir := t.TempDir()
+	cfg := "{\n  configVersion: \"1\"\n  action: \"nop\"\n" +
+		"  discovery: { root: \"" + filepath.ToSlash(root) + "\" }\n" +
+		"  output: { out: \"" + filepath.ToSlash(filepath.Join(dir, "out.ndjson")) + "\" }\n" +
+		body + "}\n"
+	p := filepath.Join(dir, "run.cue")
+	if err := os.WriteFile(p, []byte(cfg), 0o644); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return p
+}
+
+func TestExecutePipeline_StreamingKeepsWarnings(t *testing.T) {
+	cfg := writeRunConfig(t, "  output: { lines: true }\n"+
+		"  map: { inline: \"thoth.warn('check ' .. locator); return { locator = locator }\" }\n"+
+		"  errors: { failOn: \"warning\" }\n")
+	env, err := executePipeline(context.Background(), cfg, nil, "")
+	if err != nil {
+		t.Fatalf("executePipeline: %v", err)
+	}
+	if len(env.Warnings) != 2 {
+		t.Fatalf("expected one warning per record, got %+v", env.Warnings)
+	}
+	assertExitError(t, evaluateRunExit(env), "warnings reported", exitCodeExecErr)
+}
+
+// readRunRecords decodes the records written to output.out, either as
+// NDJSON lines or as one envelope.
+func readRunRecords(t *testing.T, cfg string, lines bool) []stage.Record {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(filepath.Dir(cfg), "out.ndjson"))
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	if !lines {
+		var env stage.Envelope
+		if err := json.Unmarshal(b, &env); err != nil {
+			t.Fatalf("decode envelope: %v", err)
+		}
+		return env.Records
+	}
+	var recs []stage.Record
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	for sc.Scan() {
+		var r stage.Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("decode line %q: %v", sc.Text(), err)
+		}
+		recs = append(recs, r)
+	}
+	return recs
+}
+
+func TestExecutePipeline_LookupSameWithLines(t *testing.T) {
+	body := "  map: { inline: \"return { name = meta.name }\" }\n" +
+		"  postMap: { inline: \"local o = thoth.lookup(locator == 'a' and 'b' or 'a'); return { sibling = o.mapped.name }\" }\n"
+	results := map[bool][]stage.Record{}
+	for _, lines := range []bool{false, true} {
+		cfg := writeRunConfig(t, body+"  output: { lines: "+map[bool]string{false: "false", true: "true"}[lines]+" }\n")
+		if _, err := executePipeline(context.Background(), cfg, nil, ""); err != nil {
+			t.Fatalf("executePipeline lines=%v: %v", lines, err)
+		}
+		results[lines] = readRunRecords(t, cfg, lines)
+	}
+	if len(results[true]) != 2 || !reflect.DeepEqual(results[true], results[false]) {
+		t.Fatalf("lookup results depend on output.lines:\nlines: %+v\nbuffered: %+v", results[true], results[false])
+	}
+	if got := results[true][0].Post; !reflect.DeepEqual(got, map[string]any{"sibling": "B"}) {
+		t.Fatalf("postMap must see sibling mapped values, got %+v", got)
+	}
+}
+
+func TestExecutePipeline_StreamingKeepsDebugLogs(t *testing.T) {
+	cfg := writeRunConfig(t, "  output: { lines: true }\n"+
+		"  lua: { log: \"envelope\" }\n"+
+		"  map: { inline: \"thoth.log('info', 'seen'); return { locator = locator }\" }\n")
+	env, err := executePipeline(context.Background(), cfg, []string{"a"}, "")
+	if err != nil {
+		t.Fatalf("executePipeline: %v", err)
+	}
+	if env.Debug == nil || len(env.Debug.Logs) != 2 || len(env.Debug.Traces) == 0 {
+		t.Fatalf("expected streamed debug output, got %+v", env.Debug)
+	}
+}
+
+func TestExecutePipeline_GlobMatchStillStreams(t *testing.T) {
+	// Two records exceed a limit of one, so only a streaming run succeeds.
+	limits := "  limits: { maxRecordsInMemory: 1 }\n  output: { lines: true }\n"
+	cfg := writeRunConfig(t, limits+
+		"  map: { inline: \"local global = thoth.glob_match('*', locator); return { doc = global }\" }\n")
+	if _, err := executePipeline(context.Background(), cfg, nil, ""); err != nil {
+		t.Fatalf("glob_match must not force buffering: %v", err)
+	}
+
+	cfg = writeRunConfig(t, limits+
+		"  postMap: { inline: \"return { n = #thoth.glob('*') }\" }\n")
+	_, err := executePipeline(context.Background(), cfg, nil, "")
+	want := "buffered mode exceeds maxRecordsInMemory=1; thoth.lookup/thoth.glob forces buffering even with output.lines=true"
+	if err == nil || err.Error() != want {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
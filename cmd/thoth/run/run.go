@@ -1,7 +1,7 @@
 // File Guide for dev/ai agents:
 // Purpose: Define the `thoth run` Cobra command that executes config-driven actions from the CLI.
 // Responsibilities:
-// - Define the `run` command and its required `--config` flag.
+// - Define the `run` command, its required `--config` flag, the `--trace-locator` debug flag, and `--plan` for shell dry-runs.
 // - Invoke the pipeline executor with a background context.
 // - Apply final exit-rule evaluation after the pipeline completes.
 // Architecture notes:
@@ -17,7 +17,9 @@ import (
 )
 
 var (
-	cfgPath string
+	cfgPath       string
+	traceLocators []string
+	planFormat    string
 )
 
 // Cmd represents the `thoth run` command.
@@ -30,7 +32,10 @@ var Cmd = &cobra.Command{
 		if cfgPath == "" {
 			return fmt.Errorf("missing required flag: --config")
 		}
-		out, err := executePipeline(context.Background(), cfgPath)
+		if planFormat != "" && planFormat != "json" && planFormat != "sh" {
+			return fmt.Errorf("invalid --plan: must be json or sh")
+		}
+		out, err := executePipeline(context.Background(), cfgPath, traceLocators, planFormat)
 		if err != nil {
 			return err
 		}
@@ -40,4 +45,7 @@ var Cmd = &cobra.Command{
 
 func init() {
 	Cmd.Flags().StringVarP(&cfgPath, "config", "c", "", "Config file path (.cue, required)")
+	Cmd.Flags().StringArrayVar(&traceLocators, "trace-locator", nil, "Dump Lua inputs and outputs for this locator at each Lua stage (repeatable)")
+	Cmd.Flags().StringVar(&planFormat, "plan", "", "Write the shell commands that would run (json|sh) instead of running them")
+	Cmd.Flags().Lookup("plan").NoOptDefVal = "json"
 }
@@ -37,6 +37,10 @@ PipelineConfig: {
   errors?: {
     mode?: "keep-going" | "fail-fast" | "keep-going"
     embedErrors?: bool | true
+    // Unset: keep-going runs pass while at least one record succeeds.
+    // "error" fails the run on any error or failed record; "warning"
+    // additionally fails it when the envelope has warnings.
+    failOn?: "error" | "warning"
   }
 
   // Lua sandbox + runtime
@@ -59,11 +63,23 @@ PipelineConfig: {
     envAllowlist?: [...string]
     deterministicRandom?: bool | true
     randomSeed?: int
+    // Enables thoth.read_file / thoth.read_lines, scoped to discovery.root
+    fileRead?: bool | false
+    fileReadMaxBytes?: int & >0 | 1024*1024 // total bytes one script may read
+    // Where thoth.log output goes: stderr (locator-prefixed lines),
+    // envelope (top-level "debug" block), or off
+    log?: "stderr" | "envelope" | "off" // default "stderr"
   }
 
   // Validation strictness for meta files
   validation?: {
     allowUnknownTopLevel?: bool | false
+    // JSON pointers whose values (string or list of strings) are locators.
+    // Checked by validate-references for validate and diff-meta actions.
+    references?: {
+      pointers?: [...string]
+      graph?: bool | false // also report reference cycles
+    }
   }
 
   limits?: {
@@ -76,8 +92,26 @@ PipelineConfig: {
     allowParentRefs?: bool | false
     posixStyle?: bool | true
     allowURLs?: bool | false
+    // Report locators that only differ by case (README.md vs Readme.md),
+    // across input files and sidecar locators alike
+    detectCaseCollisions?: bool | false
+    // Report locators that only differ by Unicode normalization (NFC vs NFD)
+    unicodeNormalization?: "NFC" | "none" | "none"
   }
 
+  // Per-glob metadata rules (validate and diff-meta actions).
+  // Keys are top-level meta keys; warnings are reported in meta.rulesReport
+  // without failing the run.
+  rules?: [...{
+    name?: string // default "rules[i]"
+    match: string // discovery-style glob, e.g. "**/*.go" or "docs/**"
+    required?: [...string]
+    forbidden?: [...string]
+    enum?: [string]: [...(string | number | bool)]
+    regex?: [string]: string
+    severity?: "error" | "warning" | "error"
+  }]
+
   // Diff options
   diff?: {
     includeSnapshots?: bool | false
@@ -93,34 +127,131 @@ PipelineConfig: {
   filter?: InlineScript
   map?: InlineScript
   postMap?: InlineScript
-  reduce?: InlineScript
+  // Either one reducer, optionally grouped, or named reducers keyed by output name
+  reduce?: ReduceScript | {[!~"^(inline|groupBy)$"]: ReduceScript}
+
+  // Directory rollup (input-pipeline + persistMeta only). Runs bottom-up per
+  // directory with globals dir, files [{locator, meta}] and children
+  // [{dir, meta}]; the returned table is merged into <dir>/.thoth.yaml
+  // (or <dir>.thoth.yaml under persistMeta.outDir). Return nil to skip.
+  rollup?: InlineScript
 
-  // Shell execution
+  // Shell execution. A map result with skipShell = true skips the record
+  // (shell.skipped = true in postMap and the record output).
   shell?: {
     enabled?: bool | false
     decodeJsonStdout?: bool | false
+    // Structured stdout decoder into shell.json; "json" equals
+    // decodeJsonStdout: true. csv/tsv/kv/lines values stay strings.
+    decode?: "none" | "json" | "ndjson" | "yaml" | "csv" | "tsv" | "kv" | "lines"
+    decodeOptions?: {
+      header?: bool | true // csv/tsv: first row names the columns
+      delimiter?: string // csv/tsv field delimiter (one char); kv separator (default "=")
+    }
     program?: "bash" | "sh" | "zsh" | "bash"
     commandTemplate?: string // exactly one of commandTemplate or argsTemplate
     // Supported placeholders in argsTemplate:
     // {json}, {locator}, {file.base}, {file.dir},
-    // {file.stem}, {file.ext}, {mapped.<path>}
+    // {file.stem}, {file.ext}, {mapped.<path>}, {meta.<path>},
+    // {fileInfo.<path>}, {git.<path>}, {env.NAME}, {steps.<name>.<path>}
+    // {env.NAME} reads the command's environment (inheritEnv/envAllowlist
+    // plus env); without strictTemplating, unresolved meta, fileInfo, git,
+    // env and steps placeholders stay literal.
+    // Paths take keys and list indexes: {mapped.a.b[0]}.
+    // Modifiers: {mapped.lang|go} default when missing; filters shq
+    // (POSIX single-quote), json, join:<sep>, e.g. {mapped.list|join:,|shq}
     argsTemplate?: [...string]
     workingDir?: string | "."
+    // "nearest:<marker>[,<marker>...]": run each record from the closest
+    // ancestor of its locator (up to the discovery root) that holds a marker
+    // file; falls back to workingDir when none is found. A map result
+    // `shell = { workingDir, timeoutMs }` overrides both per record.
+    workingDirStrategy?: =~"^nearest:[^/,]+(,[^/,]+)*$"
     env?: [string]: string
+    // false starts children from envAllowlist names plus env only
+    inheritEnv?: bool | true
+    envAllowlist?: [...string] // requires inheritEnv: false
+    // rlimits set in the child before exec. cpu and fileSize violations
+    // are reported as shell.limitExceeded ("cpu" | "fileSize") with
+    // exitCode -3, distinct from timeouts (-2).
+    limits?: {
+      cpuSeconds?: int & >=1
+      addressSpaceBytes?: int & >=1
+      openFiles?: int & >=1
+      fileSizeBytes?: int & >=1
+    }
+    // Per-run cap on concurrent child processes, independent of workers
+    maxConcurrent?: int & >=1
     timeoutMs?: int & >=0 | 60000
+    // When set, any other exit code is a record error ("exit code 3 not
+    // accepted"); when omitted every exit code is accepted. Entries are
+    // codes or inclusive "min-max" ranges.
+    acceptExitCodes?: [...(int & >=0 | string)]
+    // Re-run matching commands; backoff doubles after each attempt.
+    // Only the final attempt decides errors; shell.attempts records the count.
+    retry?: {
+      attempts: int & >=1
+      backoffMs?: int & >=0 | 0
+      onExitCodes?: [...(int & >=0 | string)] // same syntax as acceptExitCodes
+      onTimeout?: bool | false
+    }
     failFast?: bool | true
     capture?: {
       stdout?: bool | true
       stderr?: bool | true
       maxBytes?: int & >=0 | 1048576
     }
+    // What each command reads on stdin. json writes the mapped value
+    // (source "mapped") or the record context with mapped (source
+    // "record"); file streams the record's source file under
+    // discovery.root (no '..' or symlinks leaving the root); template
+    // renders a string with the argsTemplate placeholders.
+    stdin?: {
+      mode?: "none" | "json" | "file" | "template" | "none"
+      source?: "mapped" | "record" | "mapped" // json mode only
+      template?: string // required for template mode
+    }
     strictTemplating?: bool | true
     killProcessGroup?: bool | true
     termGraceMs?: int & >=0 | 2000
+    // Named commands run in order per record instead of argsTemplate.
+    // Results are exposed as shell.steps.<name> and to later steps as
+    // {steps.<name>.exitCode|stdout|stderr|json.<path>}.
+    steps?: [...{
+      name: string
+      program?: string // default shell.program
+      argsTemplate: [...string]
+      decodeJsonStdout?: bool // default shell.decodeJsonStdout
+      decode?: string // default shell.decode unless decodeJsonStdout is set
+      // Run only when an earlier step exited with one of exitCodes
+      runIf?: {
+        step: string
+        exitCodes?: [...int] | [0]
+      }
+    }]
+    // Files a command writes under the per-record {tmpdir} placeholder,
+    // e.g. argsTemplate: ["--out", "{tmpdir}/report.json"]. They are read
+    // (capped at capture.maxBytes) into shell.artifacts.<name> after the
+    // last command; the directory is removed before the record moves on.
+    // Missing, oversized json/yaml, or undecodable files are record errors;
+    // oversized text is cut and listed in shell.artifactsTruncated.
+    artifacts?: [...{
+      name: string
+      path: string // relative, may not leave {tmpdir}
+      decode?: "json" | "yaml" | "text" | "text"
+      optional?: bool | false
+    }]
+    // Write the commands shell-exec would run (after filter and map) to
+    // output.out instead of running them; later stages are skipped.
+    // Same as `thoth run --plan[=sh]`. Template errors are still reported.
+    dryRun?: bool | false
+    planFormat?: "json" | "sh" | "json" // requires dryRun
   }
 
   // Output options
   output?: {
+    // "sarif" and "junit" render errors and meta drift as CI reports
+    format?: "json" | "sarif" | "junit" | "json"
     lines?: bool | false
     pretty?: bool | false
     out?: string | "-"
@@ -132,6 +263,12 @@ PipelineConfig: {
     progressIntervalMs?: int & >=0 | 500
   }
 
+  // Per-run settings. now pins thoth.time.now() (RFC 3339); when omitted
+  // the clock is the process start time.
+  run?: {
+    now?: string
+  }
+
   // Persist postMap.meta into sidecars for input-pipeline
   persistMeta?: {
     enabled?: bool | false
@@ -153,3 +290,8 @@ PipelineConfig: {
 InlineScript: {
   inline: string
 }
+
+// Reduce script; groupBy returns a string/number key per item (nil skips)
+ReduceScript: InlineScript & {
+  groupBy?: string
+}
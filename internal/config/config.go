@@ -71,13 +71,17 @@ type Minimal struct {
 	Shell         Shell
 	PostMap       PostMap
 	Reduce        Reduce
+	Rollup        Rollup
 	PersistMeta   PersistMeta
 	UpdateMeta    UpdateMeta
 	DiffMeta      DiffMeta
+	Rules         []Rule
+	HasRules      bool
 	Output        Output
 	Errors        Errors
 	Workers       Workers
 	UI            UI
+	Run           Run
 }
 
 // ParseMinimal validates and extracts minimal values from the CUE config.
@@ -109,7 +113,10 @@ func ParseMinimal(path string) (Minimal, error) {
 	if err != nil {
 		return Minimal{}, err
 	}
-	m.Validation = parseValidationSection(v)
+	m.Validation, err = parseValidationSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
 	m.Limits = parseLimitsSection(v)
 	m.LuaSandbox = parseLuaSandboxSection(v)
 	m.LocatorPolicy = parseLocatorPolicySection(v)
@@ -117,9 +124,19 @@ func ParseMinimal(path string) (Minimal, error) {
 	m.Git = parseGitSection(v)
 	m.Filter = parseFilterSection(v)
 	m.Map = parseMapSection(v)
-	m.Shell = parseShellSection(v)
+	m.Shell, err = parseShellSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
 	m.PostMap = parsePostMapSection(v)
-	m.Reduce = parseReduceSection(v)
+	m.Reduce, err = parseReduceSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
+	m.Rollup, err = parseRollupSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
 	m.PersistMeta = parsePersistMetaSection(v)
 	m.UpdateMeta, err = parseUpdateMetaSection(v)
 	if err != nil {
@@ -129,10 +146,18 @@ func ParseMinimal(path string) (Minimal, error) {
 	if err != nil {
 		return Minimal{}, err
 	}
+	m.Rules, m.HasRules, err = parseRulesSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
 	m.Output = parseOutputSection(v)
 	m.Errors = parseErrorsSection(v)
 	m.Workers = parseWorkersSection(v)
 	m.UI = parseUISection(v)
+	m.Run, err = parseRunSection(v)
+	if err != nil {
+		return Minimal{}, err
+	}
 	return m, nil
 }
 
@@ -140,6 +165,28 @@ func ParseMinimal(path string) (Minimal, error) {
 type Validation struct {
 	AllowUnknownTopLevel bool
 	HasAllowUnknownTop   bool
+	References           ValidationReferences
+	HasReferences        bool
+}
+
+// ValidationReferences holds optional cross-file reference checks.
+type ValidationReferences struct {
+	Pointers    []string
+	HasPointers bool
+	Graph       bool
+	HasGraph    bool
+}
+
+// Rule holds one per-glob metadata rule. Name defaults to "rules[i]" and
+// Severity to "error".
+type Rule struct {
+	Name      string
+	Match     string
+	Required  []string
+	Forbidden []string
+	Enum      map[string][]any
+	Regex     map[string]string
+	Severity  string
 }
 
 // Limits holds optional processing limits and presence flags.
@@ -168,11 +215,17 @@ type LuaSandbox struct {
 	InstructionLimit       int
 	MemoryLimitBytes       int
 	DeterministicRandom    bool
+	FileRead               bool
+	FileReadMaxBytes       int
+	Log                    string
 	HasSection             bool
 	HasTimeoutMs           bool
 	HasInstructionLimit    bool
 	HasMemoryLimitBytes    bool
 	HasDeterministicRandom bool
+	HasFileRead            bool
+	HasFileReadMaxBytes    bool
+	HasLog                 bool
 	Libs                   LuaSandboxLibs
 }
 
@@ -217,6 +270,23 @@ type Shell struct {
 	StrictTemplating bool
 	KillProcessGroup bool
 	TermGraceMs      int
+	Steps            []ShellStep
+	StdinMode        string
+	StdinSource      string
+	StdinTemplate    string
+	Decode           string
+	DecodeHeader     bool
+	DecodeDelimiter  string
+	AcceptExitCodes  []ExitCodeRange
+	Retry            ShellRetry
+	InheritEnv       bool
+	EnvAllowlist     []string
+	Limits           ShellLimits
+	MaxConcurrent    int
+	WorkDirStrategy  string
+	Artifacts        []ShellArtifact
+	DryRun           bool
+	PlanFormat       string
 	HasSection       bool
 	HasEnabled       bool
 	HasDecodeJSON    bool
@@ -232,6 +302,78 @@ type Shell struct {
 	HasStrictTpl     bool
 	HasKillPG        bool
 	HasTermGrace     bool
+	HasSteps         bool
+	HasStdin         bool
+	HasStdinMode     bool
+	HasStdinSource   bool
+	HasStdinTemplate bool
+	HasDecode        bool
+	HasDecodeOptions bool
+	HasDecodeHeader  bool
+	HasDecodeDelim   bool
+	HasAcceptExit    bool
+	HasRetry         bool
+	HasInheritEnv    bool
+	HasEnvAllowlist  bool
+	HasLimits        bool
+	HasMaxConcurrent bool
+	HasWorkDirStrat  bool
+	HasArtifacts     bool
+	HasDryRun        bool
+	HasPlanFormat    bool
+}
+
+// ShellLimits are per-child resource limits; zero leaves a limit unset.
+type ShellLimits struct {
+	CPUSeconds        int64
+	AddressSpaceBytes int64
+	OpenFiles         int64
+	FileSizeBytes     int64
+}
+
+// ExitCodeRange is an inclusive process exit code range; a single code has
+// Min == Max.
+type ExitCodeRange struct {
+	Min int
+	Max int
+}
+
+// ShellRetry re-runs a command whose final exit code or timeout matches.
+type ShellRetry struct {
+	Attempts    int
+	BackoffMs   int
+	OnExitCodes []ExitCodeRange
+	OnTimeout   bool
+}
+
+// ShellStep is one named command in a shell.steps chain. Program,
+// DecodeJSONStdout and Decode fall back to the shell-level values when
+// omitted.
+type ShellStep struct {
+	Name             string
+	Program          string
+	ArgsTemplate     []string
+	DecodeJSONStdout bool
+	Decode           string
+	RunIf            *ShellStepRunIf
+	HasProgram       bool
+	HasDecodeJSON    bool
+	HasDecode        bool
+}
+
+// ShellStepRunIf makes a step conditional on an earlier step's exit code.
+type ShellStepRunIf struct {
+	Step      string
+	ExitCodes []int
+}
+
+// ShellArtifact is a file a command writes under its per-record {tmpdir};
+// Decode is json, yaml or text (the default).
+type ShellArtifact struct {
+	Name     string
+	Path     string
+	Decode   string
+	Optional bool
 }
 
 // PostMap holds optional post-map configuration.
@@ -240,10 +382,28 @@ type PostMap struct {
 	HasInline bool
 }
 
-// Reduce holds optional reduce config.
+// Reduce holds optional reduce config: either one reducer (Inline with an
+// optional GroupBy) or several named reducers sharing one pass.
 type Reduce struct {
-	Inline    string
-	HasInline bool
+	Inline     string
+	GroupBy    string
+	Named      []Reducer
+	HasInline  bool
+	HasGroupBy bool
+	HasNamed   bool
+}
+
+// Reducer is one named entry of a multi-output reduce section.
+type Reducer struct {
+	Name    string
+	Inline  string
+	GroupBy string
+}
+
+// Rollup holds the optional directory rollup combine script.
+type Rollup struct {
+	Inline     string
+	HasSection bool
 }
 
 // PersistMeta enables sidecar persistence from the input pipeline.
@@ -288,17 +448,21 @@ type Output struct {
 	Out       string
 	Pretty    bool
 	Lines     bool
+	Format    string
 	HasOut    bool
 	HasPretty bool
 	HasLines  bool
+	HasFormat bool
 }
 
 // Errors holds error handling mode config.
 type Errors struct {
 	Mode        string
 	EmbedErrors bool
+	FailOn      string
 	HasMode     bool
 	HasEmbed    bool
+	HasFailOn   bool
 }
 
 // Workers holds optional worker count.
@@ -316,6 +480,14 @@ type UI struct {
 	HasIntervalMs      bool
 }
 
+// Run holds optional per-run settings; Now pins the clock seen by Lua
+// time helpers.
+type Run struct {
+	Now        string
+	HasSection bool
+	HasNow     bool
+}
+
 // FileInfo holds optional fileInfo config.
 type FileInfo struct {
 	Enabled    bool
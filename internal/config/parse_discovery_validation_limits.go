@@ -2,7 +2,7 @@
 // Purpose: Parse the discovery, validation, and limits sections that shape broad run behavior.
 // Responsibilities:
 // - Decode discovery root and include/exclude controls.
-// - Decode validation flags for config strictness.
+// - Decode validation flags for config strictness and cross-file reference checks.
 // - Decode processing limits such as YAML size and in-memory record caps.
 // Architecture notes:
 // - These sections are grouped here because they are global execution controls rather than action-specific features.
@@ -63,12 +63,13 @@ func parseDiscoverySection(v cue.Value) (Discovery, error) {
 	return d, nil
 }
 
-// parseValidationSection extracts optional validation.allowUnknownTopLevel.
-func parseValidationSection(v cue.Value) Validation {
+// parseValidationSection extracts optional validation.allowUnknownTopLevel
+// and validation.references.
+func parseValidationSection(v cue.Value) (Validation, error) {
 	var val Validation
 	vv := v.LookupPath(cue.ParsePath("validation"))
 	if !vv.Exists() {
-		return val
+		return val, nil
 	}
 	auv := vv.LookupPath(cue.ParsePath("allowUnknownTopLevel"))
 	if auv.Exists() && (auv.Kind() == cue.BoolKind) {
@@ -76,7 +77,31 @@ func parseValidationSection(v cue.Value) Validation {
 			val.HasAllowUnknownTop = true
 		}
 	}
-	return val
+	rv := vv.LookupPath(cue.ParsePath("references"))
+	if !rv.Exists() {
+		return val, nil
+	}
+	if rv.Kind() != cue.StructKind {
+		return Validation{}, fmt.Errorf("invalid validation.references: must be object")
+	}
+	val.HasReferences = true
+	pv := rv.LookupPath(cue.ParsePath("pointers"))
+	if pv.Exists() {
+		if pv.Kind() != cue.ListKind {
+			return Validation{}, fmt.Errorf("invalid validation.references.pointers: must be list of strings")
+		}
+		if err := pv.Decode(&val.References.Pointers); err != nil {
+			return Validation{}, fmt.Errorf("invalid validation.references.pointers: must be list of strings")
+		}
+		val.References.HasPointers = true
+	}
+	gv := rv.LookupPath(cue.ParsePath("graph"))
+	if gv.Exists() && gv.Kind() == cue.BoolKind {
+		if err := gv.Decode(&val.References.Graph); err == nil {
+			val.References.HasGraph = true
+		}
+	}
+	return val, nil
 }
 
 // parseLimitsSection extracts optional limits.maxYAMLBytes and limits.maxRecordsInMemory.
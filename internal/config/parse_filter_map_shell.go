@@ -2,7 +2,7 @@
 // Purpose: Parse the programmable per-record stages for filtering, mapping, and shell execution.
 // Responsibilities:
 // - Decode filter.inline and map.inline Lua snippets.
-// - Decode shell execution settings, including capture, templating, and timeouts.
+// - Decode shell execution settings, including capture, stdin, stdout decoding, templating, and timeouts.
 // - Preserve presence flags that let later validation reason about explicit shell config.
 // Architecture notes:
 // - Shell parsing is verbose by design because many downstream defaults depend on whether a field was explicitly set.
@@ -44,11 +44,11 @@ func parseMapSection(v cue.Value) Map {
 }
 
 // parseShellSection extracts optional shell.* fields.
-func parseShellSection(v cue.Value) Shell {
+func parseShellSection(v cue.Value) (Shell, error) {
 	var s Shell
 	sv := v.LookupPath(cue.ParsePath("shell"))
 	if !sv.Exists() {
-		return s
+		return s, nil
 	}
 	s.HasSection = true
 	ev := sv.LookupPath(cue.ParsePath("enabled"))
@@ -61,6 +61,25 @@ func parseShellSection(v cue.Value) Shell {
 		_ = djv.Decode(&s.DecodeJSONStdout)
 		s.HasDecodeJSON = true
 	}
+	dev := sv.LookupPath(cue.ParsePath("decode"))
+	if dev.Exists() && dev.Kind() == cue.StringKind {
+		_ = dev.Decode(&s.Decode)
+		s.HasDecode = true
+	}
+	dov := sv.LookupPath(cue.ParsePath("decodeOptions"))
+	if dov.Exists() {
+		s.HasDecodeOptions = true
+		hv := dov.LookupPath(cue.ParsePath("header"))
+		if hv.Exists() && hv.Kind() == cue.BoolKind {
+			_ = hv.Decode(&s.DecodeHeader)
+			s.HasDecodeHeader = true
+		}
+		dlv := dov.LookupPath(cue.ParsePath("delimiter"))
+		if dlv.Exists() && dlv.Kind() == cue.StringKind {
+			_ = dlv.Decode(&s.DecodeDelimiter)
+			s.HasDecodeDelim = true
+		}
+	}
 	pv := sv.LookupPath(cue.ParsePath("program"))
 	if pv.Exists() && pv.Kind() == cue.StringKind {
 		_ = pv.Decode(&s.Program)
@@ -78,6 +97,21 @@ func parseShellSection(v cue.Value) Shell {
 		_ = wv.Decode(&s.WorkingDir)
 		s.HasWorkingDir = true
 	}
+	drv := sv.LookupPath(cue.ParsePath("dryRun"))
+	if drv.Exists() && drv.Kind() == cue.BoolKind {
+		_ = drv.Decode(&s.DryRun)
+		s.HasDryRun = true
+	}
+	pfv := sv.LookupPath(cue.ParsePath("planFormat"))
+	if pfv.Exists() && pfv.Kind() == cue.StringKind {
+		_ = pfv.Decode(&s.PlanFormat)
+		s.HasPlanFormat = true
+	}
+	wsv := sv.LookupPath(cue.ParsePath("workingDirStrategy"))
+	if wsv.Exists() && wsv.Kind() == cue.StringKind {
+		_ = wsv.Decode(&s.WorkDirStrategy)
+		s.HasWorkDirStrat = true
+	}
 	envv := sv.LookupPath(cue.ParsePath("env"))
 	if envv.Exists() {
 		tmp := map[string]string{}
@@ -110,6 +144,25 @@ func parseShellSection(v cue.Value) Shell {
 			s.HasCaptureMax = true
 		}
 	}
+	inv := sv.LookupPath(cue.ParsePath("stdin"))
+	if inv.Exists() {
+		s.HasStdin = true
+		mv := inv.LookupPath(cue.ParsePath("mode"))
+		if mv.Exists() && mv.Kind() == cue.StringKind {
+			_ = mv.Decode(&s.StdinMode)
+			s.HasStdinMode = true
+		}
+		srcv := inv.LookupPath(cue.ParsePath("source"))
+		if srcv.Exists() && srcv.Kind() == cue.StringKind {
+			_ = srcv.Decode(&s.StdinSource)
+			s.HasStdinSource = true
+		}
+		tplv := inv.LookupPath(cue.ParsePath("template"))
+		if tplv.Exists() && tplv.Kind() == cue.StringKind {
+			_ = tplv.Decode(&s.StdinTemplate)
+			s.HasStdinTemplate = true
+		}
+	}
 	stv := sv.LookupPath(cue.ParsePath("strictTemplating"))
 	if stv.Exists() && stv.Kind() == cue.BoolKind {
 		_ = stv.Decode(&s.StrictTemplating)
@@ -125,5 +178,20 @@ func parseShellSection(v cue.Value) Shell {
 		_ = tgv.Decode(&s.TermGraceMs)
 		s.HasTermGrace = true
 	}
-	return s
+	if err := parseShellExitPolicy(sv, &s); err != nil {
+		return Shell{}, err
+	}
+	steps, hasSteps, err := parseShellSteps(sv)
+	if err != nil {
+		return Shell{}, err
+	}
+	s.Steps = steps
+	s.HasSteps = hasSteps
+	artifacts, hasArtifacts, err := parseShellArtifacts(sv)
+	if err != nil {
+		return Shell{}, err
+	}
+	s.Artifacts = artifacts
+	s.HasArtifacts = hasArtifacts
+	return s, nil
 }
@@ -2,6 +2,7 @@
 // Purpose: Parse locator policy toggles that constrain what paths/config locators are considered valid.
 // Responsibilities:
 // - Decode locatorPolicy booleans for absolute paths, parent refs, URL allowance, and POSIX normalization.
+// - Decode collision detection toggles (case folding and Unicode normalization form).
 // - Preserve per-field presence flags for later validation and defaults.
 // Architecture notes:
 // - This file is intentionally narrow because locator policy is a distinct contract from discovery or action behavior.
@@ -11,14 +12,18 @@ import "cuelang.org/go/cue"
 
 // LocatorPolicy holds optional locator policy booleans and presence flags.
 type LocatorPolicy struct {
-	AllowAbsolute   bool
-	AllowParentRefs bool
-	PosixStyle      bool
-	AllowURLs       bool
-	HasAllowAbs     bool
-	HasAllowParent  bool
-	HasPosix        bool
-	HasAllowURLs    bool
+	AllowAbsolute           bool
+	AllowParentRefs         bool
+	PosixStyle              bool
+	AllowURLs               bool
+	DetectCaseCollisions    bool
+	UnicodeNormalization    string
+	HasAllowAbs             bool
+	HasAllowParent          bool
+	HasPosix                bool
+	HasAllowURLs            bool
+	HasDetectCaseCollisions bool
+	HasUnicodeNormalization bool
 }
 
 // parseLocatorPolicySection extracts optional locatorPolicy.* fields.
@@ -48,5 +53,15 @@ func parseLocatorPolicySection(v cue.Value) LocatorPolicy {
 		_ = auv.Decode(&lp.AllowURLs)
 		lp.HasAllowURLs = true
 	}
+	dcv := pv.LookupPath(cue.ParsePath("detectCaseCollisions"))
+	if dcv.Exists() && dcv.Kind() == cue.BoolKind {
+		_ = dcv.Decode(&lp.DetectCaseCollisions)
+		lp.HasDetectCaseCollisions = true
+	}
+	unv := pv.LookupPath(cue.ParsePath("unicodeNormalization"))
+	if unv.Exists() && unv.Kind() == cue.StringKind {
+		_ = unv.Decode(&lp.UnicodeNormalization)
+		lp.HasUnicodeNormalization = true
+	}
 	return lp
 }
@@ -2,7 +2,7 @@
 // Purpose: Parse Lua sandbox settings that control script safety and deterministic behavior.
 // Responsibilities:
 // - Decode Lua timeout, instruction, and memory limits.
-// - Decode deterministicRandom and allowed standard-library toggles.
+// - Decode deterministicRandom, fileRead access, log routing, and allowed standard-library toggles.
 // - Preserve section and field presence for downstream validation/defaulting.
 // Architecture notes:
 // - This file only parses sandbox policy; actual Lua execution lives under internal/stage.
@@ -44,6 +44,25 @@ func parseLuaSandboxSection(v cue.Value) LuaSandbox {
 			s.HasDeterministicRandom = true
 		}
 	}
+	fv := lv.LookupPath(cue.ParsePath("fileRead"))
+	if fv.Exists() && fv.Kind() == cue.BoolKind {
+		if err := fv.Decode(&s.FileRead); err == nil {
+			s.HasFileRead = true
+		}
+	}
+	fmv := lv.LookupPath(cue.ParsePath("fileReadMaxBytes"))
+	if fmv.Exists() && fmv.Kind() == cue.IntKind {
+		if err := fmv.Decode(&s.FileReadMaxBytes); err == nil {
+			s.HasFileReadMaxBytes = true
+		}
+	}
+	logv := lv.LookupPath(cue.ParsePath("log"))
+	if logv.Exists() {
+		s.HasLog = true
+		if logv.Kind() == cue.StringKind {
+			_ = logv.Decode(&s.Log)
+		}
+	}
 
 	libs := lv.LookupPath(cue.ParsePath("libs"))
 	if !libs.Exists() {
@@ -2,14 +2,18 @@
 // Purpose: Parse output and lightweight runtime toggles that do not belong to the core programmable stages.
 // Responsibilities:
 // - Decode output destination and formatting settings.
-// - Decode error embedding mode, worker count, and UI progress settings.
+// - Decode error embedding mode, worker count, UI progress, and run clock settings.
 // - Decode fileInfo and git enrichment toggles.
 // Architecture notes:
 // - These settings are grouped here because they shape execution/reporting around the pipeline rather than the pipeline logic itself.
 // - Enrichment flags live here intentionally; they are optional runtime decorations, not part of config discovery.
 package config
 
-import "cuelang.org/go/cue"
+import (
+	"fmt"
+
+	"cuelang.org/go/cue"
+)
 
 // parseOutputSection extracts optional output.* fields.
 func parseOutputSection(v cue.Value) Output {
@@ -33,6 +37,11 @@ func parseOutputSection(v cue.Value) Output {
 		_ = lv.Decode(&o.Lines)
 		o.HasLines = true
 	}
+	fv := ov.LookupPath(cue.ParsePath("format"))
+	if fv.Exists() && fv.Kind() == cue.StringKind {
+		_ = fv.Decode(&o.Format)
+		o.HasFormat = true
+	}
 	return o
 }
 
@@ -53,6 +62,11 @@ func parseErrorsSection(v cue.Value) Errors {
 		_ = emb.Decode(&e.EmbedErrors)
 		e.HasEmbed = true
 	}
+	fv := ev.LookupPath(cue.ParsePath("failOn"))
+	if fv.Exists() && fv.Kind() == cue.StringKind {
+		_ = fv.Decode(&e.FailOn)
+		e.HasFailOn = true
+	}
 	return e
 }
 
@@ -88,6 +102,26 @@ func parseUISection(v cue.Value) UI {
 	return u
 }
 
+// parseRunSection extracts optional run.now.
+func parseRunSection(v cue.Value) (Run, error) {
+	var r Run
+	rv := v.LookupPath(cue.ParsePath("run"))
+	if !rv.Exists() {
+		return r, nil
+	}
+	r.HasSection = true
+	nv := rv.LookupPath(cue.ParsePath("now"))
+	if !nv.Exists() {
+		return r, nil
+	}
+	if nv.Kind() != cue.StringKind {
+		return Run{}, fmt.Errorf("invalid run.now: must be string")
+	}
+	_ = nv.Decode(&r.Now)
+	r.HasNow = true
+	return r, nil
+}
+
 // parseFileInfoSection extracts optional fileInfo.enabled.
 func parseFileInfoSection(v cue.Value) FileInfo {
 	var fi FileInfo
@@ -1,8 +1,8 @@
 // File Guide for dev/ai agents:
 // Purpose: Parse the later-stage action sections that shape post-processing, persistence, update, and diff behavior.
 // Responsibilities:
-// - Decode postMap and reduce Lua sections.
-// - Decode persistMeta settings for sidecar writes.
+// - Decode postMap and reduce Lua sections, including grouped and named reducers.
+// - Decode the directory rollup script and persistMeta settings for sidecar writes.
 // - Decode updateMeta and diffMeta sections, including their stricter validation rules.
 // Architecture notes:
 // - updateMeta and diffMeta parsing return errors directly because these sections have richer schema constraints than the lighter parse helpers.
@@ -31,12 +31,13 @@ func parsePostMapSection(v cue.Value) PostMap {
 	return pm
 }
 
-// parseReduceSection extracts optional reduce.inline.
-func parseReduceSection(v cue.Value) Reduce {
+// parseReduceSection extracts optional reduce.inline/reduce.groupBy, or a
+// set of named reducers such as reduce: { byLang: { inline, groupBy } }.
+func parseReduceSection(v cue.Value) (Reduce, error) {
 	var r Reduce
 	rv := v.LookupPath(cue.ParsePath("reduce"))
 	if !rv.Exists() {
-		return r
+		return r, nil
 	}
 	iv := rv.LookupPath(cue.ParsePath("inline"))
 	if iv.Exists() && iv.Kind() == cue.StringKind {
@@ -44,7 +45,64 @@ func parseReduceSection(v cue.Value) Reduce {
 			r.HasInline = true
 		}
 	}
-	return r
+	gv := rv.LookupPath(cue.ParsePath("groupBy"))
+	if gv.Exists() {
+		if gv.Kind() != cue.StringKind {
+			return Reduce{}, fmt.Errorf("invalid reduce.groupBy: must be string")
+		}
+		_ = gv.Decode(&r.GroupBy)
+		r.HasGroupBy = true
+	}
+	it, err := rv.Fields()
+	if err != nil {
+		return r, nil
+	}
+	for it.Next() {
+		name := it.Selector().Unquoted()
+		if name == "inline" || name == "groupBy" {
+			continue
+		}
+		if r.HasInline || r.HasGroupBy {
+			return Reduce{}, fmt.Errorf("invalid reduce: use either inline/groupBy or named reducers, not both")
+		}
+		nv := it.Value()
+		rd := Reducer{Name: name}
+		niv := nv.LookupPath(cue.ParsePath("inline"))
+		if nv.Kind() != cue.StructKind || !niv.Exists() || niv.Kind() != cue.StringKind {
+			return Reduce{}, fmt.Errorf("invalid reduce.%s.inline: must be string", name)
+		}
+		_ = niv.Decode(&rd.Inline)
+		ngv := nv.LookupPath(cue.ParsePath("groupBy"))
+		if ngv.Exists() {
+			if ngv.Kind() != cue.StringKind {
+				return Reduce{}, fmt.Errorf("invalid reduce.%s.groupBy: must be string", name)
+			}
+			_ = ngv.Decode(&rd.GroupBy)
+		}
+		r.Named = append(r.Named, rd)
+		r.HasNamed = true
+	}
+	if r.HasGroupBy && !r.HasInline {
+		return Reduce{}, fmt.Errorf("invalid reduce.groupBy: requires reduce.inline")
+	}
+	return r, nil
+}
+
+// parseRollupSection extracts optional rollup.inline, which is required once
+// the section is present.
+func parseRollupSection(v cue.Value) (Rollup, error) {
+	var r Rollup
+	rv := v.LookupPath(cue.ParsePath("rollup"))
+	if !rv.Exists() {
+		return r, nil
+	}
+	r.HasSection = true
+	iv := rv.LookupPath(cue.ParsePath("inline"))
+	if !iv.Exists() || iv.Kind() != cue.StringKind {
+		return Rollup{}, fmt.Errorf("invalid rollup.inline: must be string")
+	}
+	_ = iv.Decode(&r.Inline)
+	return r, nil
 }
 
 // parsePersistMetaSection extracts optional persistMeta.enabled.
@@ -0,0 +1,121 @@
+// File Guide for dev/ai agents:
+// Purpose: Parse the per-glob metadata rules list used by the validate-rules stage.
+// Responsibilities:
+// - Decode each rule's match glob, required/forbidden keys, enums, and regex constraints.
+// - Normalize severity defaults and reject malformed rule entries early.
+// - Compile regex constraints once so invalid patterns fail at config time.
+// Architecture notes:
+// - Rules are decoded field by field with indexed error messages (`rules[2].severity`) so users can find the bad entry in long lists.
+// - Enum values are kept as decoded CUE scalars; the stage compares them against YAML values with type-aware equality.
+// - Enum and regex entries are checked in sorted key order so the first reported error is the same on every run.
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// parseRulesSection extracts the optional top-level rules list.
+func parseRulesSection(v cue.Value) ([]Rule, bool, error) {
+	rv := v.LookupPath(cue.ParsePath("rules"))
+	if !rv.Exists() {
+		return nil, false, nil
+	}
+	if rv.Kind() != cue.ListKind {
+		return nil, false, fmt.Errorf("invalid rules: must be list of objects")
+	}
+	it, err := rv.List()
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid rules: must be list of objects")
+	}
+	rules := make([]Rule, 0)
+	for i := 0; it.Next(); i++ {
+		r, err := parseRule(it.Value(), i)
+		if err != nil {
+			return nil, false, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, true, nil
+}
+
+func parseRule(v cue.Value, idx int) (Rule, error) {
+	field := fmt.Sprintf("rules[%d]", idx)
+	if v.Kind() != cue.StructKind {
+		return Rule{}, fmt.Errorf("invalid %s: must be object", field)
+	}
+	r := Rule{Name: field, Severity: "error"}
+	if nv := v.LookupPath(cue.ParsePath("name")); nv.Exists() {
+		if err := nv.Decode(&r.Name); err != nil || nv.Kind() != cue.StringKind {
+			return Rule{}, fmt.Errorf("invalid %s.name: must be string", field)
+		}
+	}
+	mv := v.LookupPath(cue.ParsePath("match"))
+	if !mv.Exists() || mv.Kind() != cue.StringKind {
+		return Rule{}, fmt.Errorf("invalid %s.match: must be non-empty glob", field)
+	}
+	if err := mv.Decode(&r.Match); err != nil || strings.TrimSpace(r.Match) == "" {
+		return Rule{}, fmt.Errorf("invalid %s.match: must be non-empty glob", field)
+	}
+	for _, key := range []string{"required", "forbidden"} {
+		lv := v.LookupPath(cue.ParsePath(key))
+		if !lv.Exists() {
+			continue
+		}
+		var keys []string
+		if lv.Kind() != cue.ListKind || lv.Decode(&keys) != nil {
+			return Rule{}, fmt.Errorf("invalid %s.%s: must be list of strings", field, key)
+		}
+		if key == "required" {
+			r.Required = keys
+		} else {
+			r.Forbidden = keys
+		}
+	}
+	if ev := v.LookupPath(cue.ParsePath("enum")); ev.Exists() {
+		raw := map[string]any{}
+		if ev.Kind() != cue.StructKind || ev.Decode(&raw) != nil {
+			return Rule{}, fmt.Errorf("invalid %s.enum: must be object of lists", field)
+		}
+		enum := make(map[string][]any, len(raw))
+		for _, k := range sortedRuleKeys(raw) {
+			values, ok := raw[k].([]any)
+			if !ok {
+				return Rule{}, fmt.Errorf("invalid %s.enum.%s: must be list", field, k)
+			}
+			enum[k] = values
+		}
+		r.Enum = enum
+	}
+	if xv := v.LookupPath(cue.ParsePath("regex")); xv.Exists() {
+		patterns := map[string]string{}
+		if xv.Kind() != cue.StructKind || xv.Decode(&patterns) != nil {
+			return Rule{}, fmt.Errorf("invalid %s.regex: must be object of strings", field)
+		}
+		for _, k := range sortedRuleKeys(patterns) {
+			if _, err := regexp.Compile(patterns[k]); err != nil {
+				return Rule{}, fmt.Errorf("invalid %s.regex.%s: %v", field, k, err)
+			}
+		}
+		r.Regex = patterns
+	}
+	if sv := v.LookupPath(cue.ParsePath("severity")); sv.Exists() {
+		if sv.Decode(&r.Severity) != nil || (r.Severity != "error" && r.Severity != "warning") {
+			return Rule{}, fmt.Errorf("invalid %s.severity: must be 'error' or 'warning'", field)
+		}
+	}
+	return r, nil
+}
+
+func sortedRuleKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
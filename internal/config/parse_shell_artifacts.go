@@ -0,0 +1,88 @@
+// File Guide for dev/ai agents:
+// Purpose: Parse the optional shell.artifacts list of files a command writes into its per-record temp directory.
+// Responsibilities:
+// - Decode each artifact's name, path, decode format, and optional flag.
+// - Reject duplicate names, unknown formats, and paths that escape `{tmpdir}`.
+// Architecture notes:
+// - Artifacts are decoded field by field with indexed error messages (`shell.artifacts[0].path`), matching shell.steps.
+// - Paths are validated here so the runtime only ever reads inside the temp directory it created.
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// parseShellArtifacts extracts the optional shell.artifacts list.
+func parseShellArtifacts(sv cue.Value) ([]ShellArtifact, bool, error) {
+	lv := sv.LookupPath(cue.ParsePath("artifacts"))
+	if !lv.Exists() {
+		return nil, false, nil
+	}
+	it, err := lv.List()
+	if lv.Kind() != cue.ListKind || err != nil {
+		return nil, false, fmt.Errorf("invalid shell.artifacts: must be list of objects")
+	}
+	out := make([]ShellArtifact, 0)
+	seen := map[string]bool{}
+	for i := 0; it.Next(); i++ {
+		a, err := parseShellArtifact(it.Value(), i, seen)
+		if err != nil {
+			return nil, false, err
+		}
+		seen[a.Name] = true
+		out = append(out, a)
+	}
+	if len(out) == 0 {
+		return nil, false, fmt.Errorf("invalid shell.artifacts: must contain at least one artifact")
+	}
+	return out, true, nil
+}
+
+func parseShellArtifact(v cue.Value, idx int, earlier map[string]bool) (ShellArtifact, error) {
+	field := fmt.Sprintf("shell.artifacts[%d]", idx)
+	if v.Kind() != cue.StructKind {
+		return ShellArtifact{}, fmt.Errorf("invalid %s: must be object", field)
+	}
+	a := ShellArtifact{Decode: "text"}
+	nv := v.LookupPath(cue.ParsePath("name"))
+	if !nv.Exists() || nv.Kind() != cue.StringKind || nv.Decode(&a.Name) != nil ||
+		strings.TrimSpace(a.Name) == "" {
+		return ShellArtifact{}, fmt.Errorf("invalid %s.name: must be non-empty string", field)
+	}
+	if earlier[a.Name] {
+		return ShellArtifact{}, fmt.Errorf("invalid %s.name: duplicate artifact %q", field, a.Name)
+	}
+	pv := v.LookupPath(cue.ParsePath("path"))
+	if !pv.Exists() || pv.Kind() != cue.StringKind || pv.Decode(&a.Path) != nil ||
+		!artifactPathConfined(a.Path) {
+		return ShellArtifact{}, fmt.Errorf(
+			"invalid %s.path: must be a relative path inside {tmpdir}", field,
+		)
+	}
+	if dv := v.LookupPath(cue.ParsePath("decode")); dv.Exists() {
+		if dv.Kind() != cue.StringKind || dv.Decode(&a.Decode) != nil ||
+			(a.Decode != "json" && a.Decode != "yaml" && a.Decode != "text") {
+			return ShellArtifact{}, fmt.Errorf(
+				"invalid %s.decode: must be one of json, yaml, text", field,
+			)
+		}
+	}
+	if ov := v.LookupPath(cue.ParsePath("optional")); ov.Exists() {
+		if ov.Kind() != cue.BoolKind || ov.Decode(&a.Optional) != nil {
+			return ShellArtifact{}, fmt.Errorf("invalid %s.optional: must be bool", field)
+		}
+	}
+	return a, nil
+}
+
+func artifactPathConfined(p string) bool {
+	if p == "" || strings.Contains(p, `\`) || path.IsAbs(p) {
+		return false
+	}
+	clean := path.Clean(p)
+	return clean != "." && clean != ".." && !strings.HasPrefix(clean, "../")
+}
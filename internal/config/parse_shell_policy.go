@@ -0,0 +1,170 @@
+// File Guide for dev/ai agents:
+// Purpose: Parse shell exit code, retry, environment isolation, and resource limit policy.
+// Responsibilities:
+// - Decode shell.acceptExitCodes entries given as ints or "min-max" range strings.
+// - Decode shell.retry attempts, backoff, and the exit codes or timeouts that trigger a retry.
+// - Decode shell.inheritEnv/envAllowlist, shell.limits, and shell.maxConcurrent.
+// - Reject malformed ranges and retry blocks that could never retry.
+// Architecture notes:
+// - Exit code lists share one range syntax so acceptExitCodes and retry.onExitCodes read the same way in configs.
+// - Only non-negative codes are accepted; -1/-2 are internal start-failure/timeout markers and are handled by other settings.
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+func parseShellExitPolicy(sv cue.Value, s *Shell) error {
+	if av := sv.LookupPath(cue.ParsePath("acceptExitCodes")); av.Exists() {
+		codes, err := parseExitCodeRanges(av, "shell.acceptExitCodes")
+		if err != nil {
+			return err
+		}
+		s.AcceptExitCodes = codes
+		s.HasAcceptExit = true
+	}
+	if err := parseShellIsolation(sv, s); err != nil {
+		return err
+	}
+	rv := sv.LookupPath(cue.ParsePath("retry"))
+	if !rv.Exists() {
+		return nil
+	}
+	if rv.Kind() != cue.StructKind {
+		return fmt.Errorf("invalid shell.retry: must be object")
+	}
+	s.HasRetry = true
+	r := ShellRetry{}
+	atv := rv.LookupPath(cue.ParsePath("attempts"))
+	if !atv.Exists() || atv.Kind() != cue.IntKind || atv.Decode(&r.Attempts) != nil || r.Attempts < 1 {
+		return fmt.Errorf("invalid shell.retry.attempts: must be int >= 1")
+	}
+	if bv := rv.LookupPath(cue.ParsePath("backoffMs")); bv.Exists() {
+		if bv.Kind() != cue.IntKind || bv.Decode(&r.BackoffMs) != nil || r.BackoffMs < 0 {
+			return fmt.Errorf("invalid shell.retry.backoffMs: must be int >= 0")
+		}
+	}
+	if ov := rv.LookupPath(cue.ParsePath("onExitCodes")); ov.Exists() {
+		codes, err := parseExitCodeRanges(ov, "shell.retry.onExitCodes")
+		if err != nil {
+			return err
+		}
+		r.OnExitCodes = codes
+	}
+	if tv := rv.LookupPath(cue.ParsePath("onTimeout")); tv.Exists() {
+		if tv.Kind() != cue.BoolKind || tv.Decode(&r.OnTimeout) != nil {
+			return fmt.Errorf("invalid shell.retry.onTimeout: must be bool")
+		}
+	}
+	if len(r.OnExitCodes) == 0 && !r.OnTimeout {
+		return fmt.Errorf("invalid shell.retry: set onExitCodes or onTimeout")
+	}
+	s.Retry = r
+	return nil
+}
+
+func parseShellIsolation(sv cue.Value, s *Shell) error {
+	if iv := sv.LookupPath(cue.ParsePath("inheritEnv")); iv.Exists() {
+		if iv.Kind() != cue.BoolKind || iv.Decode(&s.InheritEnv) != nil {
+			return fmt.Errorf("invalid shell.inheritEnv: must be bool")
+		}
+		s.HasInheritEnv = true
+	}
+	if av := sv.LookupPath(cue.ParsePath("envAllowlist")); av.Exists() {
+		if av.Kind() != cue.ListKind || av.Decode(&s.EnvAllowlist) != nil {
+			return fmt.Errorf("invalid shell.envAllowlist: must be list of strings")
+		}
+		s.HasEnvAllowlist = true
+	}
+	if mv := sv.LookupPath(cue.ParsePath("maxConcurrent")); mv.Exists() {
+		if mv.Kind() != cue.IntKind || mv.Decode(&s.MaxConcurrent) != nil || s.MaxConcurrent < 1 {
+			return fmt.Errorf("invalid shell.maxConcurrent: must be int >= 1")
+		}
+		s.HasMaxConcurrent = true
+	}
+	lv := sv.LookupPath(cue.ParsePath("limits"))
+	if !lv.Exists() {
+		return nil
+	}
+	if lv.Kind() != cue.StructKind {
+		return fmt.Errorf("invalid shell.limits: must be object")
+	}
+	fields := []struct {
+		name string
+		dst  *int64
+	}{
+		{"cpuSeconds", &s.Limits.CPUSeconds},
+		{"addressSpaceBytes", &s.Limits.AddressSpaceBytes},
+		{"openFiles", &s.Limits.OpenFiles},
+		{"fileSizeBytes", &s.Limits.FileSizeBytes},
+	}
+	for _, f := range fields {
+		fv := lv.LookupPath(cue.ParsePath(f.name))
+		if !fv.Exists() {
+			continue
+		}
+		if fv.Kind() != cue.IntKind || fv.Decode(f.dst) != nil || *f.dst < 1 {
+			return fmt.Errorf("invalid shell.limits.%s: must be int >= 1", f.name)
+		}
+	}
+	s.HasLimits = true
+	return nil
+}
+
+// parseExitCodeRanges decodes a list of ints and "min-max" strings.
+func parseExitCodeRanges(v cue.Value, field string) ([]ExitCodeRange, error) {
+	bad := fmt.Errorf(
+		"invalid %s: must be list of exit codes (int >= 0) or \"min-max\" ranges",
+		field,
+	)
+	if v.Kind() != cue.ListKind {
+		return nil, bad
+	}
+	it, err := v.List()
+	if err != nil {
+		return nil, bad
+	}
+	out := make([]ExitCodeRange, 0)
+	for it.Next() {
+		ev := it.Value()
+		switch ev.Kind() {
+		case cue.IntKind:
+			var n int
+			if ev.Decode(&n) != nil || n < 0 {
+				return nil, bad
+			}
+			out = append(out, ExitCodeRange{Min: n, Max: n})
+		case cue.StringKind:
+			var s string
+			_ = ev.Decode(&s)
+			r, ok := parseExitCodeRange(s)
+			if !ok {
+				return nil, bad
+			}
+			out = append(out, r)
+		default:
+			return nil, bad
+		}
+	}
+	if len(out) == 0 {
+		return nil, bad
+	}
+	return out, nil
+}
+
+func parseExitCodeRange(s string) (ExitCodeRange, bool) {
+	lo, hi, isRange := strings.Cut(strings.TrimSpace(s), "-")
+	if !isRange {
+		hi = lo
+	}
+	minCode, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	maxCode, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || minCode < 0 || maxCode < minCode {
+		return ExitCodeRange{}, false
+	}
+	return ExitCodeRange{Min: minCode, Max: maxCode}, true
+}
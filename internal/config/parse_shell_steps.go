@@ -0,0 +1,119 @@
+// File Guide for dev/ai agents:
+// Purpose: Parse the optional shell.steps chain of named commands run in order per record.
+// Responsibilities:
+// - Decode each step's name, program, argsTemplate, decodeJsonStdout/decode, and runIf condition.
+// - Reject duplicate or empty step names and runIf references to steps that do not run earlier.
+// - Keep presence flags so omitted step fields can inherit shell-level values later.
+// Architecture notes:
+// - Steps are decoded field by field with indexed error messages (`shell.steps[1].name`), matching the rules parser.
+// - Ordering checks live here because step names only have meaning within the list being parsed.
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"cuelang.org/go/cue"
+)
+
+// parseShellSteps extracts the optional shell.steps list.
+func parseShellSteps(sv cue.Value) ([]ShellStep, bool, error) {
+	lv := sv.LookupPath(cue.ParsePath("steps"))
+	if !lv.Exists() {
+		return nil, false, nil
+	}
+	if lv.Kind() != cue.ListKind {
+		return nil, false, fmt.Errorf("invalid shell.steps: must be list of objects")
+	}
+	it, err := lv.List()
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid shell.steps: must be list of objects")
+	}
+	steps := make([]ShellStep, 0)
+	seen := map[string]bool{}
+	for i := 0; it.Next(); i++ {
+		st, err := parseShellStep(it.Value(), i, seen)
+		if err != nil {
+			return nil, false, err
+		}
+		seen[st.Name] = true
+		steps = append(steps, st)
+	}
+	if len(steps) == 0 {
+		return nil, false, fmt.Errorf("invalid shell.steps: must contain at least one step")
+	}
+	return steps, true, nil
+}
+
+func parseShellStep(v cue.Value, idx int, earlier map[string]bool) (ShellStep, error) {
+	field := fmt.Sprintf("shell.steps[%d]", idx)
+	if v.Kind() != cue.StructKind {
+		return ShellStep{}, fmt.Errorf("invalid %s: must be object", field)
+	}
+	var st ShellStep
+	nv := v.LookupPath(cue.ParsePath("name"))
+	if !nv.Exists() || nv.Kind() != cue.StringKind || nv.Decode(&st.Name) != nil ||
+		strings.TrimSpace(st.Name) == "" || strings.ContainsAny(st.Name, ".{}") {
+		return ShellStep{}, fmt.Errorf(
+			"invalid %s.name: must be non-empty string without '.', '{' or '}'", field,
+		)
+	}
+	if earlier[st.Name] {
+		return ShellStep{}, fmt.Errorf("invalid %s.name: duplicate step %q", field, st.Name)
+	}
+	if pv := v.LookupPath(cue.ParsePath("program")); pv.Exists() {
+		if pv.Kind() != cue.StringKind || pv.Decode(&st.Program) != nil {
+			return ShellStep{}, fmt.Errorf("invalid %s.program: must be string", field)
+		}
+		st.HasProgram = true
+	}
+	av := v.LookupPath(cue.ParsePath("argsTemplate"))
+	if !av.Exists() || av.Kind() != cue.ListKind || av.Decode(&st.ArgsTemplate) != nil ||
+		len(st.ArgsTemplate) == 0 {
+		return ShellStep{}, fmt.Errorf(
+			"invalid %s.argsTemplate: must be non-empty list of strings", field,
+		)
+	}
+	if dv := v.LookupPath(cue.ParsePath("decodeJsonStdout")); dv.Exists() {
+		if dv.Kind() != cue.BoolKind || dv.Decode(&st.DecodeJSONStdout) != nil {
+			return ShellStep{}, fmt.Errorf("invalid %s.decodeJsonStdout: must be bool", field)
+		}
+		st.HasDecodeJSON = true
+	}
+	if dv := v.LookupPath(cue.ParsePath("decode")); dv.Exists() {
+		if dv.Kind() != cue.StringKind || dv.Decode(&st.Decode) != nil {
+			return ShellStep{}, fmt.Errorf("invalid %s.decode: must be string", field)
+		}
+		st.HasDecode = true
+	}
+	if rv := v.LookupPath(cue.ParsePath("runIf")); rv.Exists() {
+		runIf, err := parseShellStepRunIf(rv, field+".runIf", earlier)
+		if err != nil {
+			return ShellStep{}, err
+		}
+		st.RunIf = runIf
+	}
+	return st, nil
+}
+
+func parseShellStepRunIf(v cue.Value, field string, earlier map[string]bool) (*ShellStepRunIf, error) {
+	if v.Kind() != cue.StructKind {
+		return nil, fmt.Errorf("invalid %s: must be object", field)
+	}
+	r := &ShellStepRunIf{ExitCodes: []int{0}}
+	sv := v.LookupPath(cue.ParsePath("step"))
+	if !sv.Exists() || sv.Kind() != cue.StringKind || sv.Decode(&r.Step) != nil {
+		return nil, fmt.Errorf("invalid %s.step: must be string", field)
+	}
+	if !earlier[r.Step] {
+		return nil, fmt.Errorf("invalid %s.step: %q must name an earlier step", field, r.Step)
+	}
+	if ev := v.LookupPath(cue.ParsePath("exitCodes")); ev.Exists() {
+		var codes []int
+		if ev.Kind() != cue.ListKind || ev.Decode(&codes) != nil || len(codes) == 0 {
+			return nil, fmt.Errorf("invalid %s.exitCodes: must be non-empty list of ints", field)
+		}
+		r.ExitCodes = codes
+	}
+	return r, nil
+}
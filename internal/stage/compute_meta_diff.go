@@ -2,6 +2,7 @@
 // Purpose: Compare discovered inputs and existing sidecars to produce the diff-meta report consumed by users and tests.
 // Responsibilities:
 // - Build paired input/meta sets and detect orphan sidecar files.
+// - Treat rollup directory sidecars as owned while their directory still holds inputs.
 // - Compute expected metadata from static patches or per-locator Lua and diff it against existing metadata.
 // - Filter, summarize, and emit the final diff report into envelope metadata.
 // Architecture notes:
@@ -12,13 +13,31 @@ package stage
 import (
 	"context"
 	"fmt"
+	"path"
 	"sort"
-	"strings"
 )
 
 const computeMetaDiffStage = "compute-meta-diff"
 const diffMetaExpectedLuaStage = "diff-meta-expectedLua"
 
+// inputDirSet returns every directory holding an input, including the
+// root ".", which is where rollup directory sidecars may live.
+func inputDirSet(inputs []string) map[string]struct{} {
+	dirs := map[string]struct{}{}
+	for _, s := range inputs {
+		for d := path.Dir(s); ; d = path.Dir(d) {
+			if _, seen := dirs[d]; seen {
+				break
+			}
+			dirs[d] = struct{}{}
+			if d == "." || d == "/" {
+				break
+			}
+		}
+	}
+	return dirs
+}
+
 func computeMetaDiffRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error) {
 	if in.Meta == nil {
 		return Envelope{}, fmt.Errorf("compute-meta-diff: missing meta")
@@ -71,9 +90,12 @@ func computeMetaDiffRunner(ctx context.Context, in Envelope, deps Deps) (Envelop
 	details := make([]DiffDetail, 0)
 	sort.Strings(orphans)
 
+	dirSet := inputDirSet(inputs)
 	for _, m := range metas {
-		base := strings.TrimSuffix(m, ".thoth.yaml")
-		if _, ok := inputSet[base]; !ok {
+		target := sidecarLocator(m)
+		_, isInput := inputSet[target]
+		_, isDir := dirSet[target]
+		if !isInput && !isDir {
 			orphans = append(orphans, m)
 		}
 	}
@@ -66,6 +66,15 @@ func diffMetaMapsV3Detailed(existing, expected map[string]any) diffSummary {
 	}
 }
 
+// DiffValues compares two JSON-like values with the detailed diff engine.
+// Paths are relative to the compared values; a root-level mismatch has an
+// empty path.
+func DiffValues(existing, expected any) []DiffChange {
+	c := &diffCollector{format: "detailed"}
+	c.compareValues("", existing, expected)
+	return sortDiffChanges(c.changes)
+}
+
 func diffMetaMapsV3JSONPatch(existing, expected map[string]any) diffSummary {
 	s := diffMetaMapsV3(existing, expected)
 	s.patch = diffMetaJSONPatch(existing, expected)
@@ -4,6 +4,7 @@
 // - Walk the configured discovery root and collect eligible non-sidecar files.
 // - Apply always-excluded, default-excluded, gitignore, include, and exclude rules deterministically.
 // - Materialize sorted input records and diff-meta input metadata from the discovered locators.
+// - Report case/Unicode locator collisions when locatorPolicy asks for it.
 // Architecture notes:
 // - This file owns input discovery only; pattern matching helpers live in discovery_filters.go and gitignore matching is reused from meta discovery helpers.
 // - Existing .thoth.yaml files and .gitignore files are always excluded here so file actions operate on source inputs, not metadata artifacts.
@@ -104,6 +105,9 @@ func discoverInputFilesRunner(ctx context.Context, in Envelope, deps Deps) (Enve
 	if out.Meta != nil && out.Meta.Config != nil && out.Meta.Config.Action == "diff-meta" {
 		out.Meta.Inputs = append([]string(nil), locators...)
 	}
+	if err := applyLocatorCollisions(&out, "discover-input-files", nil); err != nil {
+		return Envelope{}, err
+	}
 	return out, nil
 }
 
@@ -49,6 +49,8 @@ type Meta struct {
 	Inputs          []string         `json:"inputs,omitempty"`
 	MetaFiles       []string         `json:"metaFiles,omitempty"`
 	Diff            *DiffReport      `json:"diff,omitempty"`
+	Rules           []RuleMeta       `json:"rules,omitempty"`
+	RulesReport     *RulesReport     `json:"rulesReport,omitempty"`
 	Lua             *LuaMeta         `json:"lua,omitempty"`
 	LuaSandbox      *LuaSandboxMeta  `json:"luaSandbox,omitempty"`
 	Shell           *ShellMeta       `json:"shell,omitempty"`
@@ -57,14 +59,37 @@ type Meta struct {
 	UpdateMeta      *UpdateMetaMeta  `json:"updateMeta,omitempty"`
 	DiffMeta        *DiffMetaMeta    `json:"diffMeta,omitempty"`
 	Reduced         any              `json:"reduced,omitempty"`
+	RollupReport    *RollupReport    `json:"rollupReport,omitempty"`
 	Errors          *ErrorsMeta      `json:"errors,omitempty"`
 	Workers         int              `json:"workers,omitempty"`
 	UI              *UIMeta          `json:"ui,omitempty"`
+	Run             *RunMeta         `json:"run,omitempty"`
+	// TraceLocators comes from --trace-locator; it is never serialized so
+	// tracing a run leaves its output bytes unchanged.
+	TraceLocators []string `json:"-"`
+
+	// warnings collects soft findings for the stage currently running; it
+	// is never serialized (see warnings.go).
+	warnings *warningCollector
+	// records is the stage-start snapshot read by thoth.lookup/thoth.glob
+	// (see lua_thoth_records.go).
+	records *recordSnapshot
+	// debug collects thoth.log entries and traces for the stage currently
+	// running (see lua_debug.go).
+	debug *debugCollector
 }
 
-// ValidationMeta controls strictness for top-level YAML fields.
+// ValidationMeta controls strictness for top-level YAML fields and
+// optional cross-file reference checks.
 type ValidationMeta struct {
-	AllowUnknownTopLevel bool `json:"allowUnknownTopLevel"`
+	AllowUnknownTopLevel bool            `json:"allowUnknownTopLevel"`
+	References           *ReferencesMeta `json:"references,omitempty"`
+}
+
+// ReferencesMeta lists JSON pointers whose values are locators.
+type ReferencesMeta struct {
+	Pointers []string `json:"pointers"`
+	Graph    bool     `json:"graph"`
 }
 
 // LimitsMeta controls parsing size limits.
@@ -122,18 +147,47 @@ type DiffOp struct {
 
 // LocatorPolicy mirrors policy flags for locator validation in meta.
 type LocatorPolicy struct {
-	AllowAbsolute   bool `json:"allowAbsolute"`
-	AllowParentRefs bool `json:"allowParentRefs"`
-	PosixStyle      bool `json:"posixStyle"`
-	AllowURLs       bool `json:"allowURLs"`
+	AllowAbsolute        bool   `json:"allowAbsolute"`
+	AllowParentRefs      bool   `json:"allowParentRefs"`
+	PosixStyle           bool   `json:"posixStyle"`
+	AllowURLs            bool   `json:"allowURLs"`
+	DetectCaseCollisions bool   `json:"detectCaseCollisions,omitempty"`
+	UnicodeNormalization string `json:"unicodeNormalization,omitempty"`
 }
 
 // Envelope is a minimal JSON-serializable contract between stages.
 // Field order is stable to keep JSON deterministic in tests.
 type Envelope struct {
-	Records []Record `json:"records"`
-	Meta    *Meta    `json:"meta,omitempty"`
-	Errors  []Error  `json:"errors,omitempty"`
+	Records  []Record    `json:"records"`
+	Meta     *Meta       `json:"meta,omitempty"`
+	Errors   []Error     `json:"errors,omitempty"`
+	Warnings []Error     `json:"warnings,omitempty"`
+	Debug    *DebugBlock `json:"debug,omitempty"`
+}
+
+// DebugBlock holds Lua debug output embedded in the envelope when
+// lua.log is "envelope".
+type DebugBlock struct {
+	Logs   []LuaLogEntry `json:"logs,omitempty"`
+	Traces []LuaTrace    `json:"traces,omitempty"`
+}
+
+// LuaLogEntry is one thoth.log call made by a Lua script.
+type LuaLogEntry struct {
+	Stage   string `json:"stage"`
+	Locator string `json:"locator,omitempty"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// LuaTrace captures the globals passed to one Lua script run and its result
+// for a locator named with --trace-locator.
+type LuaTrace struct {
+	Stage   string         `json:"stage"`
+	Locator string         `json:"locator,omitempty"`
+	Input   map[string]any `json:"input"`
+	Output  any            `json:"output,omitempty"`
+	Error   string         `json:"error,omitempty"`
 }
 
 // ValidateEnvelope performs basic schema checks for the public contract.
@@ -168,6 +222,17 @@ type LuaMeta struct {
 	MapInline     string `json:"mapInline,omitempty"`
 	PostMapInline string `json:"postMapInline,omitempty"`
 	ReduceInline  string `json:"reduceInline,omitempty"`
+	ReduceGroupBy string `json:"reduceGroupBy,omitempty"`
+	RollupInline  string `json:"rollupInline,omitempty"`
+	// Reducers holds named reducers; when set, meta.reduced is keyed by name.
+	Reducers []ReducerMeta `json:"reducers,omitempty"`
+}
+
+// ReducerMeta is one named reducer, optionally grouped by a Lua key function.
+type ReducerMeta struct {
+	Name    string `json:"name"`
+	Inline  string `json:"inline"`
+	GroupBy string `json:"groupBy,omitempty"`
 }
 
 // LuaSandboxMeta holds runtime sandbox controls for Lua.
@@ -177,6 +242,9 @@ type LuaSandboxMeta struct {
 	MemoryLimitBytes    int                `json:"memoryLimitBytes"`
 	Libs                LuaSandboxLibsMeta `json:"libs"`
 	DeterministicRandom bool               `json:"deterministicRandom"`
+	FileRead            bool               `json:"fileRead,omitempty"`
+	FileReadMaxBytes    int                `json:"fileReadMaxBytes,omitempty"`
+	Log                 string             `json:"log,omitempty"`
 }
 
 // LuaSandboxLibsMeta toggles exposed Lua libs.
@@ -200,6 +268,99 @@ type ShellMeta struct {
 	StrictTemplating bool              `json:"strictTemplating"`
 	KillProcessGroup bool              `json:"killProcessGroup"`
 	TermGraceMs      int               `json:"termGraceMs"`
+	Steps            []ShellStepMeta   `json:"steps,omitempty"`
+	Stdin            *ShellStdinMeta   `json:"stdin,omitempty"`
+	// Decode names the stdout decoder; empty means decodeJsonStdout
+	// alone decides.
+	Decode        string                  `json:"decode,omitempty"`
+	DecodeOptions *ShellDecodeOptionsMeta `json:"decodeOptions,omitempty"`
+	// AcceptExitCodes, when set, turns any other exit code into a record
+	// error; when empty every exit code is accepted.
+	AcceptExitCodes []ShellExitCodeRange `json:"acceptExitCodes,omitempty"`
+	Retry           *ShellRetryMeta      `json:"retry,omitempty"`
+	// InheritEnv is nil when the parent environment is inherited (the
+	// default); false starts children from EnvAllowlist plus env only.
+	InheritEnv    *bool            `json:"inheritEnv,omitempty"`
+	EnvAllowlist  []string         `json:"envAllowlist,omitempty"`
+	Limits        *ShellLimitsMeta `json:"limits,omitempty"`
+	MaxConcurrent int              `json:"maxConcurrent,omitempty"`
+	// WorkingDirStrategy "nearest:<marker>[,<marker>...]" runs each record
+	// from its closest ancestor directory containing a marker file.
+	WorkingDirStrategy string `json:"workingDirStrategy,omitempty"`
+	// Artifacts are files collected from the per-record {tmpdir} after the
+	// command (or steps chain) ran.
+	Artifacts []ShellArtifactMeta `json:"artifacts,omitempty"`
+	// DryRun replaces shell-exec and everything after it with a command
+	// plan written in PlanFormat ("json" or "sh"); nothing is spawned.
+	DryRun     bool   `json:"dryRun,omitempty"`
+	PlanFormat string `json:"planFormat,omitempty"`
+}
+
+// ShellArtifactMeta is a file read from {tmpdir}/Path and decoded as json,
+// yaml or text into ShellResult.Artifacts[Name].
+type ShellArtifactMeta struct {
+	Name     string `json:"name"`
+	Path     string `json:"path"`
+	Decode   string `json:"decode"`
+	Optional bool   `json:"optional,omitempty"`
+}
+
+// ShellLimitsMeta holds rlimits applied to each child before exec; zero
+// leaves a limit unset.
+type ShellLimitsMeta struct {
+	CPUSeconds        int64 `json:"cpuSeconds,omitempty"`
+	AddressSpaceBytes int64 `json:"addressSpaceBytes,omitempty"`
+	OpenFiles         int64 `json:"openFiles,omitempty"`
+	FileSizeBytes     int64 `json:"fileSizeBytes,omitempty"`
+}
+
+// ShellExitCodeRange is an inclusive exit code range.
+type ShellExitCodeRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// ShellRetryMeta re-runs a command when its exit code or timeout matches;
+// the delay doubles after each attempt starting from BackoffMs.
+type ShellRetryMeta struct {
+	Attempts    int                  `json:"attempts"`
+	BackoffMs   int                  `json:"backoffMs"`
+	OnExitCodes []ShellExitCodeRange `json:"onExitCodes,omitempty"`
+	OnTimeout   bool                 `json:"onTimeout"`
+}
+
+// ShellDecodeOptionsMeta tunes the csv, tsv and kv stdout decoders.
+type ShellDecodeOptionsMeta struct {
+	Header    bool   `json:"header"`
+	Delimiter string `json:"delimiter,omitempty"`
+}
+
+// ShellStdinMeta selects what is written to each command's stdin: nothing,
+// the mapped value or record context as JSON, the source file, or a
+// rendered template.
+type ShellStdinMeta struct {
+	Mode     string `json:"mode"`
+	Source   string `json:"source,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+// ShellStepMeta is one named command in a shell.steps chain, with program,
+// decodeJsonStdout and decode already resolved against the shell-level
+// settings.
+type ShellStepMeta struct {
+	Name             string              `json:"name"`
+	Program          string              `json:"program"`
+	ArgsTemplate     []string            `json:"argsTemplate"`
+	DecodeJSONStdout bool                `json:"decodeJsonStdout"`
+	Decode           string              `json:"decode,omitempty"`
+	RunIf            *ShellStepRunIfMeta `json:"runIf,omitempty"`
+}
+
+// ShellStepRunIfMeta runs a step only when an earlier step exited with one
+// of the listed codes.
+type ShellStepRunIfMeta struct {
+	Step      string `json:"step"`
+	ExitCodes []int  `json:"exitCodes"`
 }
 
 // ShellCaptureMeta controls shell output capture behavior.
@@ -214,6 +375,7 @@ type OutputMeta struct {
 	Out    string `json:"out,omitempty"`
 	Pretty bool   `json:"pretty,omitempty"`
 	Lines  bool   `json:"lines,omitempty"`
+	Format string `json:"format,omitempty"`
 }
 
 // PersistMetaMeta enables sidecar persistence from input-pipeline records.
@@ -239,10 +401,52 @@ type DiffMetaMeta struct {
 	FailOnChange      bool           `json:"failOnChange"`
 }
 
+// RuleMeta mirrors one configured per-glob metadata rule.
+type RuleMeta struct {
+	Name      string            `json:"name"`
+	Match     string            `json:"match"`
+	Required  []string          `json:"required,omitempty"`
+	Forbidden []string          `json:"forbidden,omitempty"`
+	Enum      map[string][]any  `json:"enum,omitempty"`
+	Regex     map[string]string `json:"regex,omitempty"`
+	Severity  string            `json:"severity"`
+}
+
+// RulesReport holds the sorted violations produced by validate-rules.
+type RulesReport struct {
+	Violations   []RuleViolation `json:"violations"`
+	ErrorCount   int             `json:"errorCount"`
+	WarningCount int             `json:"warningCount"`
+}
+
+// RuleViolation is one rule check that failed for one locator.
+type RuleViolation struct {
+	Locator  string `json:"locator"`
+	Rule     string `json:"rule"`
+	Key      string `json:"key"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// RollupReport lists the directory sidecars produced by the rollup stage,
+// deepest directories first.
+type RollupReport struct {
+	Dirs []RollupDir `json:"dirs"`
+}
+
+// RollupDir is one directory sidecar written (or skipped in dry-run) by rollup.
+type RollupDir struct {
+	Dir          string         `json:"dir"`
+	MetaPath     string         `json:"metaPath"`
+	Meta         map[string]any `json:"meta"`
+	WriteSkipped string         `json:"writeSkipped,omitempty"`
+}
+
 // ErrorsMeta holds error handling behavior.
 type ErrorsMeta struct {
 	Mode        string `json:"mode,omitempty"`
 	EmbedErrors bool   `json:"embedErrors,omitempty"`
+	FailOn      string `json:"failOn,omitempty"`
 }
 
 // FileInfoMeta controls file info enrichment behavior.
@@ -255,6 +459,11 @@ type GitMeta struct {
 	Enabled bool `json:"enabled"`
 }
 
+// RunMeta holds per-run settings such as the pinned Lua clock.
+type RunMeta struct {
+	Now string `json:"now,omitempty"`
+}
+
 // UIMeta holds optional runtime UI settings.
 type UIMeta struct {
 	Progress           bool `json:"progress"`
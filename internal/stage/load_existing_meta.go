@@ -21,34 +21,47 @@ const loadExistingStage = "load-existing-meta"
 
 func loadOneExistingWithMeta(meta *Meta, root string, rec Record) (Record, *Error, error) {
 	abs, rel := persistMetaFilePath(meta, root, rec.Locator)
-	b, err := os.ReadFile(abs)
+	ymeta, found, msg, err := readExistingSidecar(abs, rel)
 	if err != nil {
+		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: msg}, err
+	}
+	if !found {
 		// Not found → expose path only
+		rec.Post = mergePostMap(rec, map[string]any{"existingMetaPath": rel})
+		return rec, nil, nil
+	}
+	rec.Post = mergePostMap(rec, map[string]any{"existingMetaPath": rel, "existingMeta": ymeta})
+	return rec, nil, nil
+}
+
+// readExistingSidecar reads and checks the sidecar at abs. A missing file is
+// reported as found=false without error; msg is the record-facing message
+// accompanying a non-nil err.
+func readExistingSidecar(abs, rel string) (map[string]any, bool, string, error) {
+	b, err := os.ReadFile(abs)
+	if err != nil {
 		if os.IsNotExist(err) {
-			// attach existingMetaPath only
-			rec.Post = mergePostMap(rec, map[string]any{"existingMetaPath": rel})
-			return rec, nil, nil
+			return nil, false, "", nil
 		}
-		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: err.Error()}, err
+		return nil, false, err.Error(), err
 	}
 	var y any
 	if err := yaml.Unmarshal(b, &y); err != nil {
-		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: fmt.Sprintf("invalid YAML: %v", err)}, err
+		return nil, false, fmt.Sprintf("invalid YAML: %v", err), err
 	}
 	ym, ok := y.(map[string]any)
 	if !ok {
-		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: "top-level must be mapping"}, fmt.Errorf("invalid meta YAML: %s", rel)
+		return nil, false, "top-level must be mapping", fmt.Errorf("invalid meta YAML: %s", rel)
 	}
 	yloc, ok := ym["locator"].(string)
 	if !ok || yloc == "" {
-		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: "missing or invalid locator"}, fmt.Errorf("invalid meta YAML: %s", rel)
+		return nil, false, "missing or invalid locator", fmt.Errorf("invalid meta YAML: %s", rel)
 	}
 	ymeta, ok := ym["meta"].(map[string]any)
 	if !ok {
-		return rec, &Error{Stage: loadExistingStage, Locator: rec.Locator, Message: "missing or invalid meta"}, fmt.Errorf("invalid meta YAML: %s", rel)
+		return nil, false, "missing or invalid meta", fmt.Errorf("invalid meta YAML: %s", rel)
 	}
-	rec.Post = mergePostMap(rec, map[string]any{"existingMetaPath": rel, "existingMeta": ymeta})
-	return rec, nil, nil
+	return ymeta, true, "", nil
 }
 
 func mergePostMap(rec Record, base map[string]any) map[string]any {
@@ -0,0 +1,140 @@
+// File Guide for dev/ai agents:
+// Purpose: Detect locators that are distinct byte strings but name the same file on case-insensitive or normalizing filesystems.
+// Responsibilities:
+// - Derive the collision key from locatorPolicy.detectCaseCollisions and locatorPolicy.unicodeNormalization.
+// - Group colliding locators deterministically.
+// - Report each group once as a stage error and mark every member record in keep-going mode.
+// Architecture notes:
+// - The check is shared by discover-input-files and validate-locators so input and sidecar sets follow the same policy.
+// - validate-locators groups the union of sidecar locators and discovered inputs (diff-meta), tagging each member by origin, so `Readme.md` on disk collides with a `README.md.thoth.yaml` sidecar.
+// - Groups are sorted internally and by first member, so error output does not depend on walk or worker order.
+package stage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type collisionPolicy struct {
+	foldCase bool
+	nfc      bool
+}
+
+func (p collisionPolicy) enabled() bool { return p.foldCase || p.nfc }
+
+func collisionPolicyFromMeta(meta *Meta) collisionPolicy {
+	if meta == nil || meta.LocatorPolicy == nil {
+		return collisionPolicy{}
+	}
+	return collisionPolicy{
+		foldCase: meta.LocatorPolicy.DetectCaseCollisions,
+		nfc:      meta.LocatorPolicy.UnicodeNormalization == "NFC",
+	}
+}
+
+func locatorCollisionKey(loc string, p collisionPolicy) string {
+	key := loc
+	if p.nfc {
+		key = norm.NFC.String(key)
+	}
+	if p.foldCase {
+		key = strings.ToLower(key)
+	}
+	return key
+}
+
+// findLocatorCollisions returns groups of two or more distinct locators that
+// share a collision key.
+func findLocatorCollisions(locators []string, p collisionPolicy) [][]string {
+	byKey := map[string]map[string]struct{}{}
+	for _, loc := range locators {
+		k := locatorCollisionKey(loc, p)
+		if byKey[k] == nil {
+			byKey[k] = map[string]struct{}{}
+		}
+		byKey[k][loc] = struct{}{}
+	}
+	groups := make([][]string, 0)
+	for _, members := range byKey {
+		if len(members) < 2 {
+			continue
+		}
+		group := make([]string, 0, len(members))
+		for m := range members {
+			group = append(group, m)
+		}
+		sort.Strings(group)
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
+
+// applyLocatorCollisions checks out.Records, together with any discovered
+// inputs passed alongside, against the collision policy. Groups made only
+// of inputs are left to discover-input-files, which already reported them.
+// Fail-fast returns the first group as an error; keep-going records one
+// envelope error per group and embeds it into every member record.
+func applyLocatorCollisions(out *Envelope, stageName string, inputs []string) error {
+	p := collisionPolicyFromMeta(out.Meta)
+	if !p.enabled() {
+		return nil
+	}
+	origins := map[string][]string{}
+	for _, loc := range inputs {
+		origins[loc] = []string{"input"}
+	}
+	for _, r := range out.Records {
+		if o := origins[r.Locator]; len(o) == 0 || o[len(o)-1] != "sidecar" {
+			origins[r.Locator] = append(o, "sidecar")
+		}
+	}
+	groups := findLocatorCollisions(sortedKeys(origins), p)
+	if len(groups) == 0 {
+		return nil
+	}
+	mode, embed := errorMode(out.Meta)
+	msgByLocator := map[string]string{}
+	var envErrs []Error
+	for _, g := range groups {
+		if len(inputs) > 0 && allInputs(g, origins) {
+			continue
+		}
+		names := g
+		if len(inputs) > 0 {
+			names = make([]string, len(g))
+			for i, loc := range g {
+				names[i] = loc + " (" + strings.Join(origins[loc], "+") + ")"
+			}
+		}
+		msg := "colliding locators: " + strings.Join(names, ", ")
+		if mode != "keep-going" {
+			return fmt.Errorf("%s: %s", stageName, msg)
+		}
+		envErrs = append(envErrs, Error{Stage: stageName, Locator: g[0], Message: msg})
+		for _, loc := range g {
+			msgByLocator[loc] = msg
+		}
+	}
+	if embed {
+		for i, r := range out.Records {
+			if msg, ok := msgByLocator[r.Locator]; ok && r.Error == nil {
+				out.Records[i].Error = &RecError{Stage: stageName, Message: msg}
+			}
+		}
+	}
+	appendSanitizedErrors(out, envErrs)
+	return nil
+}
+
+func allInputs(group []string, origins map[string][]string) bool {
+	for _, loc := range group {
+		if origins[loc][0] != "input" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,111 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestFindLocatorCollisions_CaseAndNFC(t *testing.T) {
+	nfd := "docs/cafe\u0301.md"
+	nfc := "docs/caf\u00e9.md"
+	locs := []string{"b/x.go", "README.md", nfd, "Readme.md", nfc, "b/X.go", "main.go"}
+
+	caseOnly := findLocatorCollisions(locs, collisionPolicy{foldCase: true})
+	if len(caseOnly) != 2 ||
+		strings.Join(caseOnly[0], ",") != "README.md,Readme.md" ||
+		strings.Join(caseOnly[1], ",") != "b/X.go,b/x.go" {
+		t.Fatalf("unexpected case groups: %q", caseOnly)
+	}
+
+	nfcOnly := findLocatorCollisions(locs, collisionPolicy{nfc: true})
+	if len(nfcOnly) != 1 || len(nfcOnly[0]) != 2 {
+		t.Fatalf("unexpected NFC groups: %q", nfcOnly)
+	}
+
+	both := findLocatorCollisions(append(locs, "docs/CAF\u00c9.md"), collisionPolicy{foldCase: true, nfc: true})
+	if len(both) != 3 || len(both[2]) != 3 {
+		t.Fatalf("unexpected combined groups: %q", both)
+	}
+}
+
+func TestValidateLocators_KeepGoingReportsCollisionGroup(t *testing.T) {
+	in := Envelope{
+		Records: []Record{{Locator: "Readme.md"}, {Locator: "README.md"}, {Locator: "main.go"}},
+		Meta: &Meta{
+			Errors:        &ErrorsMeta{Mode: "keep-going", EmbedErrors: true},
+			LocatorPolicy: &LocatorPolicy{PosixStyle: true, DetectCaseCollisions: true},
+		},
+	}
+	out, err := Run(context.Background(), validateLocatorsStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-locators: %v", err)
+	}
+	if len(out.Errors) != 1 || out.Errors[0].Message != "colliding locators: README.md, Readme.md" ||
+		out.Errors[0].Locator != "README.md" {
+		t.Fatalf("unexpected errors: %+v", out.Errors)
+	}
+	if out.Records[0].Error == nil || out.Records[1].Error == nil || out.Records[2].Error != nil {
+		t.Fatalf("expected both colliding records marked: %+v", out.Records)
+	}
+}
+
+func TestValidateLocators_CollisionsAcrossInputsAndSidecars(t *testing.T) {
+	in := Envelope{
+		Records: []Record{{Locator: "README.md"}, {Locator: "main.go"}},
+		Meta: &Meta{
+			Inputs:        []string{"A.go", "Readme.md", "a.go", "main.go"},
+			Errors:        &ErrorsMeta{Mode: "keep-going", EmbedErrors: true},
+			LocatorPolicy: &LocatorPolicy{PosixStyle: true, DetectCaseCollisions: true},
+		},
+	}
+	out, err := Run(context.Background(), validateLocatorsStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-locators: %v", err)
+	}
+	// A.go/a.go are inputs only and were reported by discover-input-files.
+	if len(out.Errors) != 1 || out.Errors[0].Message != "colliding locators: README.md (sidecar), Readme.md (input)" {
+		t.Fatalf("unexpected errors: %+v", out.Errors)
+	}
+	if out.Records[0].Error == nil || out.Records[1].Error != nil {
+		t.Fatalf("expected only the sidecar record marked: %+v", out.Records)
+	}
+}
+
+func TestDiscoverInputFiles_FailFastCaseCollision(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Simulate a case-variant sibling; on case-insensitive filesystems the
+	// second write would reuse the first file, so only assert when both exist.
+	if err := os.WriteFile(filepath.Join(root, "A.go"), []byte("y"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(root)
+	if len(entries) != 3 {
+		t.Skip("filesystem is case-insensitive")
+	}
+	in := Envelope{Meta: &Meta{
+		Discovery:     &DiscoveryMeta{Root: root},
+		LocatorPolicy: &LocatorPolicy{DetectCaseCollisions: true},
+	}}
+	_, err := Run(context.Background(), "discover-input-files", in, Deps{})
+	if err == nil || err.Error() != "discover-input-files: colliding locators: A.go, a.go" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_RejectsUnknownUnicodeNormalization(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  locatorPolicy: { unicodeNormalization: \"NFD\" }\n}\n"
+	_, err := runValidateConfigWithContent(t, "locator_nfd_validate_test.cue", content)
+	if err == nil || err.Error() != "invalid locatorPolicy.unicodeNormalization: must be 'NFC' or 'none'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
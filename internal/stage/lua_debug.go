@@ -0,0 +1,133 @@
+// File Guide for dev/ai agents:
+// Purpose: Collect Lua debug output (thoth.log entries and --trace-locator traces) for one stage run and route it to stderr or the envelope.
+// Responsibilities:
+// - Provide the concurrency-safe collector attached to the per-stage meta copy next to the warning collector.
+// - Decide whether a locator is traced and which sink lua.log selects.
+// - Flush collected entries in deterministic locator order after the stage finishes.
+// Architecture notes:
+// - Debug output never changes records, errors, or exit codes; it is a side channel for script authors.
+// - Entries are sorted stably by locator so per-record call order survives while parallel worker interleaving does not leak into output.
+// - Stderr output is flushed even when the stage fails fast, because that is exactly when script authors need it.
+package stage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	luaLogStderr   = "stderr"
+	luaLogEnvelope = "envelope"
+	luaLogOff      = "off"
+)
+
+type debugCollector struct {
+	mu     sync.Mutex
+	logs   []LuaLogEntry
+	traces []LuaTrace
+}
+
+func (c *debugCollector) addLog(e LuaLogEntry) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.logs = append(c.logs, e)
+	c.mu.Unlock()
+}
+
+func (c *debugCollector) addTrace(t LuaTrace) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.traces = append(c.traces, t)
+	c.mu.Unlock()
+}
+
+func (c *debugCollector) drain() ([]LuaLogEntry, []LuaTrace) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	logs, traces := c.logs, c.traces
+	c.logs, c.traces = nil, nil
+	sort.SliceStable(logs, func(i, j int) bool { return logs[i].Locator < logs[j].Locator })
+	sort.SliceStable(traces, func(i, j int) bool { return traces[i].Locator < traces[j].Locator })
+	return logs, traces
+}
+
+func luaLogRoute(meta *Meta) string {
+	if meta == nil || meta.LuaSandbox == nil || meta.LuaSandbox.Log == "" {
+		return luaLogStderr
+	}
+	return meta.LuaSandbox.Log
+}
+
+// addLuaLog records a thoth.log call unless lua.log is "off".
+func addLuaLog(meta *Meta, stageName, locator, level, msg string) {
+	if meta == nil || luaLogRoute(meta) == luaLogOff {
+		return
+	}
+	meta.debug.addLog(LuaLogEntry{Stage: stageName, Locator: locator, Level: level, Message: msg})
+}
+
+func luaTraceEnabled(meta *Meta, locator string) bool {
+	if meta == nil || meta.debug == nil || locator == "" {
+		return false
+	}
+	for _, l := range meta.TraceLocators {
+		if l == locator {
+			return true
+		}
+	}
+	return false
+}
+
+// flushLuaDebug writes collected debug output to w, or into out.Debug when
+// lua.log is "envelope". Traces are still written when lua.log is "off".
+func flushLuaDebug(out *Envelope, meta *Meta, c *debugCollector, w io.Writer) {
+	logs, traces := c.drain()
+	if len(logs) == 0 && len(traces) == 0 {
+		return
+	}
+	if luaLogRoute(meta) == luaLogEnvelope {
+		if out == nil {
+			return
+		}
+		if out.Debug == nil {
+			out.Debug = &DebugBlock{}
+		}
+		out.Debug.Logs = append(out.Debug.Logs, logs...)
+		out.Debug.Traces = append(out.Debug.Traces, traces...)
+		return
+	}
+	if w == nil {
+		return
+	}
+	var sb strings.Builder
+	for _, e := range logs {
+		sb.WriteString(debugLinePrefix(e.Locator, e.Stage, e.Level))
+		sb.WriteString(e.Message)
+		sb.WriteByte('\n')
+	}
+	for _, t := range traces {
+		b, err := json.Marshal(map[string]any{"input": t.Input, "output": t.Output, "error": t.Error})
+		if err != nil {
+			b = []byte(fmt.Sprintf("%q", err.Error()))
+		}
+		sb.WriteString(debugLinePrefix(t.Locator, t.Stage, "trace"))
+		sb.Write(b)
+		sb.WriteByte('\n')
+	}
+	_, _ = io.WriteString(w, sb.String())
+}
+
+func debugLinePrefix(locator, stageName, level string) string {
+	if locator == "" {
+		return fmt.Sprintf("%s %s: ", stageName, level)
+	}
+	return fmt.Sprintf("%s: %s %s: ", locator, stageName, level)
+}
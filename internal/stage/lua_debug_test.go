@@ -0,0 +1,124 @@
+package stage
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func logTestEnvelope(route string, traced ...string) Envelope {
+	return Envelope{
+		Records: []Record{
+			{Locator: "b", Meta: map[string]any{"n": 2}},
+			{Locator: "a", Meta: map[string]any{"n": 1}},
+		},
+		Meta: &Meta{
+			Lua:           &LuaMeta{MapInline: "thoth.log('info', 'n is', meta.n); thoth.log('debug', 'done'); return meta.n"},
+			LuaSandbox:    &LuaSandboxMeta{Libs: LuaSandboxLibsMeta{Base: true, String: true, Table: true, Math: true}, Log: route},
+			TraceLocators: traced,
+		},
+	}
+}
+
+func TestThothLog_StderrIsLocatorPrefixedAndSorted(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := Run(context.Background(), luaMapStage, logTestEnvelope(""), Deps{Stderr: &buf})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	want := "a: lua-map info: n is 1\n" +
+		"a: lua-map debug: done\n" +
+		"b: lua-map info: n is 2\n" +
+		"b: lua-map debug: done\n"
+	if buf.String() != want {
+		t.Fatalf("stderr=%q want %q", buf.String(), want)
+	}
+	if out.Debug != nil || out.Meta.debug != nil {
+		t.Fatalf("stderr routing must not embed debug output: %+v", out.Debug)
+	}
+}
+
+func TestThothLog_EnvelopeAndOffRouting(t *testing.T) {
+	var buf bytes.Buffer
+	out, err := Run(context.Background(), luaMapStage, logTestEnvelope("envelope"), Deps{Stderr: &buf})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("envelope routing wrote to stderr: %q", buf.String())
+	}
+	if out.Debug == nil || len(out.Debug.Logs) != 4 ||
+		out.Debug.Logs[0] != (LuaLogEntry{Stage: luaMapStage, Locator: "a", Level: "info", Message: "n is 1"}) {
+		t.Fatalf("unexpected debug block: %+v", out.Debug)
+	}
+
+	out, err = Run(context.Background(), luaMapStage, logTestEnvelope("off"), Deps{Stderr: &buf})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if buf.Len() != 0 || out.Debug != nil {
+		t.Fatalf("off routing emitted output: %q %+v", buf.String(), out.Debug)
+	}
+}
+
+func TestThothLog_InvalidLevelFailsRecord(t *testing.T) {
+	in := logTestEnvelope("")
+	in.Meta.Lua.MapInline = "thoth.log('loud', 'x'); return 1"
+	_, err := Run(context.Background(), luaMapStage, in, Deps{})
+	if err == nil || !strings.Contains(err.Error(), "thoth.log: invalid level 'loud'") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestTraceLocator_CapturesInputsAndOutput(t *testing.T) {
+	in := logTestEnvelope("envelope", "b")
+	out, err := Run(context.Background(), luaMapStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if out.Debug == nil || len(out.Debug.Traces) != 1 {
+		t.Fatalf("expected one trace, got %+v", out.Debug)
+	}
+	tr := out.Debug.Traces[0]
+	if tr.Stage != luaMapStage || tr.Locator != "b" || tr.Input["locator"] != "b" || tr.Output != float64(2) {
+		t.Fatalf("unexpected trace: %+v", tr)
+	}
+
+	var buf bytes.Buffer
+	in = logTestEnvelope("off", "a")
+	if _, err := Run(context.Background(), luaMapStage, in, Deps{Stderr: &buf}); err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "a: lua-map trace: {") || strings.Count(buf.String(), "\n") != 1 {
+		t.Fatalf("unexpected trace output: %q", buf.String())
+	}
+}
+
+func TestValidateConfig_LuaLog(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  lua: { log: \"envelope\" }\n}\n"
+	out, err := runValidateConfigWithContent(t, "lua_log_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.LuaSandbox == nil || out.Meta.LuaSandbox.Log != "envelope" {
+		t.Fatalf("unexpected sandbox meta: %+v", out.Meta.LuaSandbox)
+	}
+	bad := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  lua: { log: \"file\" }\n}\n"
+	_, err = runValidateConfigWithContent(t, "lua_log_bad_validate_test.cue", bad)
+	if err == nil || err.Error() != "invalid lua.log: must be 'stderr', 'envelope', or 'off'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestMeta_TraceLocatorsNotSerialized(t *testing.T) {
+	b, err := encodeJSONCompact(Meta{TraceLocators: []string{"a"}})
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if strings.Contains(string(b), "traceLocators") {
+		t.Fatalf("trace locators leaked into output: %s", b)
+	}
+}
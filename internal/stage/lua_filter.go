@@ -26,9 +26,10 @@ func luaFilterRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, err
 	outs := make([]Record, n)
 	var envErrs []Error
 	workers := getWorkers(in.Meta)
+	luaMeta := withRecordSnapshot(in.Meta, in.Records)
 	results := runIndexedParallel(n, workers, func(idx int) luaFilterRes {
 		r := in.Records[idx]
-		keep, outRec, envE, fatal := processLuaFilterRecord(r, pred, mode, in.Meta)
+		keep, outRec, envE, fatal := processLuaFilterRecord(r, pred, mode, luaMeta)
 		return luaFilterRes{idx: idx, keep: keep, out: outRec, envE: envE, fatal: fatal}
 	})
 	var firstErr error
@@ -23,9 +23,10 @@ func luaMapRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error)
 	mode, _ := errorMode(in.Meta)
 	n := len(in.Records)
 	workers := getWorkers(in.Meta)
+	luaMeta := withRecordSnapshot(in.Meta, in.Records)
 	results := runIndexedParallel(n, workers, func(idx int) recordParallelRes {
 		r := in.Records[idx]
-		rec, envE, fatal := processLuaMapRecord(r, code, mode, in.Meta)
+		rec, envE, fatal := processLuaMapRecord(r, code, mode, luaMeta)
 		return recordParallelRes{idx: idx, rec: rec, envE: envE, fatal: fatal}
 	})
 	return mergeRecordParallelResults(out, results)
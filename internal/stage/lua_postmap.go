@@ -23,8 +23,9 @@ func luaPostMapRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, er
 	}
 
 	code := buildLuaPostMapCode(in)
+	luaMeta := withRecordSnapshot(in.Meta, in.Records)
 	return runPostMapParallel(in, mode, func(r Record) (Record, *Error, error) {
-		return processLuaPostMapRecord(r, code, mode, in.Meta)
+		return processLuaPostMapRecord(r, code, mode, luaMeta)
 	})
 }
 
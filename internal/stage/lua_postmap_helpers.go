@@ -40,10 +40,12 @@ func processDefaultPostMapRecord(rec Record, mode string) (Record, *Error, error
 	type postResult struct {
 		Locator string `json:"locator"`
 		Exit    int    `json:"exit,omitempty"`
+		Skipped bool   `json:"skipped,omitempty"`
 	}
 	pr := postResult{Locator: rec.Locator}
 	if rec.Shell != nil {
 		pr.Exit = rec.Shell.ExitCode
+		pr.Skipped = rec.Shell.Skipped
 	}
 	rec.Post = pr
 	return rec, nil, nil
@@ -56,6 +58,7 @@ func processLuaPostMapRecord(rec Record, code string, mode string, metaCfg *Meta
 		shellMap = map[string]any{
 			"exitCode": rec.Shell.ExitCode,
 			"timedOut": rec.Shell.TimedOut,
+			"skipped":  rec.Shell.Skipped,
 		}
 		if rec.Shell.Stdout != nil {
 			shellMap["stdout"] = *rec.Shell.Stdout
@@ -69,6 +72,21 @@ func processLuaPostMapRecord(rec Record, code string, mode string, metaCfg *Meta
 		if rec.Shell.Error != nil {
 			shellMap["error"] = *rec.Shell.Error
 		}
+		if rec.Shell.LimitExceeded != "" {
+			shellMap["limitExceeded"] = rec.Shell.LimitExceeded
+		}
+		if rec.Shell.Attempts > 0 {
+			shellMap["attempts"] = rec.Shell.Attempts
+		}
+		if rec.Shell.DecodeError != nil {
+			shellMap["decodeError"] = rec.Shell.DecodeError.luaView()
+		}
+		if rec.Shell.Artifacts != nil {
+			shellMap["artifacts"] = rec.Shell.Artifacts
+		}
+		if steps := shellStepsView(rec.Shell.Steps); steps != nil {
+			shellMap["steps"] = steps
+		}
 	}
 	luaCtx := luaRecordContext(rec)
 	luaCtx["mapped"] = rec.Mapped
@@ -1,12 +1,12 @@
 // File Guide for dev/ai agents:
-// Purpose: Run the final Lua reduce stage that collapses per-record results into one aggregate value.
+// Purpose: Run the Lua reduce stage that folds all records into meta.reduced.
 // Responsibilities:
-// - Provide the default aggregate behavior when no reduce script is configured.
-// - Execute the configured reduce Lua across the current record set.
-// - Store the aggregate result in meta.reduced for final output.
+// - Fall back to a record count when no reducer is configured.
+// - Run single, grouped, or named reducers in one ordered pass over the records.
+// - Store the final accumulator(s) on the envelope metadata.
 // Architecture notes:
-// - Reduced output lives in meta.reduced by contract so records remain available alongside the aggregate summary.
-// - The default reduction is intentionally simple record counting to keep the stage predictable even when configured but unused.
+// - Reduce runs sequentially on purpose; accumulators depend on record order.
+// - Grouped results are plain maps, which JSON output already serializes with sorted keys.
 package stage
 
 import (
@@ -15,14 +15,13 @@ import (
 
 func luaReduceRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error) {
 	// Default: count records
-	if in.Meta == nil || in.Meta.Lua == nil || in.Meta.Lua.ReduceInline == "" {
+	if !luaReduceConfigured(in.Meta) {
 		out := in
 		out.Meta.Reduced = len(in.Records)
 		return out, nil
 	}
-	code := buildLuaReduceCode(in)
 
-	acc, err := runLuaReduce(in, code)
+	acc, err := runLuaReducers(in, buildLuaReducers(in))
 	if err != nil {
 		return Envelope{}, err
 	}
@@ -35,4 +34,8 @@ func luaReduceRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, err
 	return out, nil
 }
 
+func luaReduceConfigured(meta *Meta) bool {
+	return meta != nil && meta.Lua != nil && (meta.Lua.ReduceInline != "" || len(meta.Lua.Reducers) > 0)
+}
+
 func init() { Register("lua-reduce", luaReduceRunner) }
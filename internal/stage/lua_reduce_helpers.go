@@ -3,7 +3,7 @@
 // Responsibilities:
 // - Normalize reduce code into runnable Lua.
 // - Convert each record into the reducer item shape.
-// - Run the reducer deterministically across records and return the final accumulator.
+// - Run single, grouped, and named reducers deterministically across records in one pass.
 // Architecture notes:
 // - Reduce order matters; this file relies on the current record order being deterministic before reduction starts.
 // - The reducer input prefers `post` when present, which is intentional because postMap is the last per-record shaping step.
@@ -11,19 +11,48 @@ package stage
 
 import (
 	"fmt"
+	"strconv"
 )
 
 const luaReduceStage = "lua-reduce"
 
-// buildLuaReduceCode returns the Lua reduce code, wrapping expressions without explicit return.
-func buildLuaReduceCode(in Envelope) string {
-	code := in.Meta.Lua.ReduceInline
+// luaReducer is one compiled reducer. name is empty for the single
+// unnamed reduce; groupBy is empty when the reducer is not grouped.
+type luaReducer struct {
+	name    string
+	code    string
+	groupBy string
+}
+
+// wrapLuaReduceCode wraps expressions without explicit return.
+func wrapLuaReduceCode(code string) string {
 	if !containsReturn(code) {
 		code = "return (" + code + ")"
 	}
 	return code
 }
 
+// buildLuaReducers returns the configured reducers in config order.
+func buildLuaReducers(in Envelope) []luaReducer {
+	lm := in.Meta.Lua
+	if len(lm.Reducers) == 0 {
+		r := luaReducer{code: wrapLuaReduceCode(lm.ReduceInline)}
+		if lm.ReduceGroupBy != "" {
+			r.groupBy = wrapLuaReduceCode(lm.ReduceGroupBy)
+		}
+		return []luaReducer{r}
+	}
+	out := make([]luaReducer, 0, len(lm.Reducers))
+	for _, rm := range lm.Reducers {
+		r := luaReducer{name: rm.Name, code: wrapLuaReduceCode(rm.Inline)}
+		if rm.GroupBy != "" {
+			r.groupBy = wrapLuaReduceCode(rm.GroupBy)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
 // reduceItemFromRecord converts a Record to the item value expected by the reducer.
 func reduceItemFromRecord(rec Record) any {
 	if rec.Post != nil {
@@ -40,6 +69,7 @@ func reduceItemFromRecord(rec Record) any {
 		sm := map[string]any{
 			"exitCode": rec.Shell.ExitCode,
 			"timedOut": rec.Shell.TimedOut,
+			"skipped":  rec.Shell.Skipped,
 		}
 		if rec.Shell.Stdout != nil {
 			sm["stdout"] = *rec.Shell.Stdout
@@ -58,32 +88,104 @@ func reduceItemFromRecord(rec Record) any {
 	return m
 }
 
-// runLuaReduce executes the reduce across all records and returns the final accumulator.
-func runLuaReduce(in Envelope, code string) (any, error) {
-	var acc any
+// runLuaReducers folds all records through every reducer in a single pass
+// and returns the final accumulator. With one unnamed reducer the result is
+// its accumulator; named reducers produce a map keyed by reducer name.
+// Grouped reducers keep one accumulator per group key.
+func runLuaReducers(in Envelope, reducers []luaReducer) (any, error) {
+	type state struct {
+		acc    any
+		groups map[string]any
+	}
+	states := make([]state, len(reducers))
+	for i, r := range reducers {
+		if r.groupBy != "" {
+			states[i].groups = map[string]any{}
+		}
+	}
+	luaMeta := withRecordSnapshot(in.Meta, in.Records)
 	for _, rec := range in.Records {
 		item := reduceItemFromRecord(rec)
 		locator := rec.Locator
 		if locator == "" {
 			locator = "reduce"
 		}
-		ret, violation, err := runLuaScriptWithSandbox(luaReduceStage, in.Meta, locator, map[string]any{
-			"acc":  acc,
-			"item": item,
-		}, code)
-		if err != nil {
-			return nil, fmt.Errorf(
-				"lua-reduce: %s",
-				formatLuaError(luaReduceStage, locator, code, err.Error()),
-			)
+		for i, r := range reducers {
+			st := &states[i]
+			if r.groupBy == "" {
+				acc, err := runLuaReduceStep(luaMeta, r, r.code, locator, map[string]any{"acc": st.acc, "item": item})
+				if err != nil {
+					return nil, err
+				}
+				st.acc = acc
+				continue
+			}
+			key, err := runLuaReduceStep(luaMeta, r, r.groupBy, locator, map[string]any{"item": item})
+			if err != nil {
+				return nil, err
+			}
+			if key == nil {
+				continue
+			}
+			group, ok := reduceGroupKey(key)
+			if !ok {
+				return nil, luaReduceError(r, fmt.Sprintf("groupBy must return a string, number, or nil (%s)", locator))
+			}
+			acc, err := runLuaReduceStep(luaMeta, r, r.code, locator, map[string]any{"acc": st.groups[group], "item": item, "group": group})
+			if err != nil {
+				return nil, err
+			}
+			st.groups[group] = acc
 		}
-		if violation != "" {
-			return nil, luaViolationFailFast(
-				luaReduceStage,
-				formatLuaError(luaReduceStage, locator, code, violation),
-			)
+	}
+	result := func(i int) any {
+		if states[i].groups != nil {
+			return states[i].groups
 		}
-		acc = ret
+		return states[i].acc
+	}
+	if len(reducers) == 1 && reducers[0].name == "" {
+		return result(0), nil
+	}
+	out := make(map[string]any, len(reducers))
+	for i, r := range reducers {
+		out[r.name] = result(i)
+	}
+	return out, nil
+}
+
+func runLuaReduceStep(meta *Meta, r luaReducer, code, locator string, globals map[string]any) (any, error) {
+	ret, violation, err := runLuaScriptWithSandbox(luaReduceStage, meta, locator, globals, code)
+	if err != nil {
+		return nil, luaReduceError(r, formatLuaError(luaReduceStage, locator, code, err.Error()))
+	}
+	if violation != "" {
+		return nil, luaViolationFailFast(
+			luaReduceStage,
+			reducerPrefix(r)+formatLuaError(luaReduceStage, locator, code, violation),
+		)
+	}
+	return ret, nil
+}
+
+func luaReduceError(r luaReducer, msg string) error {
+	return fmt.Errorf("lua-reduce: %s%s", reducerPrefix(r), msg)
+}
+
+func reducerPrefix(r luaReducer) string {
+	if r.name == "" {
+		return ""
+	}
+	return "reducer " + r.name + ": "
+}
+
+func reduceGroupKey(v any) (string, bool) {
+	switch x := v.(type) {
+	case string:
+		return x, true
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), true
+	default:
+		return "", false
 	}
-	return acc, nil
 }
@@ -0,0 +1,87 @@
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func reduceEnvelope(lm *LuaMeta) Envelope {
+	meta := defaultLuaSandboxForTest()
+	meta.Lua = lm
+	return Envelope{
+		Records: []Record{
+			{Locator: "cmd/main.go", Post: map[string]any{"lang": "go", "dir": "cmd", "fns": 2}},
+			{Locator: "web/app.ts", Post: map[string]any{"lang": "ts", "dir": "web", "fns": 5}},
+			{Locator: "cmd/util.go", Post: map[string]any{"lang": "go", "dir": "cmd", "fns": 3}},
+			{Locator: "README.md", Post: map[string]any{"dir": "."}},
+		},
+		Meta: meta,
+	}
+}
+
+func reducedJSON(t *testing.T, in Envelope) string {
+	t.Helper()
+	out, err := Run(context.Background(), "lua-reduce", in, Deps{})
+	if err != nil {
+		t.Fatalf("lua-reduce: %v", err)
+	}
+	b, err := json.Marshal(out.Meta.Reduced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestLuaReduce_GroupBy(t *testing.T) {
+	got := reducedJSON(t, reduceEnvelope(&LuaMeta{
+		ReduceInline:  "return (acc or 0) + (item.fns or 0)",
+		ReduceGroupBy: "item.lang",
+	}))
+	if got != `{"go":5,"ts":5}` {
+		t.Fatalf("unexpected grouped reduce: %s", got)
+	}
+}
+
+func TestLuaReduce_NamedReducersOnePass(t *testing.T) {
+	got := reducedJSON(t, reduceEnvelope(&LuaMeta{Reducers: []ReducerMeta{
+		{Name: "total", Inline: "return (acc or 0) + 1"},
+		{Name: "byDir", Inline: "local a = acc or {}; thoth.push(a, item.dir .. ':' .. group); return a", GroupBy: "return item.dir"},
+	}}))
+	want := `{"byDir":{".":[".:."],"cmd":["cmd:cmd","cmd:cmd"],"web":["web:web"]},"total":4}`
+	if got != want {
+		t.Fatalf("got %s want %s", got, want)
+	}
+}
+
+func TestLuaReduce_GroupByRejectsTableAndBoolKeys(t *testing.T) {
+	for _, groupBy := range []string{"return {}", "return item.dir == 'cmd'"} {
+		in := reduceEnvelope(&LuaMeta{Reducers: []ReducerMeta{{Name: "bad", Inline: "return acc", GroupBy: groupBy}}})
+		_, err := Run(context.Background(), "lua-reduce", in, Deps{})
+		if err == nil || err.Error() != "lua-reduce: reducer bad: groupBy must return a string, number, or nil (cmd/main.go)" {
+			t.Fatalf("%s: unexpected error: %v", groupBy, err)
+		}
+	}
+}
+
+func TestValidateConfig_ParsesNamedReducers(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  reduce: { byLang: { inline: \"acc\", groupBy: \"item.lang\" }, total: { inline: \"(acc or 0) + 1\" } }\n}\n"
+	out, err := runValidateConfigWithContent(t, "reduce_named_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	rs := out.Meta.Lua.Reducers
+	if len(rs) != 2 || rs[0].Name != "byLang" || rs[0].GroupBy != "item.lang" || rs[1].Name != "total" {
+		t.Fatalf("unexpected reducers: %+v", rs)
+	}
+
+	mixed := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  reduce: { inline: \"acc\", total: { inline: \"acc\" } }\n}\n"
+	_, err = runValidateConfigWithContent(t, "reduce_mixed_validate_test.cue", mixed)
+	if err == nil || err.Error() != "invalid reduce: use either inline/groupBy or named reducers, not both" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -3,10 +3,11 @@
 // Responsibilities:
 // - Build sandbox configuration from envelope metadata.
 // - Initialize the Lua VM with only the allowed libraries and deterministic helpers.
-// - Execute Lua code under timeout, instruction, and memory constraints.
+// - Execute Lua code under timeout, instruction, and memory constraints on pooled states (lua_state_pool.go).
 // Architecture notes:
 // - This file is the enforcement boundary for Lua safety; do not casually widen the exposed library surface here.
-// - Deterministic random seeding is intentional so tests and repeated runs stay stable per stage/locator.
+// - Per-run sinks (thoth.warn, thoth.log, lookups, file reads, the pinned clock) are rebound on every run; --trace-locator tracing wraps the run without touching the state.
+// - Deterministic random seeding is intentional so tests and repeated runs stay stable per stage/locator; it is re-applied on every run because states are reused.
 // - Sandbox violations are translated into fixed strings because downstream stages and tests depend on them.
 package stage
 
@@ -54,6 +55,8 @@ func luaSandboxFromMeta(meta *Meta) LuaSandboxMeta {
 	}
 	cfg.Libs = in.Libs
 	cfg.DeterministicRandom = in.DeterministicRandom
+	cfg.FileRead = in.FileRead
+	cfg.FileReadMaxBytes = in.FileReadMaxBytes
 	return cfg
 }
 
@@ -87,6 +90,7 @@ func newSandboxLuaState(stage, locator string, cfg LuaSandboxMeta) *lua.LState {
 		installDeterministicRandom(L, seed)
 	}
 	installThothLib(L)
+	bindThothDataLimits(L, cfg.MemoryLimitBytes)
 	return L
 }
 
@@ -234,27 +238,61 @@ func estimateValueSize(v any, depth int) int {
 }
 
 func runLuaScriptWithSandbox(stage string, meta *Meta, locator string, globals map[string]any, code string) (any, string, error) {
+	var input map[string]any
+	if luaTraceEnabled(meta, locator) {
+		input, _ = deepCopyAny(globals).(map[string]any)
+	}
+	out, violation, err := execLuaScriptWithSandbox(stage, meta, locator, globals, code)
+	if input != nil {
+		t := LuaTrace{Stage: stage, Locator: locator, Input: input, Output: out, Error: violation}
+		if err != nil {
+			t.Error = err.Error()
+		}
+		meta.debug.addTrace(t)
+	}
+	return out, violation, err
+}
+
+func execLuaScriptWithSandbox(stage string, meta *Meta, locator string, globals map[string]any, code string) (any, string, error) {
 	cfg := luaSandboxFromMeta(meta)
 
-	L := newSandboxLuaState(stage, locator, cfg)
-	defer L.Close()
+	proto, err := compileLuaProto(code)
+	if err != nil {
+		return nil, "", err
+	}
+	ps := acquireSandboxLuaState(cfg)
+	clean := false
+	defer func() { releaseSandboxLuaState(cfg, ps, clean) }()
+	L := ps.L
+	if cfg.Libs.Math && cfg.DeterministicRandom {
+		installDeterministicRandom(L, deterministicSeed(stage, locator))
+	}
+	bindThothDataLimits(L, cfg.MemoryLimitBytes)
+	bindThothWarn(L, func(msg string) {
+		addWarning(meta, stage, locator, msg)
+	})
+	bindThothLog(L, func(level, msg string) {
+		addLuaLog(meta, stage, locator, level, msg)
+	})
+	bindThothRecords(L, meta)
+	bindThothTime(L, luaRunNow(meta))
+	if cfg.FileRead {
+		bindThothFileRead(L, luaFileRootFromMeta(meta), cfg.FileReadMaxBytes)
+	}
 	L.SetInstructionLimit(cfg.InstructionLimit)
 
 	if cfg.TimeoutMs > 0 {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.TimeoutMs)*time.Millisecond)
 		defer cancel()
 		L.SetContext(ctx)
+		defer L.RemoveContext()
 	}
 
 	for k, v := range globals {
 		L.SetGlobal(k, toLValue(L, v))
 	}
 
-	fn, err := L.LoadString(code)
-	if err != nil {
-		return nil, "", err
-	}
-	L.Push(fn)
+	L.Push(L.NewFunctionFromProto(proto))
 	if err := L.PCall(0, 1, nil); err != nil {
 		if isTimeoutError(err) {
 			return nil, sandboxTimeoutViolation, nil
@@ -262,7 +300,8 @@ func runLuaScriptWithSandbox(stage string, meta *Meta, locator string, globals m
 		if isInstructionLimitError(err) {
 			return nil, sandboxInstructionViolation, nil
 		}
-		if strings.Contains(strings.ToLower(err.Error()), "registry overflow") {
+		if strings.Contains(strings.ToLower(err.Error()), "registry overflow") ||
+			strings.Contains(err.Error(), sandboxMemoryViolation) {
 			return nil, sandboxMemoryViolation, nil
 		}
 		return nil, "", err
@@ -270,6 +309,7 @@ func runLuaScriptWithSandbox(stage string, meta *Meta, locator string, globals m
 	ret := L.Get(-1)
 	L.Pop(1)
 	out := fromLValue(ret)
+	clean = true
 	if cfg.MemoryLimitBytes > 0 && estimateValueSize(out, 0) > cfg.MemoryLimitBytes {
 		return nil, sandboxMemoryViolation, nil
 	}
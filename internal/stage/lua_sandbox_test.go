@@ -159,3 +159,44 @@ func TestLuaSandbox_ThothEndsWith(t *testing.T) {
 		t.Fatalf("expected test=false, got %#v", mapped["test"])
 	}
 }
+
+func TestLuaSandbox_PooledStatesDoNotLeakGlobals(t *testing.T) {
+	meta := defaultLuaSandboxForTest()
+	code := `local seen = leaked
+leaked = locator
+thoth.ends_with = nil
+string.upper = nil
+setmetatable(thoth, { __index = function() return "x" end })
+return { seen = seen, r = math.random(1000) }`
+	first := map[string]any{}
+	for i := 0; i < 3; i++ {
+		for _, loc := range []string{"a", "b"} {
+			rec, _, err := processLuaMapRecord(Record{Locator: loc, Meta: map[string]any{}}, code, "fail-fast", meta)
+			if err != nil {
+				t.Fatalf("run %d %s: %v", i, loc, err)
+			}
+			m := rec.Mapped.(map[string]any)
+			if _, ok := m["seen"]; ok {
+				t.Fatalf("global leaked between runs: %#v", m)
+			}
+			if i == 0 {
+				first[loc] = m["r"]
+			} else if first[loc] != m["r"] {
+				t.Fatalf("random sequence not reset for %s: %v vs %v", loc, first[loc], m["r"])
+			}
+		}
+	}
+	rec, _, err := processLuaMapRecord(
+		Record{Locator: "c.go", Meta: map[string]any{}},
+		"return { go = thoth.ends_with(locator, '.go'), up = string.upper('a'), missing = thoth.nope }",
+		"fail-fast",
+		meta,
+	)
+	if err != nil {
+		t.Fatalf("expected restored library, got %v", err)
+	}
+	m := rec.Mapped.(map[string]any)
+	if m["go"] != true || m["up"] != "A" || m["missing"] != nil {
+		t.Fatalf("library not restored: %#v", m)
+	}
+}
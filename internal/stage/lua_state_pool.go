@@ -0,0 +1,138 @@
+// File Guide for dev/ai agents:
+// Purpose: Amortize Lua setup cost by compiling scripts once and reusing sandboxed states across records.
+// Responsibilities:
+// - Cache compiled FunctionProtos by source so each inline script is parsed once per process.
+// - Pool sandbox states per sandbox configuration and hand one to each concurrently running worker.
+// - Restore every global and every reachable library table to its pristine contents before a state is reused.
+// Architecture notes:
+// - FunctionProtos are immutable after compilation, so one proto is safely shared by all pooled states.
+// - Only states that finished a script cleanly go back to the pool; errors and sandbox violations close the state, so a half-unwound VM is never reused.
+// - Per-run bindings (random seed, warn sink, file reader, record snapshot, limits) are re-applied by runLuaScriptWithSandbox after acquire.
+package stage
+
+import (
+	"strings"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// luaChunkName matches the chunk name used by LState.LoadString so compile
+// errors keep their existing text.
+const luaChunkName = "<string>"
+
+var luaProtos = struct {
+	mu     sync.Mutex
+	protos map[string]*lua.FunctionProto
+}{protos: map[string]*lua.FunctionProto{}}
+
+func compileLuaProto(code string) (*lua.FunctionProto, error) {
+	luaProtos.mu.Lock()
+	proto, ok := luaProtos.protos[code]
+	luaProtos.mu.Unlock()
+	if ok {
+		return proto, nil
+	}
+	chunk, err := parse.Parse(strings.NewReader(code), luaChunkName)
+	if err != nil {
+		return nil, err
+	}
+	proto, err = lua.Compile(chunk, luaChunkName)
+	if err != nil {
+		return nil, err
+	}
+	luaProtos.mu.Lock()
+	luaProtos.protos[code] = proto
+	luaProtos.mu.Unlock()
+	return proto, nil
+}
+
+// pooledLuaState is a sandbox state plus a snapshot of its pristine tables.
+type pooledLuaState struct {
+	L          *lua.LState
+	pristine   map[*lua.LTable]map[lua.LValue]lua.LValue
+	metatables map[*lua.LTable]lua.LValue
+}
+
+var luaStatePools = struct {
+	mu    sync.Mutex
+	pools map[LuaSandboxMeta]*sync.Pool
+}{pools: map[LuaSandboxMeta]*sync.Pool{}}
+
+func luaStatePool(cfg LuaSandboxMeta) *sync.Pool {
+	luaStatePools.mu.Lock()
+	defer luaStatePools.mu.Unlock()
+	p, ok := luaStatePools.pools[cfg]
+	if !ok {
+		p = &sync.Pool{New: func() any {
+			L := newSandboxLuaState("", "", cfg)
+			ps := &pooledLuaState{L: L, pristine: snapshotLuaTables(L), metatables: map[*lua.LTable]lua.LValue{}}
+			for t := range ps.pristine {
+				ps.metatables[t] = L.GetMetatable(t)
+			}
+			return ps
+		}}
+		luaStatePools.pools[cfg] = p
+	}
+	return p
+}
+
+func acquireSandboxLuaState(cfg LuaSandboxMeta) *pooledLuaState {
+	return luaStatePool(cfg).Get().(*pooledLuaState)
+}
+
+// releaseSandboxLuaState returns a cleanly finished state to its pool after
+// resetting it, or closes it.
+func releaseSandboxLuaState(cfg LuaSandboxMeta, ps *pooledLuaState, clean bool) {
+	if !clean {
+		ps.L.Close()
+		return
+	}
+	ps.L.SetTop(0)
+	ps.restore()
+	luaStatePool(cfg).Put(ps)
+}
+
+// snapshotLuaTables records the contents of _G, every table reachable from
+// it, and the string metatable.
+func snapshotLuaTables(L *lua.LState) map[*lua.LTable]map[lua.LValue]lua.LValue {
+	out := map[*lua.LTable]map[lua.LValue]lua.LValue{}
+	var walk func(t *lua.LTable)
+	walk = func(t *lua.LTable) {
+		if _, seen := out[t]; seen {
+			return
+		}
+		fields := map[lua.LValue]lua.LValue{}
+		out[t] = fields
+		t.ForEach(func(k, v lua.LValue) {
+			fields[k] = v
+			if sub, ok := v.(*lua.LTable); ok {
+				walk(sub)
+			}
+		})
+	}
+	walk(L.G.Global)
+	if mt, ok := L.GetMetatable(lua.LString("")).(*lua.LTable); ok {
+		walk(mt)
+	}
+	return out
+}
+
+func (ps *pooledLuaState) restore() {
+	for t, fields := range ps.pristine {
+		var extra []lua.LValue
+		t.ForEach(func(k, _ lua.LValue) {
+			if _, ok := fields[k]; !ok {
+				extra = append(extra, k)
+			}
+		})
+		for _, k := range extra {
+			t.RawSet(k, lua.LNil)
+		}
+		for k, v := range fields {
+			t.RawSet(k, v)
+		}
+		ps.L.SetMetatable(t, ps.metatables[t])
+	}
+}
@@ -0,0 +1,165 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide JSON and YAML encode/decode helpers for Lua scripts in the `thoth` library.
+// Responsibilities:
+// - Decode JSON/YAML strings into Lua tables using the same value mapping as record context.
+// - Encode Lua values into canonical JSON/YAML with sorted object keys.
+// - Enforce the sandbox memory budget on helper inputs and outputs.
+// Architecture notes:
+// - Helpers are built per sandbox with the configured byte budget; the plain `newThothLibTable` variant is unlimited for tests and tooling.
+// - Budget overruns raise an error containing the sandbox memory violation text so the runner reports them like any other memory violation.
+// - Decode failures return `nil, message` instead of raising, matching common Lua parser conventions.
+package stage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+	"gopkg.in/yaml.v3"
+)
+
+// registerThothDataHelpers installs codec, hash (lua_thoth_hash.go), and
+// regex helpers on tbl. A
+// maxBytes of 0 disables the byte budget.
+func registerThothDataHelpers(L *lua.LState, tbl *lua.LTable, maxBytes int) {
+	budget := luaByteBudget{maxBytes: maxBytes}
+	tbl.RawSetString("json_decode", L.NewFunction(budget.jsonDecode))
+	tbl.RawSetString("json_encode", L.NewFunction(budget.jsonEncode))
+	tbl.RawSetString("yaml_decode", L.NewFunction(budget.yamlDecode))
+	tbl.RawSetString("yaml_encode", L.NewFunction(budget.yamlEncode))
+	tbl.RawSetString("sha256", L.NewFunction(budget.sha256))
+	tbl.RawSetString("fnv64", L.NewFunction(budget.fnv64))
+	tbl.RawSetString("base64_encode", L.NewFunction(budget.base64Encode))
+	tbl.RawSetString("base64_decode", L.NewFunction(budget.base64Decode))
+	re := newLuaRegexHelpers(budget)
+	tbl.RawSetString("re_match", L.NewFunction(re.match))
+	tbl.RawSetString("re_find_all", L.NewFunction(re.findAll))
+	tbl.RawSetString("re_replace", L.NewFunction(re.replace))
+}
+
+// bindThothDataLimits re-registers the data helpers on the global thoth
+// table with the sandbox memory budget.
+func bindThothDataLimits(L *lua.LState, maxBytes int) {
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	registerThothDataHelpers(L, thoth, maxBytes)
+}
+
+type luaByteBudget struct {
+	maxBytes int
+}
+
+func (b luaByteBudget) check(L *lua.LState, fn string, n int) {
+	if b.maxBytes > 0 && n > b.maxBytes {
+		L.RaiseError("%s: %s", fn, sandboxMemoryViolation)
+	}
+}
+
+func (b luaByteBudget) jsonDecode(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.json_decode", len(s))
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid JSON: " + err.Error()))
+		return 2
+	}
+	if dec.More() {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid JSON: trailing data"))
+		return 2
+	}
+	out := normalizeDecodedValue(v)
+	b.check(L, "thoth.json_decode", estimateValueSize(out, 0))
+	L.Push(toLValue(L, out))
+	return 1
+}
+
+func (b luaByteBudget) jsonEncode(L *lua.LState) int {
+	v := fromLValue(L.CheckAny(1))
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		L.RaiseError("thoth.json_encode: %v", err)
+		return 0
+	}
+	out := strings.TrimSuffix(buf.String(), "\n")
+	b.check(L, "thoth.json_encode", len(out))
+	L.Push(lua.LString(out))
+	return 1
+}
+
+func (b luaByteBudget) yamlDecode(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.yaml_decode", len(s))
+	var v any
+	if err := yaml.Unmarshal([]byte(s), &v); err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid YAML: " + err.Error()))
+		return 2
+	}
+	out := normalizeDecodedValue(v)
+	b.check(L, "thoth.yaml_decode", estimateValueSize(out, 0))
+	L.Push(toLValue(L, out))
+	return 1
+}
+
+func (b luaByteBudget) yamlEncode(L *lua.LState) int {
+	v := fromLValue(L.CheckAny(1))
+	data, err := yaml.Marshal(v)
+	if err != nil {
+		L.RaiseError("thoth.yaml_encode: %v", err)
+		return 0
+	}
+	b.check(L, "thoth.yaml_encode", len(data))
+	L.Push(lua.LString(string(data)))
+	return 1
+}
+
+// normalizeDecodedValue maps decoder output onto the value shapes toLValue
+// understands: string-keyed maps, []any, strings, bools, and float64.
+func normalizeDecodedValue(v any) any {
+	switch x := v.(type) {
+	case nil, string, bool, float64:
+		return x
+	case json.Number:
+		f, err := x.Float64()
+		if err != nil {
+			return x.String()
+		}
+		return f
+	case map[string]any:
+		out := make(map[string]any, len(x))
+		for k, vv := range x {
+			out[k] = normalizeDecodedValue(vv)
+		}
+		return out
+	case map[any]any:
+		out := make(map[string]any, len(x))
+		for k, vv := range x {
+			out[fmt.Sprint(k)] = normalizeDecodedValue(vv)
+		}
+		return out
+	case []any:
+		out := make([]any, len(x))
+		for i := range x {
+			out[i] = normalizeDecodedValue(x[i])
+		}
+		return out
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	default:
+		if f, ok := toFloat64(x); ok {
+			return f
+		}
+		return fmt.Sprint(x)
+	}
+}
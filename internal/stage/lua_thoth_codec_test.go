@@ -0,0 +1,124 @@
+package stage
+
+import (
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func runThothLibScript(t *testing.T, code string) []lua.LValue {
+	t.Helper()
+	L := newLuaStateWithThothLib(t)
+	defer L.Close()
+	top := L.GetTop()
+	if err := L.DoString(code); err != nil {
+		t.Fatalf("script failed: %v", err)
+	}
+	out := make([]lua.LValue, 0)
+	for i := top + 1; i <= L.GetTop(); i++ {
+		out = append(out, L.Get(i))
+	}
+	return out
+}
+
+func TestLuaThothJSON_RoundTripCanonical(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local v = thoth.json_decode('{"b":[1,2.5,"x"],"a":{"z":true,"y":null},"c":"<&>"}')
+return thoth.json_encode(v)
+`)
+	want := `{"a":{"z":true},"b":[1,2.5,"x"],"c":"<&>"}`
+	if len(got) != 1 || got[0].String() != want {
+		t.Fatalf("got %v want %s", got, want)
+	}
+}
+
+func TestLuaThothJSON_DecodeErrorReturnsNilAndMessage(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `return thoth.json_decode('{"a":')`)
+	if len(got) != 2 || got[0] != lua.LNil {
+		t.Fatalf("expected nil, message; got %v", got)
+	}
+	if got[1].String() != "invalid JSON: unexpected EOF" {
+		t.Fatalf("unexpected message: %q", got[1].String())
+	}
+	got = runThothLibScript(t, `return thoth.json_decode('1 2')`)
+	if len(got) != 2 || got[1].String() != "invalid JSON: trailing data" {
+		t.Fatalf("unexpected trailing data result: %v", got)
+	}
+}
+
+func TestLuaThothYAML_DecodeEncode(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local v = thoth.yaml_decode("name: svc\ntags: [a, b]\nport: 8080\n")
+return v.name, v.tags[2], v.port, thoth.yaml_encode({ b = 1, a = { "x" } })
+`)
+	if len(got) != 4 || got[0].String() != "svc" || got[1].String() != "b" || got[2].String() != "8080" {
+		t.Fatalf("unexpected decode: %v", got)
+	}
+	if got[3].String() != "a:\n    - x\nb: 1\n" {
+		t.Fatalf("unexpected encode: %q", got[3].String())
+	}
+}
+
+func TestLuaThothRegex_Helpers(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local ok = thoth.re_match("v1.2.3", "^v\\d+\\.\\d+\\.\\d+$")
+local words = thoth.re_find_all("a1 b22 c333", "[a-z]\\d+", 2)
+local pairs_ = thoth.re_find_all("k=v, x=y", "(\\w)=(\\w)")
+local replaced = thoth.re_replace("2024-01-31", "(\\d+)-(\\d+)-(\\d+)", "$3/$2/$1")
+return ok, table.concat(words, ","), #pairs_, pairs_[2][1], pairs_[2][3], replaced
+`)
+	want := []string{"true", "a1,b22", "2", "x=y", "y", "31/01/2024"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected results: %v", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("result[%d]=%q want %q", i, got[i].String(), want[i])
+		}
+	}
+}
+
+func TestLuaThothRegex_InvalidPatternRaises(t *testing.T) {
+	t.Parallel()
+	L := newLuaStateWithThothLib(t)
+	defer L.Close()
+	err := L.DoString(`return thoth.re_match("x", "(")`)
+	if err == nil || !strings.Contains(err.Error(), "thoth.re_match: invalid pattern") {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+}
+
+func TestLuaThothRegex_CacheIsBounded(t *testing.T) {
+	t.Parallel()
+	L := lua.NewState()
+	defer L.Close()
+	h := newLuaRegexHelpers(luaByteBudget{})
+	for i := 0; i < 3*luaRegexCacheMax; i++ {
+		h.compile(L, "thoth.re_match", "^x"+strings.Repeat("y", i)+"$")
+		if len(h.cache) > luaRegexCacheMax {
+			t.Fatalf("cache grew to %d entries", len(h.cache))
+		}
+	}
+	if re := h.compile(L, "thoth.re_match", "^a$"); !re.MatchString("a") {
+		t.Fatalf("unexpected compiled pattern after reset")
+	}
+}
+
+func TestLuaSandbox_DataHelpersRespectMemoryLimit(t *testing.T) {
+	meta := defaultLuaSandboxForTest()
+	meta.LuaSandbox.MemoryLimitBytes = 100000
+	_, _, err := processLuaMapRecord(
+		Record{Locator: "a", Meta: map[string]any{}},
+		"local s = thoth.json_encode(string.rep('a', 200000)); return #s",
+		"fail-fast",
+		meta,
+	)
+	if err == nil || err.Error() != "lua-map: sandbox memory limit" {
+		t.Fatalf("expected memory limit, got %v", err)
+	}
+}
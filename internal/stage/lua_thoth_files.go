@@ -0,0 +1,211 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide scoped, read-only file access to Lua scripts via thoth.read_file and thoth.read_lines.
+// Responsibilities:
+// - Resolve locators strictly inside the discovery root, rejecting absolute paths, '..' segments, and symlink escapes.
+// - Enforce a per-script byte budget shared by every read in one script invocation.
+// - Keep the helpers disabled unless luaSandbox.fileRead is set.
+// Architecture notes:
+// - The default thoth table only carries stubs that raise; runLuaScriptWithSandbox rebinds real readers when the toggle is on.
+// - Locator checks reuse violatesPathPolicy with absolute and parent references always denied, regardless of locatorPolicy.
+// - resolveRootedFile is Lua-free so shell-exec's `stdin.mode: "file"` and the shell plan confine paths the same way.
+// - Missing or unreadable files return `nil, message`; policy and budget violations raise, because they indicate a script bug.
+package stage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+const luaFileReadDisabled = "file access disabled (set lua.fileRead: true)"
+
+func registerThothFileReadStubs(L *lua.LState, tbl *lua.LTable) {
+	for _, name := range []string{"read_file", "read_lines"} {
+		fn := "thoth." + name
+		tbl.RawSetString(name, L.NewFunction(func(L *lua.LState) int {
+			L.RaiseError("%s: %s", fn, luaFileReadDisabled)
+			return 0
+		}))
+	}
+}
+
+func luaFileRootFromMeta(meta *Meta) string {
+	return determineRoot(Envelope{Meta: meta})
+}
+
+type luaFileReader struct {
+	root      string
+	remaining int
+}
+
+// bindThothFileRead replaces the read stubs on the global thoth table with
+// readers rooted at root and sharing a budget of maxBytes.
+func bindThothFileRead(L *lua.LState, root string, maxBytes int) {
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	if maxBytes <= 0 {
+		maxBytes = defaultLuaFileReadMaxBytes
+	}
+	r := &luaFileReader{root: root, remaining: maxBytes}
+	thoth.RawSetString("read_file", L.NewFunction(r.readFile))
+	thoth.RawSetString("read_lines", L.NewFunction(r.readLines))
+}
+
+// resolve maps a locator to a path under root. A non-empty message means the
+// file could not be opened and should be returned to the script.
+func (r *luaFileReader) resolve(L *lua.LState, fn, locator string) (string, string) {
+	target, err := resolveRootedFile(r.root, locator)
+	var policyErr *rootedFilePolicyError
+	if errors.As(err, &policyErr) {
+		L.RaiseError("%s: %s", fn, policyErr.msg)
+		return "", ""
+	}
+	if err != nil {
+		return "", err.Error()
+	}
+	return target, ""
+}
+
+// rootedFilePolicyError marks a locator that may never be read, as opposed
+// to a file that is merely missing or unreadable.
+type rootedFilePolicyError struct{ msg string }
+
+func (e *rootedFilePolicyError) Error() string { return e.msg }
+
+// resolveRootedFile maps a relative locator to a regular file under root,
+// following symlinks only while they stay inside root. Policy violations
+// are returned as *rootedFilePolicyError.
+func resolveRootedFile(root, locator string) (string, error) {
+	p := locatorPolicy{posix: true}
+	if bad, reason := violatesPathPolicy(locator, p); bad {
+		return "", &rootedFilePolicyError{msg: reason + ": " + locator}
+	}
+	if _, isURL := parseHTTPURLLocator(locator); isURL || locator == "" || filepath.IsAbs(locator) {
+		return "", &rootedFilePolicyError{msg: "locator must be a relative file path: " + locator}
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", err
+	}
+	realRoot, err := filepath.EvalSymlinks(absRoot)
+	if err != nil {
+		return "", err
+	}
+	target, err := filepath.EvalSymlinks(filepath.Join(absRoot, filepath.FromSlash(locator)))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", errors.New("file not found: " + locator)
+		}
+		return "", err
+	}
+	rel, err := filepath.Rel(realRoot, target)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", &rootedFilePolicyError{msg: "symlink escapes discovery root: " + locator}
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", errors.New("not a regular file: " + locator)
+	}
+	return target, nil
+}
+
+func (r *luaFileReader) exceeded(L *lua.LState, fn string) {
+	L.RaiseError("%s: file read budget exceeded", fn)
+}
+
+func (r *luaFileReader) readFile(L *lua.LState) int {
+	const fn = "thoth.read_file"
+	locator := L.CheckString(1)
+	limit := 0
+	if opts, ok := L.Get(2).(*lua.LTable); ok {
+		if n, ok := opts.RawGetString("maxBytes").(lua.LNumber); ok && n > 0 {
+			limit = int(n)
+		}
+	}
+	path, msg := r.resolve(L, fn, locator)
+	if msg != "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(msg))
+		return 2
+	}
+	want, truncate := r.remaining, false
+	if limit > 0 && limit <= r.remaining {
+		want, truncate = limit, true
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, int64(want)+1))
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	if len(data) > want {
+		if !truncate {
+			r.exceeded(L, fn)
+			return 0
+		}
+		data = data[:want]
+	}
+	r.remaining -= len(data)
+	L.Push(lua.LString(string(data)))
+	return 1
+}
+
+func (r *luaFileReader) readLines(L *lua.LState) int {
+	const fn = "thoth.read_lines"
+	locator := L.CheckString(1)
+	n := L.CheckInt(2)
+	path, msg := r.resolve(L, fn, locator)
+	if msg != "" {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(msg))
+		return 2
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	defer f.Close()
+	out := L.NewTable()
+	br := bufio.NewReader(io.LimitReader(f, int64(r.remaining)+1))
+	for i := 0; i < n; i++ {
+		line, err := br.ReadString('\n')
+		if len(line) > r.remaining {
+			r.exceeded(L, fn)
+			return 0
+		}
+		r.remaining -= len(line)
+		if line != "" || err == nil {
+			out.Append(lua.LString(strings.TrimRight(line, "\r\n")))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(fmt.Sprintf("%s: %v", locator, err)))
+			return 2
+		}
+	}
+	L.Push(out)
+	return 1
+}
@@ -0,0 +1,106 @@
+package stage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func fileReadMeta(t *testing.T) (*Meta, string) {
+	t.Helper()
+	base := t.TempDir()
+	root := filepath.Join(base, "root")
+	if err := os.MkdirAll(filepath.Join(root, "cmd"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(root, "cmd", "main.go"): "// Copyright ACME\npackage main\n\nfunc main() {}\n",
+		filepath.Join(base, "secret.txt"):     "top secret\n",
+	}
+	for p, c := range files {
+		if err := os.WriteFile(p, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	meta := defaultLuaSandboxForTest()
+	meta.LuaSandbox.FileRead = true
+	meta.Discovery = &DiscoveryMeta{Root: root}
+	return meta, base
+}
+
+func TestLuaThothReadFile_ReadsInsideRoot(t *testing.T) {
+	meta, _ := fileReadMeta(t)
+	rec, _, err := processLuaMapRecord(
+		Record{Locator: "cmd/main.go", Meta: map[string]any{}},
+		`local head = thoth.read_file(locator, { maxBytes = 18 })
+local lines = thoth.read_lines(locator, 2)
+local missing, msg = thoth.read_file("cmd/nope.go")
+return { head = head, second = lines[2], count = #lines, missing = missing == nil, msg = msg }`,
+		"fail-fast",
+		meta,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m := rec.Mapped.(map[string]any)
+	if m["head"] != "// Copyright ACME\n" || m["second"] != "package main" || m["count"] != float64(2) {
+		t.Fatalf("unexpected mapped: %#v", m)
+	}
+	if m["missing"] != true || m["msg"] != "file not found: cmd/nope.go" {
+		t.Fatalf("unexpected missing-file result: %#v", m)
+	}
+}
+
+func TestLuaThothReadFile_RejectsEscapes(t *testing.T) {
+	meta, base := fileReadMeta(t)
+	root := meta.Discovery.Root
+	if err := os.Symlink(filepath.Join(base, "secret.txt"), filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	cases := map[string]string{
+		`return thoth.read_file("../secret.txt")`: "parent references ('..') are not allowed",
+		`return thoth.read_file("/etc/passwd")`:   "absolute paths are not allowed",
+		`return thoth.read_file("link.txt")`:      "symlink escapes discovery root: link.txt",
+	}
+	for code, want := range cases {
+		_, _, err := processLuaMapRecord(Record{Locator: "cmd/main.go", Meta: map[string]any{}}, code, "fail-fast", meta)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: expected %q, got %v", code, want, err)
+		}
+	}
+}
+
+func TestLuaThothReadFile_BudgetAndToggle(t *testing.T) {
+	meta, _ := fileReadMeta(t)
+	meta.LuaSandbox.FileReadMaxBytes = 40
+	_, _, err := processLuaMapRecord(
+		Record{Locator: "cmd/main.go", Meta: map[string]any{}},
+		`thoth.read_file(locator, { maxBytes = 30 }); return thoth.read_file(locator)`,
+		"fail-fast",
+		meta,
+	)
+	if err == nil || !strings.Contains(err.Error(), "thoth.read_file: file read budget exceeded") {
+		t.Fatalf("expected budget error, got %v", err)
+	}
+
+	meta.LuaSandbox.FileRead = false
+	_, _, err = processLuaMapRecord(Record{Locator: "cmd/main.go", Meta: map[string]any{}}, `return thoth.read_lines(locator, 1)`, "fail-fast", meta)
+	if err == nil || !strings.Contains(err.Error(), "thoth.read_lines: file access disabled") {
+		t.Fatalf("expected disabled error, got %v", err)
+	}
+}
+
+func TestValidateConfig_ParsesLuaFileRead(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  lua: { fileRead: true, fileReadMaxBytes: 4096 }\n}\n"
+	out, err := runValidateConfigWithContent(t, "lua_file_read_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	sb := out.Meta.LuaSandbox
+	if sb == nil || !sb.FileRead || sb.FileReadMaxBytes != 4096 || sb.MemoryLimitBytes != defaultLuaMemoryLimitBytes {
+		t.Fatalf("unexpected luaSandbox: %+v", sb)
+	}
+}
@@ -0,0 +1,56 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide hashing and base64 helpers for Lua scripts in the `thoth` library.
+// Responsibilities:
+// - Expose `thoth.sha256` and `thoth.fnv64` as lowercase hex digests for stable IDs.
+// - Expose `thoth.base64_encode` / `thoth.base64_decode` using standard padded encoding.
+// - Enforce the sandbox memory budget on helper inputs and outputs.
+// Architecture notes:
+// - Digests are returned as hex strings because Lua numbers are float64 and cannot hold a 64-bit hash exactly.
+// - These helpers are registered with the codec helpers so they share the per-sandbox byte budget.
+package stage
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"hash/fnv"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func (b luaByteBudget) sha256(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.sha256", len(s))
+	sum := sha256.Sum256([]byte(s))
+	L.Push(lua.LString(hex.EncodeToString(sum[:])))
+	return 1
+}
+
+func (b luaByteBudget) fnv64(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.fnv64", len(s))
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(s))
+	L.Push(lua.LString(hex.EncodeToString(h.Sum(nil))))
+	return 1
+}
+
+func (b luaByteBudget) base64Encode(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.base64_encode", base64.StdEncoding.EncodedLen(len(s)))
+	L.Push(lua.LString(base64.StdEncoding.EncodeToString([]byte(s))))
+	return 1
+}
+
+func (b luaByteBudget) base64Decode(L *lua.LState) int {
+	s := L.CheckString(1)
+	b.check(L, "thoth.base64_decode", len(s))
+	out, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid base64: " + err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(string(out)))
+	return 1
+}
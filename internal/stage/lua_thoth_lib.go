@@ -3,6 +3,7 @@
 // Responsibilities:
 // - Register the global `thoth` helper table in the sandbox.
 // - Implement deterministic list, string, and table helpers for Lua scripts.
+// - Register codec/regex, hash, path/glob, semver, and time helpers (lua_thoth_codec.go, lua_thoth_hash.go, lua_thoth_regex.go, lua_thoth_path.go, lua_thoth_semver.go, lua_thoth_time.go) alongside the core helpers.
 // - Keep callback-based helpers such as map/filter/reduce inside the sandbox runtime.
 // Architecture notes:
 // - This helper surface is intentionally curated; prefer adding narrow deterministic helpers over enabling generic module loading.
@@ -41,9 +42,32 @@ func newThothLibTable(L *lua.LState) *lua.LTable {
 	thoth.RawSetString("sort_values", L.NewFunction(luaThothSortValues))
 	thoth.RawSetString("starts_with", L.NewFunction(luaThothStartsWith))
 	thoth.RawSetString("trim", L.NewFunction(luaThothTrim))
+	registerThothDataHelpers(L, thoth, 0)
+	registerThothFileReadStubs(L, thoth)
+	registerThothRecordHelpers(L, thoth, nil)
+	registerThothLog(L, thoth, nil)
+	registerThothPathHelpers(L, thoth)
+	registerThothSemverHelpers(L, thoth)
+	registerThothTimeHelpers(L, thoth, luaRunNow(nil))
+	thoth.RawSetString("warn", L.NewFunction(func(L *lua.LState) int {
+		L.CheckString(1)
+		return 0
+	}))
 	return thoth
 }
 
+// bindThothWarn routes thoth.warn(msg) to sink for the current script run.
+func bindThothWarn(L *lua.LState, sink func(msg string)) {
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	thoth.RawSetString("warn", L.NewFunction(func(L *lua.LState) int {
+		sink(L.CheckString(1))
+		return 0
+	}))
+}
+
 func luaThothEndsWith(L *lua.LState) int {
 	s := L.CheckString(1)
 	suffix := L.CheckString(2)
@@ -0,0 +1,44 @@
+// File Guide for dev/ai agents:
+// Purpose: Expose `thoth.log(level, ...)` so scripts can emit debug output without failing the record.
+// Responsibilities:
+// - Validate the level and join the remaining arguments with tostring semantics.
+// - Install a no-op stub for bare states and rebind the real sink for each sandbox run.
+// Architecture notes:
+// - The sink is rebound per run (like thoth.warn) because sandbox states are pooled across records and stages.
+// - Routing and ordering live in lua_debug.go; this file only shapes the Lua-facing call.
+package stage
+
+import (
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var luaLogLevels = map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
+
+func registerThothLog(L *lua.LState, tbl *lua.LTable, sink func(level, msg string)) {
+	tbl.RawSetString("log", L.NewFunction(func(L *lua.LState) int {
+		level := L.CheckString(1)
+		if !luaLogLevels[level] {
+			L.RaiseError("thoth.log: invalid level '%s' (use debug, info, warn, or error)", level)
+			return 0
+		}
+		parts := make([]string, 0, L.GetTop()-1)
+		for i := 2; i <= L.GetTop(); i++ {
+			parts = append(parts, L.ToStringMeta(L.Get(i)).String())
+		}
+		if sink != nil {
+			sink(level, strings.Join(parts, " "))
+		}
+		return 0
+	}))
+}
+
+// bindThothLog routes thoth.log(level, ...) to sink for the current script run.
+func bindThothLog(L *lua.LState, sink func(level, msg string)) {
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	registerThothLog(L, thoth, sink)
+}
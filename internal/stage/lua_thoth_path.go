@@ -0,0 +1,104 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide locator path and glob helpers for Lua scripts in the `thoth` library.
+// Responsibilities:
+// - Expose `thoth.path.{dir,base,stem,ext,join,rel}` over slash-separated locators.
+// - Expose `thoth.glob_match(pattern, path)` with discovery include/exclude semantics.
+// Architecture notes:
+// - dir/base/stem/ext reuse locatorParts so Lua agrees with the `{file.*}` shell placeholders byte for byte.
+// - glob_match reuses matchesDiscoveryPattern so a pattern behaves the same in `discovery.include` and in scripts.
+// - All helpers are pure string functions; none touch the filesystem, so they need no sandbox gating.
+package stage
+
+import (
+	"path"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func registerThothPathHelpers(L *lua.LState, tbl *lua.LTable) {
+	p := L.NewTable()
+	p.RawSetString("dir", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(locatorParts(L.CheckString(1)).dir))
+		return 1
+	}))
+	p.RawSetString("base", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(locatorParts(L.CheckString(1)).base))
+		return 1
+	}))
+	p.RawSetString("stem", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(locatorParts(L.CheckString(1)).stem))
+		return 1
+	}))
+	p.RawSetString("ext", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LString(locatorParts(L.CheckString(1)).ext))
+		return 1
+	}))
+	p.RawSetString("join", L.NewFunction(luaThothPathJoin))
+	p.RawSetString("rel", L.NewFunction(luaThothPathRel))
+	tbl.RawSetString("path", p)
+	tbl.RawSetString("glob_match", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LBool(matchesDiscoveryPattern(L.CheckString(1), L.CheckString(2))))
+		return 1
+	}))
+}
+
+func luaThothPathJoin(L *lua.LState) int {
+	parts := make([]string, 0, L.GetTop())
+	for i := 1; i <= L.GetTop(); i++ {
+		parts = append(parts, L.CheckString(i))
+	}
+	L.Push(lua.LString(path.Join(parts...)))
+	return 1
+}
+
+// luaThothPathRel returns target relative to base, or nil and a message when
+// no relative path exists (mixed absolute/relative, or base climbs above
+// its own start with "..").
+func luaThothPathRel(L *lua.LState) int {
+	base := L.CheckString(1)
+	target := L.CheckString(2)
+	rel, ok := relativeLocator(base, target)
+	if !ok {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("thoth.path.rel: cannot make " + target + " relative to " + base))
+		return 2
+	}
+	L.Push(lua.LString(rel))
+	return 1
+}
+
+func relativeLocator(base, target string) (string, bool) {
+	b := path.Clean(base)
+	t := path.Clean(target)
+	if path.IsAbs(b) != path.IsAbs(t) {
+		return "", false
+	}
+	if b == t {
+		return ".", true
+	}
+	bs := splitCleanPath(b)
+	ts := splitCleanPath(t)
+	i := 0
+	for i < len(bs) && i < len(ts) && bs[i] == ts[i] {
+		i++
+	}
+	out := make([]string, 0, len(bs)-i+len(ts)-i)
+	for j := i; j < len(bs); j++ {
+		if bs[j] == ".." {
+			// Cannot climb back out of an unknown parent.
+			return "", false
+		}
+		out = append(out, "..")
+	}
+	out = append(out, ts[i:]...)
+	return strings.Join(out, "/"), true
+}
+
+func splitCleanPath(p string) []string {
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || p == "." {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
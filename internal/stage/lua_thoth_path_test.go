@@ -0,0 +1,64 @@
+package stage
+
+import (
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestLuaThothPath_MatchesFilePlaceholders(t *testing.T) {
+	t.Parallel()
+	for _, loc := range []string{"docs/guide/intro.md", "Makefile", "a/b.tar.gz", ".env", "dir/"} {
+		got := runThothLibScript(t, `local l = "`+loc+`"
+return thoth.path.dir(l), thoth.path.base(l), thoth.path.stem(l), thoth.path.ext(l)`)
+		rec := Record{Locator: loc}
+		for i, ph := range []string{"{file.dir}", "{file.base}", "{file.stem}", "{file.ext}"} {
+			want, _, err := resolvePlaceholder(ph, rec, baseShellOpts())
+			if err != nil {
+				t.Fatalf("%s %s: %v", loc, ph, err)
+			}
+			if got[i].String() != want {
+				t.Fatalf("%s %s: lua=%q placeholder=%q", loc, ph, got[i].String(), want)
+			}
+		}
+	}
+}
+
+func TestLuaThothPath_JoinAndRel(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local rel, err = thoth.path.rel("/abs", "rel/x")
+return thoth.path.join("a", "b/../c", "d.md"),
+  thoth.path.rel("docs/guide", "docs/api/x.md"),
+  thoth.path.rel("a", "a"),
+  rel, err
+`)
+	if got[0].String() != "a/c/d.md" || got[1].String() != "../api/x.md" || got[2].String() != "." {
+		t.Fatalf("unexpected join/rel: %v", got)
+	}
+	if got[3] != lua.LNil || got[4].String() != "thoth.path.rel: cannot make rel/x relative to /abs" {
+		t.Fatalf("unexpected rel error: %v", got[3:])
+	}
+}
+
+func TestLuaThothGlobMatch_UsesDiscoverySemantics(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		pattern, path string
+	}{
+		{"docs/**", "docs/a/b.md"},
+		{"docs/**", "docs"},
+		{"*.go", "main.go"},
+		{"*.go", "pkg/main.go"},
+		{"pkg/*/README.md", "pkg/x/README.md"},
+		{"**", "anything/at/all"},
+		{"[", "x"},
+	}
+	for _, c := range cases {
+		got := runThothLibScript(t, `return thoth.glob_match("`+c.pattern+`", "`+c.path+`")`)
+		want := matchesDiscoveryPattern(c.pattern, c.path)
+		if got[0] != lua.LBool(want) {
+			t.Fatalf("glob_match(%q, %q)=%v want %v", c.pattern, c.path, got[0], want)
+		}
+	}
+}
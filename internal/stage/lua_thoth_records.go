@@ -0,0 +1,158 @@
+// File Guide for dev/ai agents:
+// Purpose: Give Lua scripts read-only access to sibling records via thoth.lookup and thoth.glob.
+// Responsibilities:
+// - Snapshot the envelope records at the start of a Lua stage.
+// - Resolve lookups by exact locator and globs by discovery-style patterns.
+// - Convert snapshot values into fresh Lua tables so scripts cannot mutate shared state.
+// Architecture notes:
+// - The snapshot rides on an unexported Meta field of a per-stage meta copy, like the warnings collector, so record workers need no new parameters.
+// - Every worker sees the same stage-start snapshot, never results produced by other workers, which keeps parallel runs deterministic.
+// - Without a snapshot (e.g. direct helper calls) lookup returns nil and glob returns an empty list.
+// - Streaming output runs stages one record at a time, so the CLI keeps scripts that use lookup/glob on buffered stages (see UsesRecordLookup).
+package stage
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+type recordSnapshot struct {
+	records []Record
+	// pinned snapshots are kept by later stages instead of being replaced;
+	// streaming pipelines pin the input set because each stage only sees
+	// one record at a time.
+	pinned bool
+
+	once      sync.Once
+	byLocator map[string]int
+	locators  []string
+}
+
+func (s *recordSnapshot) index() {
+	s.once.Do(func() {
+		s.byLocator = make(map[string]int, len(s.records))
+		s.locators = make([]string, 0, len(s.records))
+		for i, r := range s.records {
+			if _, dup := s.byLocator[r.Locator]; dup {
+				continue
+			}
+			s.byLocator[r.Locator] = i
+			s.locators = append(s.locators, r.Locator)
+		}
+		sort.Strings(s.locators)
+	})
+}
+
+func (s *recordSnapshot) lookup(locator string) (Record, bool) {
+	if s == nil {
+		return Record{}, false
+	}
+	s.index()
+	i, ok := s.byLocator[locator]
+	if !ok {
+		return Record{}, false
+	}
+	return s.records[i], true
+}
+
+func (s *recordSnapshot) glob(pattern string) []string {
+	out := make([]string, 0)
+	if s == nil {
+		return out
+	}
+	s.index()
+	for _, loc := range s.locators {
+		if matchesDiscoveryPattern(pattern, loc) {
+			out = append(out, loc)
+		}
+	}
+	return out
+}
+
+// withRecordSnapshot returns a shallow meta copy carrying a snapshot of
+// records for thoth.lookup/thoth.glob. The record slice is copied so later
+// in-place writes to the stage output do not leak into the snapshot.
+func withRecordSnapshot(meta *Meta, records []Record) *Meta {
+	if meta != nil && meta.records != nil && meta.records.pinned {
+		return meta
+	}
+	var m Meta
+	if meta != nil {
+		m = *meta
+	}
+	m.records = &recordSnapshot{records: append([]Record(nil), records...)}
+	return &m
+}
+
+// PinRecordSnapshot returns env with a meta copy whose lookup snapshot is
+// fixed to env.Records for every later Lua stage.
+func PinRecordSnapshot(env Envelope) Envelope {
+	env.Meta = withRecordSnapshot(env.Meta, env.Records)
+	env.Meta.records.pinned = true
+	return env
+}
+
+// recordLookupCall matches a call to thoth.lookup or thoth.glob, but not
+// thoth.glob_match or identifiers that merely contain those words.
+var recordLookupCall = regexp.MustCompile(`\bthoth\s*\.\s*(lookup|glob)\s*\(`)
+
+// UsesRecordLookup reports whether a per-record Lua script calls
+// thoth.lookup or thoth.glob, so streaming pipelines can switch to buffered
+// stages whenever a script reads sibling records.
+func UsesRecordLookup(meta *Meta) bool {
+	if meta == nil || meta.Lua == nil {
+		return false
+	}
+	for _, code := range []string{meta.Lua.FilterInline, meta.Lua.MapInline, meta.Lua.PostMapInline} {
+		if recordLookupCall.MatchString(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func registerThothRecordHelpers(L *lua.LState, tbl *lua.LTable, snap *recordSnapshot) {
+	tbl.RawSetString("lookup", L.NewFunction(func(L *lua.LState) int {
+		rec, ok := snap.lookup(L.CheckString(1))
+		if !ok {
+			L.Push(lua.LNil)
+			return 1
+		}
+		view := map[string]any{"locator": rec.Locator}
+		if rec.Meta != nil {
+			view["meta"] = rec.Meta
+		}
+		if rec.Mapped != nil {
+			view["mapped"] = rec.Mapped
+		}
+		if rec.Post != nil {
+			view["post"] = rec.Post
+		}
+		L.Push(toLValue(L, view))
+		return 1
+	}))
+	tbl.RawSetString("glob", L.NewFunction(func(L *lua.LState) int {
+		out := L.NewTable()
+		for _, loc := range snap.glob(L.CheckString(1)) {
+			out.Append(lua.LString(loc))
+		}
+		L.Push(out)
+		return 1
+	}))
+}
+
+// bindThothRecords points lookup/glob on the global thoth table at the
+// snapshot carried by meta.
+func bindThothRecords(L *lua.LState, meta *Meta) {
+	if meta == nil || meta.records == nil {
+		return
+	}
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	registerThothRecordHelpers(L, thoth, meta.records)
+}
@@ -0,0 +1,94 @@
+package stage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func lookupEnvelope(mapInline string, workers int) Envelope {
+	recs := []Record{
+		{Locator: "pkg/a.go", Meta: map[string]any{"owner": "team-a"}, Mapped: map[string]any{"old": true}},
+		{Locator: "pkg/a_test.go", Meta: map[string]any{}},
+		{Locator: "pkg/README.md", Meta: map[string]any{"title": "Pkg"}},
+		{Locator: "cmd/main.go", Meta: map[string]any{}},
+	}
+	meta := defaultLuaSandboxForTest()
+	meta.Lua = &LuaMeta{MapInline: mapInline}
+	meta.Errors = &ErrorsMeta{Mode: "fail-fast"}
+	meta.Workers = workers
+	return Envelope{Records: recs, Meta: meta}
+}
+
+func TestLuaThothLookup_SeesStageStartSnapshot(t *testing.T) {
+	code := `
+local src = locator:gsub("_test%.go$", ".go")
+local sib = thoth.lookup(src)
+local readme = thoth.lookup("pkg/README.md")
+return {
+  owner = sib and sib.meta.owner,
+  siblingMapped = sib ~= nil and sib.mapped ~= nil,
+  readme = readme and readme.meta.title,
+  missing = thoth.lookup("nope.go") == nil,
+}`
+	var first string
+	for _, workers := range []int{1, 4} {
+		out, err := Run(context.Background(), "lua-map", lookupEnvelope(code, workers), Deps{})
+		if err != nil {
+			t.Fatalf("lua-map: %v", err)
+		}
+		got := out.Records[1].Mapped.(map[string]any)
+		if got["owner"] != "team-a" || got["readme"] != "Pkg" || got["missing"] != true {
+			t.Fatalf("unexpected lookup result: %#v", got)
+		}
+		// pkg/a.go is rewritten by this same stage; the snapshot must still
+		// show its pre-stage mapped value.
+		if got["siblingMapped"] != true {
+			t.Fatalf("expected stage-start mapped on sibling: %#v", got)
+		}
+		s := fmt.Sprint(out.Records)
+		if first == "" {
+			first = s
+		} else if s != first {
+			t.Fatalf("results differ between worker counts:\n%s\n%s", first, s)
+		}
+	}
+}
+
+func TestLuaThothGlob_ReturnsSortedMatches(t *testing.T) {
+	out, err := Run(context.Background(), "lua-map",
+		lookupEnvelope(`return table.concat(thoth.glob("pkg/*.go"), ",")`, 2), Deps{})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if out.Records[0].Mapped != "pkg/a.go,pkg/a_test.go" {
+		t.Fatalf("unexpected glob: %#v", out.Records[0].Mapped)
+	}
+}
+
+func TestLuaThothLookup_WithoutSnapshotReturnsNil(t *testing.T) {
+	L := newLuaStateWithThothLib(t)
+	defer L.Close()
+	if err := L.DoString(`return thoth.lookup("a"), #thoth.glob("**")`); err != nil {
+		t.Fatal(err)
+	}
+	if L.Get(-2).String() != "nil" || L.Get(-1).String() != "0" {
+		t.Fatalf("unexpected results: %v %v", L.Get(-2), L.Get(-1))
+	}
+}
+
+func TestUsesRecordLookup_OnlyRealCalls(t *testing.T) {
+	cases := map[string]bool{
+		"return thoth.lookup(locator)":                true,
+		"local xs = thoth . glob ('src/**')":          true,
+		"return thoth.glob_match('docs/**', locator)": false,
+		"local global, lookupTable = 1, {}":           false,
+		"return mythoth.lookup(locator)":              false,
+	}
+	for code, want := range cases {
+		meta := &Meta{Lua: &LuaMeta{PostMapInline: code}}
+		if got := UsesRecordLookup(meta); got != want {
+			t.Fatalf("%q: got %v want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide RE2 regular expression helpers for Lua scripts, backed by Go `regexp`.
+// Responsibilities:
+// - Implement thoth.re_match, thoth.re_find_all, and thoth.re_replace.
+// - Cache compiled patterns per script run so loops do not recompile, bounded by luaRegexCacheMax.
+// - Apply the sandbox byte budget to subject strings and results.
+// Architecture notes:
+// - RE2 guarantees linear-time matching, which is why these helpers are safe to expose where Lua patterns are too weak.
+// - The cache is rebuilt with the helpers on every run and reset when full, so patterns built from record data cannot grow memory across a run.
+// - Invalid patterns raise a Lua error naming the helper so failures point at the script line that supplied them.
+package stage
+
+import (
+	"regexp"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// luaRegexCacheMax bounds the compiled patterns one script run keeps.
+const luaRegexCacheMax = 64
+
+type luaRegexHelpers struct {
+	budget luaByteBudget
+	cache  map[string]*regexp.Regexp
+}
+
+func newLuaRegexHelpers(budget luaByteBudget) *luaRegexHelpers {
+	return &luaRegexHelpers{budget: budget, cache: map[string]*regexp.Regexp{}}
+}
+
+func (h *luaRegexHelpers) compile(L *lua.LState, fn, pattern string) *regexp.Regexp {
+	if re, ok := h.cache[pattern]; ok {
+		return re
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		L.RaiseError("%s: invalid pattern: %v", fn, err)
+		return nil
+	}
+	if len(h.cache) >= luaRegexCacheMax {
+		clear(h.cache)
+	}
+	h.cache[pattern] = re
+	return re
+}
+
+func (h *luaRegexHelpers) match(L *lua.LState) int {
+	s := L.CheckString(1)
+	re := h.compile(L, "thoth.re_match", L.CheckString(2))
+	h.budget.check(L, "thoth.re_match", len(s))
+	L.Push(lua.LBool(re.MatchString(s)))
+	return 1
+}
+
+// findAll returns matches in order. Without capture groups each item is
+// the matched string; with groups each item is {match, group1, ...}.
+func (h *luaRegexHelpers) findAll(L *lua.LState) int {
+	s := L.CheckString(1)
+	re := h.compile(L, "thoth.re_find_all", L.CheckString(2))
+	n := L.OptInt(3, -1)
+	h.budget.check(L, "thoth.re_find_all", len(s))
+	out := L.NewTable()
+	if re.NumSubexp() == 0 {
+		for _, m := range re.FindAllString(s, n) {
+			out.Append(lua.LString(m))
+		}
+		L.Push(out)
+		return 1
+	}
+	for _, groups := range re.FindAllStringSubmatch(s, n) {
+		item := L.NewTable()
+		for _, g := range groups {
+			item.Append(lua.LString(g))
+		}
+		out.Append(item)
+	}
+	L.Push(out)
+	return 1
+}
+
+func (h *luaRegexHelpers) replace(L *lua.LState) int {
+	s := L.CheckString(1)
+	re := h.compile(L, "thoth.re_replace", L.CheckString(2))
+	repl := L.CheckString(3)
+	h.budget.check(L, "thoth.re_replace", len(s))
+	out := re.ReplaceAllString(s, repl)
+	h.budget.check(L, "thoth.re_replace", len(out))
+	L.Push(lua.LString(out))
+	return 1
+}
@@ -0,0 +1,304 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide semantic-version parsing, comparison, and constraint checks for Lua scripts (`thoth.semver.*`).
+// Responsibilities:
+// - Parse SemVer 2.0 strings (optional leading "v", partial "1" / "1.2" forms) into comparable values.
+// - Compare versions with SemVer precedence rules, including prerelease identifiers.
+// - Evaluate constraint expressions (=, !=, >, >=, <, <=, ^, ~, x-ranges, AND by comma/space, OR by "||").
+// Architecture notes:
+// - Implemented locally instead of adding a module dependency; the grammar is deliberately small and documented in LUA.md.
+// - parse returns `nil, message` like the codec helpers, while compare/satisfies raise on invalid input because a silent false would hide bad metadata.
+package stage
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+type semver struct {
+	major, minor, patch int
+	pre                 []string
+	build               string
+	// parts counts the numeric fields given (1-3); wildcards stop the count.
+	parts int
+}
+
+func parseSemver(s string) (semver, error) {
+	in := strings.TrimSpace(s)
+	v := strings.TrimPrefix(in, "v")
+	if v == "" {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	var out semver
+	if i := strings.IndexByte(v, '+'); i >= 0 {
+		out.build = v[i+1:]
+		v = v[:i]
+		if out.build == "" {
+			return semver{}, fmt.Errorf("invalid version %q", s)
+		}
+	}
+	if i := strings.IndexByte(v, '-'); i >= 0 {
+		pre := v[i+1:]
+		v = v[:i]
+		if pre == "" {
+			return semver{}, fmt.Errorf("invalid version %q", s)
+		}
+		out.pre = strings.Split(pre, ".")
+		for _, id := range out.pre {
+			if id == "" {
+				return semver{}, fmt.Errorf("invalid version %q", s)
+			}
+		}
+	}
+	fields := strings.Split(v, ".")
+	if len(fields) > 3 {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	nums := []*int{&out.major, &out.minor, &out.patch}
+	for i, f := range fields {
+		if f == "x" || f == "X" || f == "*" {
+			if i < len(fields)-1 || len(out.pre) > 0 {
+				return semver{}, fmt.Errorf("invalid version %q", s)
+			}
+			break
+		}
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 || (len(f) > 1 && f[0] == '0') {
+			return semver{}, fmt.Errorf("invalid version %q", s)
+		}
+		*nums[i] = n
+		out.parts = i + 1
+	}
+	if out.parts < 3 && len(out.pre) > 0 {
+		return semver{}, fmt.Errorf("invalid version %q", s)
+	}
+	return out, nil
+}
+
+func (v semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	if len(v.pre) > 0 {
+		s += "-" + strings.Join(v.pre, ".")
+	}
+	if v.build != "" {
+		s += "+" + v.build
+	}
+	return s
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// compareSemver orders versions by SemVer precedence; build metadata is
+// ignored.
+func compareSemver(a, b semver) int {
+	if c := compareInts(a.major, b.major); c != 0 {
+		return c
+	}
+	if c := compareInts(a.minor, b.minor); c != 0 {
+		return c
+	}
+	if c := compareInts(a.patch, b.patch); c != 0 {
+		return c
+	}
+	switch {
+	case len(a.pre) == 0 && len(b.pre) == 0:
+		return 0
+	case len(a.pre) == 0:
+		return 1
+	case len(b.pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.pre) && i < len(b.pre); i++ {
+		if c := comparePrereleaseID(a.pre[i], b.pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(a.pre), len(b.pre))
+}
+
+func comparePrereleaseID(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// semverBounds converts a possibly partial version into the half-open range
+// [lo, hi) it denotes; hi is nil when unbounded (a bare wildcard).
+func semverBounds(v semver) (semver, *semver) {
+	lo := semver{major: v.major, minor: v.minor, patch: v.patch, pre: v.pre}
+	switch v.parts {
+	case 0:
+		return lo, nil
+	case 1:
+		return lo, &semver{major: v.major + 1}
+	case 2:
+		return lo, &semver{major: v.major, minor: v.minor + 1}
+	}
+	hi := lo
+	hi.pre = nil
+	hi.patch++
+	if len(lo.pre) > 0 {
+		// An exact prerelease matches only itself.
+		return lo, &semver{major: lo.major, minor: lo.minor, patch: lo.patch, pre: append(append([]string(nil), lo.pre...), "0")}
+	}
+	return lo, &hi
+}
+
+func semverInRange(v, lo semver, hi *semver) bool {
+	return compareSemver(v, lo) >= 0 && (hi == nil || compareSemver(v, *hi) < 0)
+}
+
+// semverSatisfies reports whether v matches the constraint expression.
+func semverSatisfies(v semver, constraint string) (bool, error) {
+	for _, group := range strings.Split(constraint, "||") {
+		terms := strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+		if len(terms) == 0 {
+			return false, fmt.Errorf("invalid constraint %q", constraint)
+		}
+		all := true
+		for i := 0; i < len(terms); i++ {
+			term := terms[i]
+			// Allow a space between operator and version (">= 1.2").
+			if strings.TrimLeft(term, "=!<>^~") == "" && i+1 < len(terms) {
+				term += terms[i+1]
+				i++
+			}
+			ok, err := semverTermMatches(v, term)
+			if err != nil {
+				return false, fmt.Errorf("invalid constraint %q: %v", constraint, err)
+			}
+			if !ok {
+				all = false
+			}
+		}
+		if all {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func semverTermMatches(v semver, term string) (bool, error) {
+	op := ""
+	for _, cand := range []string{">=", "<=", "!=", "==", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(term, cand) {
+			op = cand
+			break
+		}
+	}
+	c, err := parseSemver(strings.TrimPrefix(term, op))
+	if err != nil {
+		return false, err
+	}
+	lo, hi := semverBounds(c)
+	switch op {
+	case "", "=", "==":
+		return semverInRange(v, lo, hi), nil
+	case "!=":
+		return !semverInRange(v, lo, hi), nil
+	case ">":
+		if c.parts == 3 {
+			return compareSemver(v, lo) > 0, nil
+		}
+		return hi != nil && compareSemver(v, *hi) >= 0, nil
+	case ">=":
+		return compareSemver(v, lo) >= 0, nil
+	case "<":
+		return compareSemver(v, lo) < 0, nil
+	case "<=":
+		if c.parts == 3 {
+			return compareSemver(v, lo) <= 0, nil
+		}
+		return hi == nil || compareSemver(v, *hi) < 0, nil
+	case "~":
+		// ~1.2.3 and ~1.2 allow patch changes; ~1 allows minor changes.
+		if c.parts <= 1 {
+			return semverInRange(v, lo, hi), nil
+		}
+		return semverInRange(v, lo, &semver{major: c.major, minor: c.minor + 1}), nil
+	default: // "^"
+		// Caret allows changes that keep the left-most non-zero field.
+		switch {
+		case c.parts == 0:
+			return true, nil
+		case c.major > 0 || c.parts == 1:
+			return semverInRange(v, lo, &semver{major: c.major + 1}), nil
+		case c.minor > 0 || c.parts == 2:
+			return semverInRange(v, lo, &semver{minor: c.minor + 1}), nil
+		}
+		return semverInRange(v, lo, &semver{patch: c.patch + 1}), nil
+	}
+}
+
+func registerThothSemverHelpers(L *lua.LState, tbl *lua.LTable) {
+	sv := L.NewTable()
+	sv.RawSetString("parse", L.NewFunction(luaThothSemverParse))
+	sv.RawSetString("compare", L.NewFunction(luaThothSemverCompare))
+	sv.RawSetString("satisfies", L.NewFunction(luaThothSemverSatisfies))
+	tbl.RawSetString("semver", sv)
+}
+
+func luaThothSemverParse(L *lua.LState) int {
+	v, err := parseSemver(L.CheckString(1))
+	if err != nil || v.parts < 3 {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(fmt.Sprintf("invalid version %q", L.CheckString(1))))
+		return 2
+	}
+	out := L.NewTable()
+	out.RawSetString("major", lua.LNumber(v.major))
+	out.RawSetString("minor", lua.LNumber(v.minor))
+	out.RawSetString("patch", lua.LNumber(v.patch))
+	pre := L.NewTable()
+	for _, id := range v.pre {
+		pre.Append(lua.LString(id))
+	}
+	out.RawSetString("prerelease", pre)
+	out.RawSetString("build", lua.LString(v.build))
+	out.RawSetString("version", lua.LString(v.String()))
+	L.Push(out)
+	return 1
+}
+
+func checkSemverArg(L *lua.LState, fn string, n int) semver {
+	v, err := parseSemver(L.CheckString(n))
+	if err != nil {
+		L.RaiseError("%s: %v", fn, err)
+	}
+	return v
+}
+
+func luaThothSemverCompare(L *lua.LState) int {
+	a := checkSemverArg(L, "thoth.semver.compare", 1)
+	b := checkSemverArg(L, "thoth.semver.compare", 2)
+	L.Push(lua.LNumber(compareSemver(a, b)))
+	return 1
+}
+
+func luaThothSemverSatisfies(L *lua.LState) int {
+	v := checkSemverArg(L, "thoth.semver.satisfies", 1)
+	ok, err := semverSatisfies(v, L.CheckString(2))
+	if err != nil {
+		L.RaiseError("thoth.semver.satisfies: %v", err)
+	}
+	L.Push(lua.LBool(ok))
+	return 1
+}
@@ -0,0 +1,116 @@
+package stage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSemver_ComparePrecedence(t *testing.T) {
+	t.Parallel()
+	// Ascending per the SemVer 2.0 spec example, plus build metadata.
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1", "1.10.0", "2.0.0",
+	}
+	for i := 0; i+1 < len(ordered); i++ {
+		a, err := parseSemver(ordered[i])
+		if err != nil {
+			t.Fatalf("parse %s: %v", ordered[i], err)
+		}
+		b, _ := parseSemver(ordered[i+1])
+		if compareSemver(a, b) != -1 || compareSemver(b, a) != 1 {
+			t.Fatalf("expected %s < %s", ordered[i], ordered[i+1])
+		}
+	}
+	a, _ := parseSemver("1.2.3+build.1")
+	b, _ := parseSemver("1.2.3+build.2")
+	if compareSemver(a, b) != 0 {
+		t.Fatalf("build metadata must not affect precedence")
+	}
+	for _, bad := range []string{"", "v", "1.2.3.4", "01.2.3", "1.2.3-", "1.2-rc.1", "1.x.3", "a.b.c"} {
+		if _, err := parseSemver(bad); err == nil {
+			t.Fatalf("expected %q to be invalid", bad)
+		}
+	}
+}
+
+func TestSemver_Satisfies(t *testing.T) {
+	t.Parallel()
+	cases := []struct {
+		version, constraint string
+		want                bool
+	}{
+		{"1.2.3", "1.2.3", true},
+		{"1.2.4", "=1.2.3", false},
+		{"1.2.9", "1.2", true},
+		{"1.3.0", "1.2.x", false},
+		{"5.0.0", "*", true},
+		{"1.2.3", "!=1.2.3", false},
+		{"1.2.4", ">1.2.3", true},
+		{"1.2.9", ">1.2", false},
+		{"1.3.0", ">1.2", true},
+		{"1.2.3", ">=1.2.3 <2", true},
+		{"2.0.0", ">=1.2.3, <2", false},
+		{"1.2.9", "<=1.2", true},
+		{"1.9.0", "^1.2.3", true},
+		{"2.0.0", "^1.2.3", false},
+		{"0.2.9", "^0.2.3", true},
+		{"0.3.0", "^0.2.3", false},
+		{"0.0.4", "^0.0.3", false},
+		{"1.2.9", "~1.2.3", true},
+		{"1.3.0", "~1.2.3", false},
+		{"1.9.0", "~1", true},
+		{"3.1.0", "^1.0 || ^3.0", true},
+		{"2.1.0", "^1.0 || ^3.0", false},
+		{"1.2.3", ">= 1.2.0", true},
+		{"1.0.0-rc.1", "1.0.0-rc.1", true},
+		{"1.0.0-rc.2", "1.0.0-rc.1", false},
+		{"1.0.0", ">1.0.0-rc.1", true},
+	}
+	for _, c := range cases {
+		v, err := parseSemver(c.version)
+		if err != nil {
+			t.Fatalf("parse %s: %v", c.version, err)
+		}
+		got, err := semverSatisfies(v, c.constraint)
+		if err != nil {
+			t.Fatalf("%s %s: %v", c.version, c.constraint, err)
+		}
+		if got != c.want {
+			t.Fatalf("satisfies(%s, %q)=%v want %v", c.version, c.constraint, got, c.want)
+		}
+	}
+}
+
+func TestLuaThothSemver_Bindings(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local v = thoth.semver.parse("v1.4.0-beta.2+sha.5")
+local bad, msg = thoth.semver.parse("1.4")
+return v.major, v.minor, v.patch, v.prerelease[2], v.build, v.version,
+  bad, msg,
+  thoth.semver.compare("1.4.0", "1.10.0"),
+  thoth.semver.satisfies("1.4.0", "^1.2")
+`)
+	want := []string{"1", "4", "0", "2", "sha.5", "1.4.0-beta.2+sha.5", "nil", `invalid version "1.4"`, "-1", "true"}
+	if len(got) != len(want) {
+		t.Fatalf("unexpected results: %v", got)
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("result[%d]=%q want %q", i, got[i].String(), want[i])
+		}
+	}
+
+	L := newLuaStateWithThothLib(t)
+	defer L.Close()
+	for _, code := range []string{
+		`thoth.semver.compare("1.x.0", "1.0.0")`,
+		`thoth.semver.satisfies("1.0.0", ">>1")`,
+	} {
+		err := L.DoString(code)
+		if err == nil || !strings.Contains(err.Error(), "thoth.semver.") {
+			t.Fatalf("%s: expected raised error, got %v", code, err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide date helpers for Lua scripts (`thoth.time.*`) with a clock pinned per run.
+// Responsibilities:
+// - Parse timestamps such as `git.lastCommit.time` and `fileInfo.modTime` into Unix seconds.
+// - Format Unix seconds back to strings and compute whole-day differences.
+// - Expose `thoth.time.now()` from `run.now` when configured, otherwise from the process start time.
+// Architecture notes:
+// - Scripts never read the wall clock directly; "now" is fixed for the whole run so every record sees the same value and pinned configs reproduce byte-identical output.
+// - All times are UTC so results do not depend on the host time zone.
+// - The clock is rebound per sandbox run because pooled states can serve configs with different `run.now` values.
+package stage
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	processNowOnce sync.Once
+	processNow     time.Time
+)
+
+// luaRunNow returns the pinned clock for Lua time helpers.
+func luaRunNow(meta *Meta) time.Time {
+	if meta != nil && meta.Run != nil && meta.Run.Now != "" {
+		if t, err := time.Parse(time.RFC3339, meta.Run.Now); err == nil {
+			return t.UTC()
+		}
+	}
+	processNowOnce.Do(func() {
+		processNow = time.Now().UTC().Truncate(time.Second)
+	})
+	return processNow
+}
+
+var luaTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+func parseLuaTime(s, layout string) (time.Time, bool) {
+	if layout != "" {
+		t, err := time.Parse(layout, s)
+		return t.UTC(), err == nil
+	}
+	for _, l := range luaTimeLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func registerThothTimeHelpers(L *lua.LState, tbl *lua.LTable, now time.Time) {
+	tm := L.NewTable()
+	tm.RawSetString("now", L.NewFunction(func(L *lua.LState) int {
+		L.Push(lua.LNumber(now.Unix()))
+		return 1
+	}))
+	tm.RawSetString("parse", L.NewFunction(luaThothTimeParse))
+	tm.RawSetString("format", L.NewFunction(luaThothTimeFormat))
+	tm.RawSetString("diff_days", L.NewFunction(func(L *lua.LState) int {
+		a := checkLuaTimeArg(L, "thoth.time.diff_days", 1)
+		b := now
+		if L.GetTop() >= 2 && L.Get(2) != lua.LNil {
+			b = checkLuaTimeArg(L, "thoth.time.diff_days", 2)
+		}
+		L.Push(lua.LNumber(math.Floor(b.Sub(a).Hours() / 24)))
+		return 1
+	}))
+	tbl.RawSetString("time", tm)
+}
+
+// bindThothTime re-registers thoth.time with the clock for the current run.
+func bindThothTime(L *lua.LState, now time.Time) {
+	thoth, ok := L.GetGlobal("thoth").(*lua.LTable)
+	if !ok {
+		return
+	}
+	registerThothTimeHelpers(L, thoth, now)
+}
+
+func luaThothTimeParse(L *lua.LState) int {
+	s := L.CheckString(1)
+	t, ok := parseLuaTime(s, L.OptString(2, ""))
+	if !ok {
+		L.Push(lua.LNil)
+		L.Push(lua.LString("invalid time: " + s))
+		return 2
+	}
+	L.Push(lua.LNumber(t.Unix()))
+	return 1
+}
+
+func luaThothTimeFormat(L *lua.LState) int {
+	t := checkLuaTimeArg(L, "thoth.time.format", 1)
+	layout := time.RFC3339
+	switch l := L.OptString(2, "rfc3339"); l {
+	case "rfc3339":
+	case "date":
+		layout = "2006-01-02"
+	default:
+		layout = l
+	}
+	L.Push(lua.LString(t.Format(layout)))
+	return 1
+}
+
+// checkLuaTimeArg accepts Unix seconds or a parseable timestamp string.
+func checkLuaTimeArg(L *lua.LState, fn string, n int) time.Time {
+	switch v := L.Get(n).(type) {
+	case lua.LNumber:
+		sec, frac := math.Modf(float64(v))
+		return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	case lua.LString:
+		t, ok := parseLuaTime(string(v), "")
+		if !ok {
+			L.RaiseError("%s: invalid time: %s", fn, string(v))
+		}
+		return t
+	}
+	L.RaiseError("%s: expected Unix seconds or timestamp string", fn)
+	return time.Time{}
+}
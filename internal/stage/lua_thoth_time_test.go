@@ -0,0 +1,94 @@
+package stage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestLuaThothHashAndBase64(t *testing.T) {
+	t.Parallel()
+	got := runThothLibScript(t, `
+local dec, err = thoth.base64_decode("!!")
+return thoth.sha256("abc"), thoth.fnv64(""), thoth.fnv64("a"),
+  thoth.base64_encode("hi?"), thoth.base64_decode("aGk/"), dec, err
+`)
+	want := []string{
+		"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		"cbf29ce484222325",
+		"af63dc4c8601ec8c",
+		"aGk/",
+		"hi?",
+		"nil",
+	}
+	for i := range want {
+		if got[i].String() != want[i] {
+			t.Fatalf("result[%d]=%q want %q", i, got[i].String(), want[i])
+		}
+	}
+	if !strings.HasPrefix(got[6].String(), "invalid base64:") {
+		t.Fatalf("unexpected decode error: %q", got[6].String())
+	}
+}
+
+func TestLuaThothTime_PinnedNow(t *testing.T) {
+	meta := defaultLuaSandboxForTest()
+	meta.Run = &RunMeta{Now: "2026-07-01T12:00:00Z"}
+	code := `
+local modified = thoth.time.parse(fileInfo.modTime)
+local bad, err = thoth.time.parse("yesterday")
+return {
+  now = thoth.time.format(thoth.time.now()),
+  stale = thoth.time.diff_days(fileInfo.modTime) >= 180,
+  days = thoth.time.diff_days(fileInfo.modTime),
+  between = thoth.time.diff_days("2026-01-01", "2026-01-31"),
+  date = thoth.time.format(modified, "date"),
+  custom = thoth.time.format(modified, "02 Jan 2006"),
+  git = thoth.time.parse(git.lastCommit.time),
+  err = err,
+}
+`
+	globals := map[string]any{
+		"fileInfo": map[string]any{"modTime": "2025-12-01T08:30:00Z"},
+		"git":      map[string]any{"lastCommit": map[string]any{"time": "2026-06-30T12:00:00+02:00"}},
+	}
+	ret, violation, err := runLuaScriptWithSandbox(luaMapStage, meta, "a.md", globals, code)
+	if err != nil || violation != "" {
+		t.Fatalf("run: %v %s", err, violation)
+	}
+	m := ret.(map[string]any)
+	if m["now"] != "2026-07-01T12:00:00Z" || m["stale"] != true || m["days"] != float64(212) ||
+		m["between"] != float64(30) || m["date"] != "2025-12-01" || m["custom"] != "01 Dec 2025" ||
+		m["git"] != float64(1782813600) || m["err"] != "invalid time: yesterday" {
+		t.Fatalf("unexpected result: %+v", m)
+	}
+
+	// Pooled states must pick up a different pinned clock on the next run.
+	meta.Run = &RunMeta{Now: "2030-01-01T00:00:00Z"}
+	ret, _, err = runLuaScriptWithSandbox(luaMapStage, meta, "a.md", nil, `return thoth.time.format(thoth.time.now(), "date")`)
+	if err != nil || ret != "2030-01-01" {
+		t.Fatalf("unexpected rebound clock: %v %v", ret, err)
+	}
+}
+
+func TestValidateConfig_RunNow(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  run: { now: \"2026-01-02T03:04:05Z\" }\n}\n"
+	out, err := runValidateConfigWithContent(t, "run_now_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Run == nil || out.Meta.Run.Now != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected run meta: %+v", out.Meta.Run)
+	}
+	bad := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  run: { now: \"2026-01-02\" }\n}\n"
+	_, err = runValidateConfigWithContent(t, "run_now_bad_validate_test.cue", bad)
+	if err == nil || err.Error() != "invalid run.now: must be an RFC 3339 timestamp" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	badType := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  run: { now: 5 }\n}\n"
+	_, err = runValidateConfigWithContent(t, "run_now_type_validate_test.cue", badType)
+	if err == nil || !strings.Contains(err.Error(), "invalid run.now: must be string") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
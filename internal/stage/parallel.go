@@ -31,8 +31,20 @@ func SortEnvelopeErrors(env *Envelope) {
 	if env == nil || len(env.Errors) == 0 {
 		return
 	}
-	sort.Slice(env.Errors, func(i, j int) bool {
-		ei, ej := env.Errors[i], env.Errors[j]
+	sortErrorList(env.Errors)
+}
+
+// SortEnvelopeWarnings sorts warnings with the same ordering as errors.
+func SortEnvelopeWarnings(env *Envelope) {
+	if env == nil || len(env.Warnings) == 0 {
+		return
+	}
+	sortErrorList(env.Warnings)
+}
+
+func sortErrorList(errs []Error) {
+	sort.Slice(errs, func(i, j int) bool {
+		ei, ej := errs[i], errs[j]
 		if ei.Stage != ej.Stage {
 			return ei.Stage < ej.Stage
 		}
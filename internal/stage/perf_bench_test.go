@@ -9,6 +9,8 @@ import (
 	"runtime"
 	"sort"
 	"testing"
+
+	lua "github.com/yuin/gopher-lua"
 )
 
 // Benchmarks in this file cover discovery walk/glob, YAML parsing, Lua filter/map,
@@ -194,3 +196,34 @@ func BenchmarkShellExecNoOp(b *testing.B) {
 		}
 	}
 }
+
+// BenchmarkLuaSandboxFreshState measures the pre-pooling cost model: a new
+// sandbox and a re-parse per record. Compare with BenchmarkLuaSandboxPooled.
+func BenchmarkLuaSandboxFreshState(b *testing.B) {
+	cfg := luaSandboxFromMeta(nil)
+	code := "return { locator = locator, value = (meta and meta.value) or 0 }"
+	meta := map[string]any{"value": 1}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		L := newSandboxLuaState(luaMapStage, "item", cfg)
+		L.SetGlobal("locator", lua.LString("item"))
+		L.SetGlobal("meta", toLValue(L, meta))
+		if err := L.DoString(code); err != nil {
+			b.Fatalf("lua failed: %v", err)
+		}
+		L.Close()
+	}
+}
+
+func BenchmarkLuaSandboxPooled(b *testing.B) {
+	code := "return { locator = locator, value = (meta and meta.value) or 0 }"
+	globals := map[string]any{"locator": "item", "meta": map[string]any{"value": 1}}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, violation, err := runLuaScriptWithSandbox(luaMapStage, nil, "item", globals, code); err != nil || violation != "" {
+			b.Fatalf("lua failed: %v %s", err, violation)
+		}
+	}
+}
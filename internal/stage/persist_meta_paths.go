@@ -4,12 +4,16 @@
 // - Compute the effective persistence root from discovery root and optional outDir.
 // - Derive the canonical sidecar relative path from a locator.
 // - Return matching absolute and relative paths for downstream read/write stages.
+// - Map a sidecar path back to the file or directory locator it describes.
 // Architecture notes:
 // - Path resolution is centralized here so alongside-source mode and dedicated outDir mode cannot drift across stages.
 // - Relative sidecar paths stay locator-based even when outDir is used, which keeps output contracts stable across persistence modes.
 package stage
 
-import "path/filepath"
+import (
+	"path/filepath"
+	"strings"
+)
 
 func persistMetaRoot(meta *Meta, discoveryRoot string) string {
 	root := discoveryRoot
@@ -34,3 +38,14 @@ func persistMetaFilePath(
 	)
 	return abs, rel
 }
+
+// sidecarLocator returns the locator a sidecar path describes: the file for
+// `<file>.thoth.yaml`, the directory for a rollup `<dir>/.thoth.yaml`, and
+// "." for the root `.thoth.yaml`.
+func sidecarLocator(metaFile string) string {
+	base := strings.TrimSuffix(metaFile, ".thoth.yaml")
+	if base == "" {
+		return "."
+	}
+	return strings.TrimSuffix(base, "/")
+}
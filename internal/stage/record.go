@@ -36,6 +36,38 @@ type ShellResult struct {
 	Program         string   `json:"program,omitempty"`
 	WorkingDir      string   `json:"workingDir,omitempty"`
 	Args            []string `json:"args,omitempty"`
+	// Skipped marks a record whose map result set skipShell = true, or a
+	// shell.steps entry whose runIf condition did not hold.
+	Skipped bool `json:"skipped,omitempty"`
+	// Steps holds per-step results keyed by step name when shell.steps is
+	// configured; the top-level fields then summarize the last step that ran.
+	Steps map[string]*ShellResult `json:"steps,omitempty"`
+	// LimitExceeded names the shell.limits rlimit ("cpu" or "fileSize")
+	// that killed the command; ExitCode is then -3.
+	LimitExceeded string `json:"limitExceeded,omitempty"`
+	// Attempts is how many times the command ran when shell.retry is
+	// configured.
+	Attempts int `json:"attempts,omitempty"`
+	// DecodeError is set when stdout could not be decoded with the
+	// configured shell.decode format.
+	DecodeError *ShellDecodeError `json:"decodeError,omitempty"`
+	// Artifacts holds decoded shell.artifacts files keyed by artifact name;
+	// ArtifactsTruncated lists text artifacts cut at captureMaxBytes.
+	Artifacts          map[string]any `json:"artifacts,omitempty"`
+	ArtifactsTruncated []string       `json:"artifactsTruncated,omitempty"`
+	// tmpDir is the per-record {tmpdir} while shell.artifacts commands run.
+	tmpDir string
+	// planning marks a shell-plan render, where {steps.*} placeholders
+	// stay literal because no step has run.
+	planning bool
+}
+
+// ShellDecodeError describes a stdout decode failure; Line is 1-based and
+// only set for line-oriented formats.
+type ShellDecodeError struct {
+	Format  string `json:"format"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
 }
 
 // RecFileInfo holds basic file metadata for a locator.
@@ -4,6 +4,7 @@
 // - Define the shared stage runner function signature and runtime dependencies.
 // - Register named stage runners during package initialization.
 // - Resolve and execute stages by name with a uniform unknown-stage error.
+// - Give each stage run its own warning collector (see warnings.go).
 // Architecture notes:
 // - The registry is intentionally package-global and small because stage composition is built at startup, not via dynamic plugin loading.
 // - Deps is kept minimal so stage implementations can share only the runtime channels and writers they actually need.
@@ -37,7 +38,7 @@ func Run(ctx context.Context, name string, in Envelope, deps Deps) (Envelope, er
 	if !ok {
 		return Envelope{}, ErrUnknown{name: name}
 	}
-	return r(ctx, in, deps)
+	return runWithWarnings(ctx, r, in, deps)
 }
 
 // ErrUnknown is returned when a stage is not found.
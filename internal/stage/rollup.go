@@ -0,0 +1,203 @@
+// File Guide for dev/ai agents:
+// Purpose: Aggregate per-file post.meta into directory-level sidecars for input-pipeline runs with persistMeta enabled.
+// Responsibilities:
+// - Group successful records by every ancestor directory, including the discovery root ".".
+// - Run the rollup Lua combine script bottom-up so each directory sees its direct files and child directory results.
+// - Merge results into existing directory sidecars and write them through the write-updated-meta-files path.
+// Architecture notes:
+// - Directories are processed deepest first and sequentially, so combine order and output never depend on worker scheduling.
+// - Directory sidecars live at `<dir>/.thoth.yaml` in-tree and `<dir>.thoth.yaml` under persistMeta.outDir, mirroring `<file>.thoth.yaml`.
+// - Results are reported in meta.rollupReport instead of new records, so record-level output and counts stay about input files.
+package stage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const rollupStage = "rollup"
+
+type rollupDir struct {
+	files    []map[string]any
+	children []string
+}
+
+// rollupDirSidecarRel returns the sidecar path for dir relative to the
+// persist root.
+func rollupDirSidecarRel(meta *Meta, dir string) string {
+	outDir := meta != nil && meta.PersistMeta != nil && meta.PersistMeta.OutDir != ""
+	if dir == "." {
+		return ".thoth.yaml"
+	}
+	if outDir {
+		return dir + ".thoth.yaml"
+	}
+	return path.Join(dir, ".thoth.yaml")
+}
+
+// collectRollupDirs indexes records with a post.meta object under each of
+// their ancestor directories.
+func collectRollupDirs(records []Record) map[string]*rollupDir {
+	dirs := map[string]*rollupDir{}
+	get := func(d string) *rollupDir {
+		if dirs[d] == nil {
+			dirs[d] = &rollupDir{}
+		}
+		return dirs[d]
+	}
+	for _, r := range records {
+		if r.Error != nil {
+			continue
+		}
+		pm, ok := r.Post.(map[string]any)
+		if !ok {
+			continue
+		}
+		m, ok := asStringMap(pm["meta"])
+		if !ok {
+			continue
+		}
+		d := path.Dir(r.Locator)
+		get(d).files = append(get(d).files, map[string]any{"locator": r.Locator, "meta": m})
+		for d != "." {
+			parent := path.Dir(d)
+			get(parent)
+			d = parent
+		}
+	}
+	for d := range dirs {
+		if d == "." {
+			continue
+		}
+		p := dirs[path.Dir(d)]
+		p.children = append(p.children, d)
+	}
+	for _, rd := range dirs {
+		sort.Strings(rd.children)
+		sort.SliceStable(rd.files, func(i, j int) bool {
+			return rd.files[i]["locator"].(string) < rd.files[j]["locator"].(string)
+		})
+	}
+	return dirs
+}
+
+// rollupOrder returns directories deepest first, then by name.
+func rollupOrder(dirs map[string]*rollupDir) []string {
+	depth := func(d string) int {
+		if d == "." {
+			return 0
+		}
+		return strings.Count(d, "/") + 1
+	}
+	out := sortedKeys(dirs)
+	sort.SliceStable(out, func(i, j int) bool {
+		return depth(out[i]) > depth(out[j])
+	})
+	return out
+}
+
+func rollupRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error) {
+	if in.Meta == nil || in.Meta.Lua == nil || in.Meta.Lua.RollupInline == "" {
+		return in, nil
+	}
+	code := in.Meta.Lua.RollupInline
+	if !containsReturn(code) {
+		code = "return (" + code + ")"
+	}
+	root := determineRoot(in)
+	mode, _ := errorMode(in.Meta)
+	out := in
+	dirs := collectRollupDirs(in.Records)
+	results := map[string]map[string]any{}
+	report := &RollupReport{Dirs: make([]RollupDir, 0)}
+	var envErrs []Error
+	fail := func(dir, msg string) error {
+		if mode != "keep-going" {
+			return fmt.Errorf("%s: %s (%s)", rollupStage, msg, dir)
+		}
+		envErrs = append(envErrs, Error{Stage: rollupStage, Locator: dir, Message: msg})
+		return nil
+	}
+	for _, dir := range rollupOrder(dirs) {
+		rd := dirs[dir]
+		files := make([]any, 0, len(rd.files))
+		for _, f := range rd.files {
+			files = append(files, f)
+		}
+		children := make([]any, 0, len(rd.children))
+		for _, c := range rd.children {
+			if m, ok := results[c]; ok {
+				children = append(children, map[string]any{"dir": c, "meta": m})
+			}
+		}
+		ret, violation, err := runLuaScriptWithSandbox(rollupStage, in.Meta, dir, map[string]any{
+			"dir":      dir,
+			"files":    files,
+			"children": children,
+		}, code)
+		if err != nil || violation != "" {
+			msg := violation
+			if err != nil {
+				msg = err.Error()
+			}
+			if ferr := fail(dir, formatLuaError(rollupStage, dir, code, msg)); ferr != nil {
+				return Envelope{}, ferr
+			}
+			continue
+		}
+		if ret == nil {
+			continue
+		}
+		next, ok := ret.(map[string]any)
+		if !ok {
+			if ferr := fail(dir, "rollup must return a table or nil"); ferr != nil {
+				return Envelope{}, ferr
+			}
+			continue
+		}
+		results[dir] = next
+		entry, msg, err := writeRollupSidecar(in.Meta, root, dir, next)
+		if err != nil {
+			if ferr := fail(dir, msg); ferr != nil {
+				return Envelope{}, ferr
+			}
+			continue
+		}
+		report.Dirs = append(report.Dirs, entry)
+	}
+	out.Meta.RollupReport = report
+	appendSanitizedErrors(&out, envErrs)
+	return out, nil
+}
+
+// writeRollupSidecar merges next into the directory's existing sidecar and
+// persists it with the same writer as file sidecars (honouring dry-run).
+func writeRollupSidecar(meta *Meta, root, dir string, next map[string]any) (RollupDir, string, error) {
+	rel := rollupDirSidecarRel(meta, dir)
+	abs := filepath.Join(persistMetaRoot(meta, root), filepath.FromSlash(rel))
+	existing, _, msg, err := readExistingSidecar(abs, rel)
+	if err != nil {
+		return RollupDir{}, msg, err
+	}
+	merged := deepMerge(existing, next)
+	rec, envE, err := writeOneUpdatedWithMeta(meta, root, Record{
+		Locator: dir,
+		Post:    map[string]any{"existingMetaPath": rel, "nextMeta": merged},
+	})
+	if err != nil {
+		return RollupDir{}, envE.Message, err
+	}
+	entry := RollupDir{Dir: dir, MetaPath: rel, Meta: merged}
+	if pm, ok := rec.Post.(map[string]any); ok {
+		if s, ok := pm["writeSkipped"].(string); ok {
+			entry.WriteSkipped = s
+		}
+	}
+	return entry, "", nil
+}
+
+func init() { Register(rollupStage, rollupRunner) }
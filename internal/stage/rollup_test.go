@@ -0,0 +1,166 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+const rollupCountCode = `
+local total = 0
+for _, f in ipairs(files) do total = total + (f.meta.fns or 0) end
+for _, c in ipairs(children) do total = total + c.meta.fns end
+return { fns = total, files = #files, subdirs = #children }`
+
+func rollupEnvelope(root, outDir string) Envelope {
+	meta := defaultLuaSandboxForTest()
+	meta.Discovery = &DiscoveryMeta{Root: root}
+	meta.Errors = &ErrorsMeta{Mode: "keep-going", EmbedErrors: true}
+	meta.PersistMeta = &PersistMetaMeta{Enabled: true, OutDir: outDir}
+	meta.Lua = &LuaMeta{RollupInline: rollupCountCode}
+	post := func(fns int) map[string]any { return map[string]any{"meta": map[string]any{"fns": fns}} }
+	return Envelope{
+		Records: []Record{
+			{Locator: "internal/stage/a.go", Post: post(2)},
+			{Locator: "internal/stage/b.go", Post: post(3)},
+			{Locator: "internal/config/c.go", Post: post(1)},
+			{Locator: "main.go", Post: post(1)},
+			{Locator: "broken.go", Error: &RecError{Stage: "lua-map", Message: "x"}},
+		},
+		Meta: meta,
+	}
+}
+
+func TestRollup_WritesDirectorySidecarsBottomUp(t *testing.T) {
+	root := t.TempDir()
+	existing := "locator: internal\nmeta:\n  owner: team-core\n  fns: 0\n"
+	if err := os.MkdirAll(filepath.Join(root, "internal"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "internal", ".thoth.yaml"), []byte(existing), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Run(context.Background(), rollupStage, rollupEnvelope(root, ""), Deps{})
+	if err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	var dirs []string
+	for _, d := range out.Meta.RollupReport.Dirs {
+		dirs = append(dirs, d.Dir+"="+d.MetaPath)
+	}
+	want := "internal/config=internal/config/.thoth.yaml,internal/stage=internal/stage/.thoth.yaml,internal=internal/.thoth.yaml,.=.thoth.yaml"
+	if strings.Join(dirs, ",") != want {
+		t.Fatalf("unexpected order: %v", dirs)
+	}
+	b, err := os.ReadFile(filepath.Join(root, "internal", ".thoth.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(b)
+	if !strings.Contains(got, "owner: team-core") || !strings.Contains(got, "fns: 6") || !strings.Contains(got, "subdirs: 2") {
+		t.Fatalf("unexpected merged sidecar:\n%s", got)
+	}
+	b, err = os.ReadFile(filepath.Join(root, ".thoth.yaml"))
+	if err != nil || !strings.Contains(string(b), "fns: 7") {
+		t.Fatalf("unexpected root sidecar: %v\n%s", err, b)
+	}
+}
+
+func TestRollup_OutDirDryRunAndLuaErrors(t *testing.T) {
+	root := t.TempDir()
+	in := rollupEnvelope(root, "sidecars")
+	in.Meta.PersistMeta.DryRun = true
+	in.Meta.Lua.RollupInline = `if dir == "internal/config" then error("boom") end
+return { n = #files }`
+	out, err := Run(context.Background(), rollupStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	if len(out.Errors) != 1 || out.Errors[0].Locator != "internal/config" || !strings.Contains(out.Errors[0].Message, "boom") {
+		t.Fatalf("unexpected errors: %+v", out.Errors)
+	}
+	if len(out.Meta.RollupReport.Dirs) != 3 || out.Meta.RollupReport.Dirs[0].MetaPath != "internal/stage.thoth.yaml" ||
+		out.Meta.RollupReport.Dirs[0].WriteSkipped != "dry-run" {
+		t.Fatalf("unexpected report: %+v", out.Meta.RollupReport.Dirs)
+	}
+	if _, err := os.Stat(filepath.Join(root, "sidecars")); !os.IsNotExist(err) {
+		t.Fatalf("dry-run must not write sidecars: %v", err)
+	}
+
+	in = rollupEnvelope(root, "")
+	in.Meta.Errors.Mode = "fail-fast"
+	in.Meta.Lua.RollupInline = `return "x"`
+	_, err = Run(context.Background(), rollupStage, in, Deps{})
+	if err == nil || err.Error() != "rollup: rollup must return a table or nil (internal/config)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_RollupRequiresPersistMeta(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"input-pipeline\"\n  rollup: { inline: \"return {}\" }\n}\n"
+	_, err := runValidateConfigWithContent(t, "rollup_no_persist_validate_test.cue", content)
+	if err == nil || err.Error() != "invalid rollup: requires persistMeta.enabled=true" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRollup_DiffMetaRoundTrip(t *testing.T) {
+	root := t.TempDir()
+	in := rollupEnvelope(root, "")
+	for _, r := range in.Records {
+		p := filepath.Join(root, filepath.FromSlash(r.Locator))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("package x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Run(context.Background(), rollupStage, in, Deps{}); err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	diff := func() *DiffReport {
+		t.Helper()
+		env := Envelope{Records: []Record{}, Meta: &Meta{
+			Config:    &ConfigMeta{Action: "diff-meta"},
+			Discovery: &DiscoveryMeta{Root: root},
+			Errors:    &ErrorsMeta{Mode: "keep-going"},
+			DiffMeta:  &DiffMetaMeta{},
+		}}
+		for _, name := range []string{"discover-input-files", "discover-meta-files", "parse-validate-yaml", "validate-locators", computeMetaDiffStage} {
+			var err error
+			if env, err = Run(context.Background(), name, env, Deps{}); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+		}
+		if len(env.Meta.MetaFiles) != 4 {
+			t.Fatalf("expected the 4 directory sidecars, got %v", env.Meta.MetaFiles)
+		}
+		return env.Meta.Diff
+	}
+	if d := diff(); d.OrphanCount != 0 {
+		t.Fatalf("directory sidecars must not be orphans: %v", d.OrphanMetaFiles)
+	}
+	if err := os.Remove(filepath.Join(root, "internal", "config", "c.go")); err != nil {
+		t.Fatal(err)
+	}
+	if d := diff(); strings.Join(d.OrphanMetaFiles, ",") != "internal/config/.thoth.yaml" {
+		t.Fatalf("expected the emptied directory's sidecar to be an orphan: %v", d.OrphanMetaFiles)
+	}
+}
+
+func TestKnownLocatorSet_DirectorySidecars(t *testing.T) {
+	known := knownLocatorSet(Envelope{Meta: &Meta{MetaFiles: []string{".thoth.yaml", "internal/.thoth.yaml", "a.go.thoth.yaml"}}})
+	for _, want := range []string{".", "internal", "a.go"} {
+		if _, ok := known[want]; !ok {
+			t.Fatalf("missing %q in %v", want, known)
+		}
+	}
+	if _, ok := known["internal/"]; ok {
+		t.Fatalf("unexpected trailing-slash locator in %v", known)
+	}
+}
@@ -0,0 +1,135 @@
+// File Guide for dev/ai agents:
+// Purpose: Give shell commands a per-record temp directory and collect the report files they write there.
+// Responsibilities:
+// - Create `{tmpdir}` before the command or steps chain runs and remove it before the record is returned.
+// - Read each declared shell.artifacts file, capped at captureMaxBytes, and decode it as json, yaml, or text.
+// - Report missing, oversized, or undecodable artifacts as record errors under errors.mode.
+// Architecture notes:
+// - The temp directory rides on rec.Shell (unexported) while commands run, so the renderer reads `{tmpdir}` from the record like `{steps.*}`.
+// - Retries and steps share one directory per record; artifacts are collected once, after the last command, and only when it did not fail.
+// - Artifacts are read in config order and symlinks are rejected, so results never depend on what the tool links to outside `{tmpdir}`.
+package stage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// processShellArtifacts runs the record's command(s) inside a fresh
+// {tmpdir} and attaches the collected artifacts.
+func processShellArtifacts(ctx context.Context, rec Record, opts shellOptions, mode string) (Record, *Error, error) {
+	dir, err := os.MkdirTemp("", "thoth-shell-")
+	if err != nil {
+		msg := sanitizeErrorMessage(fmt.Sprintf("artifacts tmpdir: %v", err))
+		rec.Shell = &ShellResult{ExitCode: -1, Error: strPtr(msg)}
+		return shellRecordFailure(rec, msg, mode)
+	}
+	defer func() { _ = os.RemoveAll(dir) }()
+	rec.Shell = &ShellResult{tmpDir: dir}
+	out, envErr, err := runShellRecord(ctx, rec, opts, mode)
+	if out.Shell != nil {
+		out.Shell.tmpDir = ""
+	}
+	if err != nil || out.Error != nil || out.Shell == nil {
+		return out, envErr, err
+	}
+	if failure := collectShellArtifacts(dir, opts, out.Shell); failure != "" {
+		out.Shell.Error = strPtr(failure)
+		return shellRecordFailure(out, failure, mode)
+	}
+	return out, envErr, nil
+}
+
+// shellTmpDir returns the record's {tmpdir}, empty when shell.artifacts
+// is not configured.
+func shellTmpDir(rec Record) string {
+	if rec.Shell == nil {
+		return ""
+	}
+	return rec.Shell.tmpDir
+}
+
+func collectShellArtifacts(dir string, opts shellOptions, shell *ShellResult) string {
+	for _, a := range opts.artifacts {
+		data, truncated, found, err := readShellArtifact(
+			filepath.Join(dir, filepath.FromSlash(a.Path)),
+			opts.captureMaxBytes,
+		)
+		if err != nil {
+			return sanitizeErrorMessage(fmt.Sprintf("artifact %s: %v", a.Name, err))
+		}
+		if !found {
+			if a.Optional {
+				continue
+			}
+			return fmt.Sprintf("artifact %s: missing %s", a.Name, a.Path)
+		}
+		var v any
+		if a.Decode == "text" {
+			v = data
+			if truncated {
+				shell.ArtifactsTruncated = append(shell.ArtifactsTruncated, a.Name)
+			}
+		} else {
+			if truncated {
+				return fmt.Sprintf(
+					"artifact %s: exceeds captureMaxBytes %d",
+					a.Name,
+					opts.captureMaxBytes,
+				)
+			}
+			decoded, decErr := decodeShellStdout(a.Decode, opts, &data)
+			if decErr != nil {
+				return sanitizeErrorMessage(fmt.Sprintf(
+					"artifact %s: invalid %s: %s",
+					a.Name,
+					a.Decode,
+					decErr.Message,
+				))
+			}
+			v = decoded
+		}
+		if shell.Artifacts == nil {
+			shell.Artifacts = map[string]any{}
+		}
+		shell.Artifacts[a.Name] = v
+	}
+	return ""
+}
+
+// readShellArtifact reads at most maxBytes of a regular file; a
+// non-positive maxBytes leaves the size uncapped.
+func readShellArtifact(p string, maxBytes int) (string, bool, bool, error) {
+	info, err := os.Lstat(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, false, nil
+	}
+	if err != nil {
+		return "", false, false, err
+	}
+	if !info.Mode().IsRegular() {
+		return "", false, false, fmt.Errorf("not a regular file")
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return "", false, false, err
+	}
+	defer func() { _ = f.Close() }()
+	var r io.Reader = f
+	if maxBytes > 0 {
+		r = io.LimitReader(f, int64(maxBytes)+1)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", false, false, err
+	}
+	if maxBytes > 0 && len(b) > maxBytes {
+		return string(b[:maxBytes]), true, true, nil
+	}
+	return string(b), false, true, nil
+}
@@ -0,0 +1,131 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestProcessShellRecord_Artifacts(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.argsT = []string{"-c", `printf '\173"n":1\175' > "$1/report.json"; printf 'a: [x]' > "$1/r.yaml"; printf 'hello world' > "$1/log.txt"; printf '%s' "$1"`, "sh", "{tmpdir}"}
+	opts.captureMaxBytes = 8
+	opts.artifacts = []ShellArtifactMeta{
+		{Name: "report", Path: "report.json", Decode: "json"},
+		{Name: "cfg", Path: "r.yaml", Decode: "yaml"},
+		{Name: "log", Path: "log.txt", Decode: "text"},
+		{Name: "extra", Path: "sub/extra.txt", Decode: "text", Optional: true},
+	}
+	rec, envErr, err := processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+	if err != nil || envErr != nil {
+		t.Fatalf("unexpected errors: %v %+v", err, envErr)
+	}
+	want := map[string]any{
+		"report": map[string]any{"n": float64(1)},
+		"cfg":    map[string]any{"a": []any{"x"}},
+		"log":    "hello wo",
+	}
+	if !reflect.DeepEqual(rec.Shell.Artifacts, want) {
+		t.Fatalf("unexpected artifacts: %#v", rec.Shell.Artifacts)
+	}
+	if !reflect.DeepEqual(rec.Shell.ArtifactsTruncated, []string{"log"}) {
+		t.Fatalf("unexpected truncation: %v", rec.Shell.ArtifactsTruncated)
+	}
+	opts.captureMaxBytes = 4096
+	opts.artifacts = []ShellArtifactMeta{{Name: "none", Path: "none.txt", Decode: "text", Optional: true}}
+	opts.argsT = []string{"-c", `printf '%s' "$1"`, "sh", "{tmpdir}"}
+	rec, _, _ = processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+	// The command printed its {tmpdir}; it must be removed afterwards.
+	dir := *rec.Shell.Stdout
+	if !strings.Contains(dir, "thoth-shell-") {
+		t.Fatalf("unexpected tmpdir: %q", dir)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected tmpdir cleanup, stat err=%v", err)
+	}
+}
+
+func TestProcessShellRecord_ArtifactFailures(t *testing.T) {
+	requirePOSIXShell(t)
+	cases := []struct {
+		script string
+		art    ShellArtifactMeta
+		want   string
+	}{
+		{script: "true", art: ShellArtifactMeta{Name: "r", Path: "r.json", Decode: "json"}, want: "artifact r: missing r.json"},
+		{script: `printf '\173' > "$1/r.json"`, art: ShellArtifactMeta{Name: "r", Path: "r.json", Decode: "json"}, want: "artifact r: invalid json: "},
+		{script: `printf '[1,2,3,4,5,6,7,8]' > "$1/r.json"`, art: ShellArtifactMeta{Name: "r", Path: "r.json", Decode: "json"}, want: "artifact r: exceeds captureMaxBytes 8"},
+		{script: `ln -s /etc/hostname "$1/r.txt"`, art: ShellArtifactMeta{Name: "r", Path: "r.txt", Decode: "text"}, want: "artifact r: not a regular file"},
+	}
+	for _, tc := range cases {
+		opts := baseShellOpts()
+		opts.captureMaxBytes = 8
+		opts.argsT = []string{"-c", tc.script, "sh", "{tmpdir}"}
+		opts.artifacts = []ShellArtifactMeta{tc.art}
+		rec, envErr, err := processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+		if err != nil || envErr == nil || !strings.HasPrefix(envErr.Message, tc.want) || rec.Error == nil {
+			t.Fatalf("%s: unexpected outcome: %v %+v", tc.script, err, envErr)
+		}
+	}
+}
+
+func TestProcessShellRecord_ArtifactsWithSteps(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.steps = []shellStep{
+		{name: "write", program: "sh", argsT: []string{"-c", `printf 'x=1' > "$1/out.txt"`, "sh", "{tmpdir}"}},
+		{name: "append", program: "sh", argsT: []string{"-c", `printf ';y=2' >> "$1/out.txt"`, "sh", "{tmpdir}"}},
+	}
+	opts.artifacts = []ShellArtifactMeta{{Name: "out", Path: "out.txt", Decode: "text"}}
+	rec, envErr, err := processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+	if err != nil || envErr != nil || rec.Shell.Artifacts["out"] != "x=1;y=2" {
+		t.Fatalf("unexpected result: %v %+v %+v", err, envErr, rec.Shell)
+	}
+}
+
+func TestRenderArgs_TmpdirRequiresArtifacts(t *testing.T) {
+	_, err := renderArgs([]string{"{tmpdir}/out.json"}, Record{}, baseShellOpts())
+	if err == nil || err.Error() != "template placeholder {tmpdir} requires shell.artifacts" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_ShellArtifacts(t *testing.T) {
+	wrap := func(artifacts string) string {
+		return "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+			"  shell: { enabled: true, argsTemplate: [\"{tmpdir}\"], artifacts: " + artifacts + " }\n}\n"
+	}
+	out, err := runValidateConfigWithContent(t, "shell_artifacts_test.cue",
+		wrap(`[{ name: "report", path: "out/report.json", decode: "json" }, { name: "log", path: "log.txt", optional: true }]`))
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	want := []ShellArtifactMeta{
+		{Name: "report", Path: "out/report.json", Decode: "json"},
+		{Name: "log", Path: "log.txt", Decode: "text", Optional: true},
+	}
+	if !reflect.DeepEqual(out.Meta.Shell.Artifacts, want) {
+		t.Fatalf("unexpected artifacts meta: %+v", out.Meta.Shell.Artifacts)
+	}
+	bad := map[string]string{
+		"shell_artifacts_escape_test.cue": `[{ name: "r", path: "../r.json" }]`,
+		"shell_artifacts_dup_test.cue":    `[{ name: "r", path: "a" }, { name: "r", path: "b" }]`,
+		"shell_artifacts_decode_test.cue": `[{ name: "r", path: "a", decode: "xml" }]`,
+	}
+	want2 := map[string]string{
+		"shell_artifacts_escape_test.cue": "invalid shell.artifacts[0].path: must be a relative path inside {tmpdir}",
+		"shell_artifacts_dup_test.cue":    "invalid shell.artifacts[1].name: duplicate artifact \"r\"",
+		"shell_artifacts_decode_test.cue": "invalid shell.artifacts[0].decode: must be one of json, yaml, text",
+	}
+	for name, artifacts := range bad {
+		_, err := runValidateConfigWithContent(t, name, wrap(artifacts))
+		if err == nil || err.Error() != want2[name] {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
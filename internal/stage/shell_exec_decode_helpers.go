@@ -0,0 +1,192 @@
+// File Guide for dev/ai agents:
+// Purpose: Decode captured shell stdout into structured values for ShellResult.JSON.
+// Responsibilities:
+// - Dispatch `shell.decode` formats: json, ndjson, yaml, csv, tsv, kv, and lines.
+// - Apply decoder options such as CSV header handling and delimiters.
+// - Report failures as ShellDecodeError values with the format and, for line-oriented formats, the 1-based line.
+// Architecture notes:
+// - Decoded values are normalized to string-keyed maps, []any, strings, bools, and float64 so postMap and templates see the same shapes as decoded JSON.
+// - CSV/TSV/kv/lines values stay strings; type coercion is left to Lua where the intent is known.
+// - `decodeJsonStdout: true` is the legacy spelling of `decode: "json"` and resolves here so both paths share one error shape.
+package stage
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// shellDecodeFormat resolves the effective stdout decoder; "" disables decoding.
+func shellDecodeFormat(opts shellOptions) string {
+	switch opts.decode {
+	case "none":
+		return ""
+	case "":
+		if opts.decodeJSONStdout {
+			return "json"
+		}
+		return ""
+	}
+	return opts.decode
+}
+
+func (e *ShellDecodeError) String() string {
+	if e.Line > 0 {
+		return fmt.Sprintf(
+			"invalid %s stdout: line %d: %s",
+			strings.ToUpper(e.Format),
+			e.Line,
+			e.Message,
+		)
+	}
+	return fmt.Sprintf("invalid %s stdout: %s", strings.ToUpper(e.Format), e.Message)
+}
+
+// luaView is the decode error shape exposed to postMap as shell.decodeError.
+func (e *ShellDecodeError) luaView() map[string]any {
+	return map[string]any{"format": e.Format, "line": e.Line, "message": e.Message}
+}
+
+func decodeShellStdout(format string, opts shellOptions, stdout *string) (any, *ShellDecodeError) {
+	if stdout == nil {
+		return nil, &ShellDecodeError{Format: format, Message: "stdout missing"}
+	}
+	fail := func(line int, err error) (any, *ShellDecodeError) {
+		return nil, &ShellDecodeError{Format: format, Line: line, Message: err.Error()}
+	}
+	switch format {
+	case "json":
+		v, err := decodeShellStdoutJSON(stdout)
+		if err != nil {
+			return fail(0, err)
+		}
+		return v, nil
+	case "ndjson":
+		return decodeShellNDJSON(*stdout, format)
+	case "yaml":
+		var v any
+		if err := yaml.Unmarshal([]byte(*stdout), &v); err != nil {
+			return fail(0, err)
+		}
+		return normalizeDecodedValue(v), nil
+	case "csv", "tsv":
+		return decodeShellCSV(*stdout, format, opts)
+	case "kv":
+		return decodeShellKV(*stdout, format, opts)
+	case "lines":
+		out := []any{}
+		for _, line := range splitShellLines(*stdout) {
+			out = append(out, line)
+		}
+		return out, nil
+	}
+	return fail(0, fmt.Errorf("unknown decoder"))
+}
+
+// splitShellLines splits stdout on newlines, dropping CRs and the final
+// empty line left by a trailing newline.
+func splitShellLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+	return lines
+}
+
+func decodeShellNDJSON(s string, format string) (any, *ShellDecodeError) {
+	out := []any{}
+	for i, line := range splitShellLines(s) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		v, err := decodeShellStdoutJSON(&line)
+		if err != nil {
+			return nil, &ShellDecodeError{Format: format, Line: i + 1, Message: err.Error()}
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func decodeShellCSV(s string, format string, opts shellOptions) (any, *ShellDecodeError) {
+	r := csv.NewReader(strings.NewReader(s))
+	r.Comma = ','
+	if format == "tsv" {
+		r.Comma = '\t'
+		r.LazyQuotes = true
+	}
+	if opts.decodeDelimiter != "" {
+		r.Comma, _ = utf8.DecodeRuneInString(opts.decodeDelimiter)
+	}
+	var header []string
+	out := []any{}
+	for {
+		row, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			line := 0
+			var pe *csv.ParseError
+			if errors.As(err, &pe) {
+				line = pe.Line
+				err = pe.Err
+			}
+			return nil, &ShellDecodeError{Format: format, Line: line, Message: err.Error()}
+		}
+		if opts.decodeHeader && header == nil {
+			header = row
+			continue
+		}
+		if header == nil {
+			cells := make([]any, len(row))
+			for i := range row {
+				cells[i] = row[i]
+			}
+			out = append(out, cells)
+			continue
+		}
+		m := make(map[string]any, len(header))
+		for i, name := range header {
+			m[name] = row[i]
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func decodeShellKV(s string, format string, opts shellOptions) (any, *ShellDecodeError) {
+	sep := opts.decodeDelimiter
+	if sep == "" {
+		sep = "="
+	}
+	out := map[string]any{}
+	sc := bufio.NewScanner(strings.NewReader(s))
+	sc.Buffer(make([]byte, 0, 64*1024), len(s)+1)
+	for i := 1; sc.Scan(); i++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, sep)
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, &ShellDecodeError{
+				Format:  format,
+				Line:    i,
+				Message: fmt.Sprintf("expected key%svalue", sep),
+			}
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
@@ -0,0 +1,113 @@
+package stage
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestDecodeShellStdout_Formats(t *testing.T) {
+	cases := []struct {
+		format, delim, in string
+		noHeader          bool
+		want              any
+	}{
+		{format: "ndjson", in: "{\"a\":1}\n\n[2]\n", want: []any{map[string]any{"a": float64(1)}, []any{float64(2)}}},
+		{format: "yaml", in: "a: 1\nb: [x, true]\n", want: map[string]any{"a": float64(1), "b": []any{"x", true}}},
+		{format: "csv", in: "name,n\nfoo,1\n\"b,ar\",2\n", want: []any{map[string]any{"name": "foo", "n": "1"}, map[string]any{"name": "b,ar", "n": "2"}}},
+		{format: "csv", in: "a;b\n", delim: ";", noHeader: true, want: []any{[]any{"a", "b"}}},
+		{format: "tsv", in: "k\tv\nx\ty\n", want: []any{map[string]any{"k": "x", "v": "y"}}},
+		{format: "kv", in: "# comment\nfiles = 3\nlang=go\n", want: map[string]any{"files": "3", "lang": "go"}},
+		{format: "kv", in: "a: 1\n", delim: ":", want: map[string]any{"a": "1"}},
+		{format: "lines", in: "one\r\ntwo\n", want: []any{"one", "two"}},
+		{format: "lines", in: "", want: []any{}},
+	}
+	for _, tc := range cases {
+		opts := baseShellOpts()
+		opts.decodeHeader = !tc.noHeader
+		opts.decodeDelimiter = tc.delim
+		got, decErr := decodeShellStdout(tc.format, opts, &tc.in)
+		if decErr != nil {
+			t.Fatalf("%s: unexpected error: %+v", tc.format, decErr)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: got %#v want %#v", tc.format, got, tc.want)
+		}
+	}
+}
+
+func TestDecodeShellStdout_ErrorsCarryLine(t *testing.T) {
+	opts := baseShellOpts()
+	opts.decodeHeader = true
+	cases := map[string]string{
+		"ndjson": "{}\n{bad\n",
+		"csv":    "a,b\n1,2\n3\n",
+		"kv":     "a=1\nnope\n",
+	}
+	for format, in := range cases {
+		_, decErr := decodeShellStdout(format, opts, &in)
+		if decErr == nil || decErr.Format != format {
+			t.Fatalf("%s: expected decode error, got %+v", format, decErr)
+		}
+		wantLine := 2
+		if format == "csv" {
+			wantLine = 3
+		}
+		if decErr.Line != wantLine || !strings.HasPrefix(decErr.String(), "invalid "+strings.ToUpper(format)+" stdout: line ") {
+			t.Fatalf("%s: unexpected error: %+v %q", format, decErr, decErr.String())
+		}
+	}
+}
+
+func TestProcessShellRecord_DecodeErrorKeepGoing(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.decode = "kv"
+	opts.argsT = []string{"-c", "printf 'a=1\\nbroken\\n'"}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if fatal != nil || envErr == nil || envErr.Message != "invalid KV stdout: line 2: expected key=value" {
+		t.Fatalf("unexpected outcome: %v %+v", fatal, envErr)
+	}
+	if rec.Shell.DecodeError == nil || rec.Shell.DecodeError.Line != 2 || rec.Shell.JSON != nil || rec.Error == nil {
+		t.Fatalf("unexpected shell result: %+v", rec.Shell)
+	}
+	opts.decode = "none"
+	opts.decodeJSONStdout = true
+	rec, envErr, _ = processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if envErr != nil || rec.Shell.JSON != nil {
+		t.Fatalf("decode none should disable decoding: %+v %+v", envErr, rec.Shell)
+	}
+}
+
+func TestValidateConfig_ShellDecode(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: { enabled: true, argsTemplate: [\"x\"], decode: \"csv\", decodeOptions: { delimiter: \";\" } }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_decode_format_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Shell.Decode != "csv" || out.Meta.Shell.DecodeOptions == nil ||
+		!out.Meta.Shell.DecodeOptions.Header || out.Meta.Shell.DecodeOptions.Delimiter != ";" {
+		t.Fatalf("unexpected decode meta: %+v %+v", out.Meta.Shell.Decode, out.Meta.Shell.DecodeOptions)
+	}
+	bad := map[string]string{
+		"shell_decode_unknown_test.cue":  "decode: \"xml\"",
+		"shell_decode_conflict_test.cue": "decode: \"yaml\", decodeJsonStdout: true",
+		"shell_decode_delim_test.cue":    "decode: \"tsv\", decodeOptions: { delimiter: \"ab\" }",
+	}
+	want := map[string]string{
+		"shell_decode_unknown_test.cue":  "invalid shell.decode: must be one of none, json, ndjson, yaml, csv, tsv, kv, lines",
+		"shell_decode_conflict_test.cue": "invalid shell.decode: conflicts with decodeJsonStdout=true",
+		"shell_decode_delim_test.cue":    "invalid shell.decodeOptions.delimiter: must be a single character for csv/tsv",
+	}
+	for name, shell := range bad {
+		cfg := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  shell: { enabled: true, argsTemplate: [\"x\"], " + shell + " }\n}\n"
+		_, err := runValidateConfigWithContent(t, name, cfg)
+		if err == nil || err.Error() != want[name] {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
@@ -24,4 +24,34 @@ type shellOptions struct {
 	strictTemplating bool
 	killProcessGroup bool
 	termGraceMs      int
+	steps            []shellStep
+	stdinMode        string
+	stdinSource      string
+	stdinTemplate    string
+	root             string
+	decode           string
+	decodeHeader     bool
+	decodeDelimiter  string
+	acceptExitCodes  []ShellExitCodeRange
+	retry            *ShellRetryMeta
+	inheritEnv       bool
+	envAllowlist     []string
+	limits           *ShellLimitsMeta
+	workingDirMarker []string
+	artifacts        []ShellArtifactMeta
+	// sem caps concurrent child processes for one stage run when
+	// shell.maxConcurrent is set; nil means only workers bound it.
+	sem chan struct{}
+}
+
+// shellStep is one resolved shell.steps entry; other options are shared
+// with the stage-level shellOptions.
+type shellStep struct {
+	name             string
+	program          string
+	argsT            []string
+	decodeJSONStdout bool
+	decode           string
+	runIfStep        string
+	runIfExitCodes   []int
 }
@@ -3,6 +3,7 @@ package stage
 import (
 	"context"
 	"runtime"
+	"slices"
 	"strings"
 	"testing"
 )
@@ -219,7 +220,7 @@ func TestRenderArgs_DirectPlaceholders(t *testing.T) {
 			"{json}",
 		},
 		rec,
-		true,
+		baseShellOpts(),
 	)
 	if err != nil {
 		t.Fatalf("renderArgs err: %v", err)
@@ -245,7 +246,7 @@ func TestRenderArgs_DirectPlaceholders(t *testing.T) {
 }
 
 func TestRenderArgs_StrictTemplatingRejectsUnknownPlaceholder(t *testing.T) {
-	_, err := renderArgs([]string{"{nope}"}, Record{}, true)
+	_, err := renderArgs([]string{"{nope}"}, Record{}, baseShellOpts())
 	if err == nil {
 		t.Fatalf("expected error")
 	}
@@ -258,7 +259,7 @@ func TestRenderArgs_MissingMappedValueFailsClearly(t *testing.T) {
 	_, err := renderArgs(
 		[]string{"{mapped.kind}"},
 		Record{Mapped: map[string]any{"name": "x"}},
-		true,
+		baseShellOpts(),
 	)
 	if err == nil {
 		t.Fatalf("expected error")
@@ -267,3 +268,178 @@ func TestRenderArgs_MissingMappedValueFailsClearly(t *testing.T) {
 		t.Fatalf("unexpected error: %q", got)
 	}
 }
+
+func TestProcessShellRecord_SkipShellFromMap(t *testing.T) {
+	opts := baseShellOpts()
+	opts.program = "this-program-does-not-exist-xyz"
+	rec, envErr, fatal := processShellRecord(
+		context.Background(),
+		Record{Locator: "gen.go", Mapped: map[string]any{"skipShell": true, "kind": "generated"}},
+		opts,
+		"fail-fast",
+	)
+	if fatal != nil || envErr != nil {
+		t.Fatalf("skipped record should not run: %v %+v", fatal, envErr)
+	}
+	if rec.Shell == nil || !rec.Shell.Skipped || rec.Shell.Stdout != nil {
+		t.Fatalf("expected skipped shell result: %+v", rec.Shell)
+	}
+	post, _, err := processLuaPostMapRecord(rec, `return { skipped = shell.skipped, kind = mapped.kind }`, "fail-fast", defaultLuaSandboxForTest())
+	if err != nil {
+		t.Fatalf("postMap: %v", err)
+	}
+	if m := post.Post.(map[string]any); m["skipped"] != true || m["kind"] != "generated" {
+		t.Fatalf("postMap should see shell.skipped: %+v", post.Post)
+	}
+	_, _, fatal = processShellRecord(
+		context.Background(),
+		Record{Locator: "a.go", Mapped: map[string]any{"skipShell": "yes"}},
+		opts,
+		"fail-fast",
+	)
+	if fatal == nil {
+		t.Fatalf("only skipShell = true should skip")
+	}
+}
+
+func TestRenderArgs_RichPlaceholders(t *testing.T) {
+	t.Setenv("THOTH_TPL_TEST", "from-env")
+	opts := baseShellOpts()
+	opts.inheritEnv = true
+	rec := Record{
+		Locator: "it's/a b.go",
+		Meta:    map[string]any{"owner": map[string]any{"team": "core"}},
+		Mapped: map[string]any{
+			"a":    map[string]any{"b": []any{"first", map[string]any{"c": 2}}},
+			"list": []any{"x", "y z", 3},
+		},
+		FileInfo: &RecFileInfo{Size: 42},
+		Git:      &RecGit{Status: "modified"},
+	}
+	args, err := renderArgs(
+		[]string{
+			"{mapped.a.b[0]}",
+			"{mapped.a.b[1].c}",
+			"{meta.owner.team}",
+			"{fileInfo.size}",
+			"{git.status}",
+			"{env.THOTH_TPL_TEST}",
+			"{mapped.lang|go}",
+			"{env.THOTH_TPL_MISSING|none}",
+			"{mapped.list|join:,}",
+			"--files={mapped.list|join: |shq}",
+			"{locator|shq}",
+			"{mapped.a.b[1]|json}",
+			"{mapped.lang||shq}",
+		},
+		rec,
+		opts,
+	)
+	if err != nil {
+		t.Fatalf("renderArgs err: %v", err)
+	}
+	want := []string{
+		"first",
+		"2",
+		"core",
+		"42",
+		"modified",
+		"from-env",
+		"go",
+		"none",
+		"x,y z,3",
+		`--files='x y z 3'`,
+		`'it'\''s/a b.go'`,
+		`{"c":2}`,
+		`''`,
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("arg[%d]=%q want %q", i, args[i], want[i])
+		}
+	}
+}
+
+func TestRenderArgs_RichPlaceholderErrors(t *testing.T) {
+	rec := Record{Mapped: map[string]any{"a": []any{"x"}, "s": "str"}}
+	cases := map[string]string{
+		"{mapped.a[3]}":          "template placeholder {mapped.a[3]} missing value",
+		"{mapped.s[0]}":          "template placeholder {mapped.s[0]} requires list value",
+		"{mapped.a[x]}":          "template placeholder {mapped.a[x]} invalid path",
+		"{mapped.s|join:,}":      "template placeholder {mapped.s|join:,}: join requires list value",
+		"{mapped.s|shq|oops}":    "template placeholder {mapped.s|shq|oops}: unknown filter oops",
+		"{fileInfo.size}":        "template placeholder {fileInfo.size} requires object value",
+		"{env.THOTH_TPL_ABSENT}": "template placeholder {env.THOTH_TPL_ABSENT} missing value",
+		"{other.x|d}":            "strict templating: invalid placeholder {other.x|d}",
+	}
+	for tpl, want := range cases {
+		_, err := renderArgs([]string{tpl}, rec, baseShellOpts())
+		if err == nil || err.Error() != want {
+			t.Fatalf("%s: unexpected error: %v", tpl, err)
+		}
+	}
+	opts := baseShellOpts()
+	opts.strictTemplating = false
+	args, err := renderArgs([]string{"{other.x|d}"}, rec, opts)
+	if err != nil || args[0] != "{other.x|d}" {
+		t.Fatalf("non-strict should keep unknown placeholders: %v %v", args, err)
+	}
+}
+
+func TestRenderArgs_NonStrictKeepsUnresolvedContextPlaceholders(t *testing.T) {
+	opts := baseShellOpts()
+	opts.strictTemplating = false
+	rec := Record{Mapped: map[string]any{"kind": "go"}}
+	tpls := []string{
+		"{meta.owner}",
+		"{fileInfo.size}",
+		"{git.status}",
+		"--env={env.THOTH_TPL_ABSENT}",
+		"{steps.lint.exitCode}",
+		"{tmpdir}/out.json",
+		"{mapped.kind}",
+	}
+	args, err := renderArgs(tpls, rec, opts)
+	if err != nil {
+		t.Fatalf("renderArgs err: %v", err)
+	}
+	want := append(append([]string(nil), tpls[:6]...), "go")
+	for i := range want {
+		if args[i] != want[i] {
+			t.Fatalf("arg[%d]=%q want %q", i, args[i], want[i])
+		}
+	}
+	if _, err := renderArgs([]string{"{mapped.lang}"}, rec, opts); err == nil {
+		t.Fatalf("missing mapped value should still fail without strict templating")
+	}
+}
+
+func TestRenderArgs_EnvFollowsChildEnvironment(t *testing.T) {
+	t.Setenv("THOTH_TPL_SECRET", "hidden")
+	t.Setenv("THOTH_TPL_ALLOWED", "shared")
+	opts := baseShellOpts()
+	opts.inheritEnv = false
+	opts.envAllowlist = []string{"THOTH_TPL_ALLOWED"}
+	opts.env = map[string]string{"THOTH_TPL_SECRET": "overlay", "THOTH_TPL_EXTRA": "set"}
+	args, err := renderArgs(
+		[]string{"{env.THOTH_TPL_ALLOWED}", "{env.THOTH_TPL_SECRET}", "{env.THOTH_TPL_EXTRA}"},
+		Record{},
+		opts,
+	)
+	if err != nil {
+		t.Fatalf("renderArgs err: %v", err)
+	}
+	if want := []string{"shared", "overlay", "set"}; !slices.Equal(args, want) {
+		t.Fatalf("args=%q want %q", args, want)
+	}
+	delete(opts.env, "THOTH_TPL_SECRET")
+	_, err = renderArgs([]string{"{env.THOTH_TPL_SECRET}"}, Record{}, opts)
+	if err == nil || err.Error() != "template placeholder {env.THOTH_TPL_SECRET} missing value" {
+		t.Fatalf("variable outside the allowlist must not resolve: %v", err)
+	}
+	opts.inheritEnv = true
+	args, err = renderArgs([]string{"{env.THOTH_TPL_SECRET}"}, Record{}, opts)
+	if err != nil || args[0] != "hidden" {
+		t.Fatalf("inheritEnv should expose parent variables: %v %v", args, err)
+	}
+}
@@ -11,6 +11,7 @@ package stage
 
 import (
 	"errors"
+	"fmt"
 	"path/filepath"
 )
 
@@ -30,6 +31,10 @@ func buildShellOptions(in Envelope) shellOptions {
 		strictTemplating: true,
 		killProcessGroup: true,
 		termGraceMs:      defaultShellTermGraceMs,
+		stdinMode:        "none",
+		root:             ".",
+		decodeHeader:     true,
+		inheritEnv:       true,
 	}
 	if in.Meta == nil || in.Meta.Shell == nil {
 		return opts
@@ -43,10 +48,50 @@ func buildShellOptions(in Envelope) shellOptions {
 	if len(cfg.ArgsTemplate) > 0 {
 		opts.argsT = append([]string(nil), cfg.ArgsTemplate...)
 	}
+	for _, st := range cfg.Steps {
+		step := shellStep{
+			name:             st.Name,
+			program:          st.Program,
+			argsT:            append([]string(nil), st.ArgsTemplate...),
+			decodeJSONStdout: st.DecodeJSONStdout,
+			decode:           st.Decode,
+		}
+		if step.program == "" {
+			step.program = opts.program
+		}
+		if st.RunIf != nil {
+			step.runIfStep = st.RunIf.Step
+			step.runIfExitCodes = append([]int(nil), st.RunIf.ExitCodes...)
+		}
+		opts.steps = append(opts.steps, step)
+	}
 	root := "."
 	if in.Meta.Discovery != nil && in.Meta.Discovery.Root != "" {
 		root = in.Meta.Discovery.Root
 	}
+	opts.root = root
+	opts.decode = cfg.Decode
+	opts.acceptExitCodes = cfg.AcceptExitCodes
+	opts.retry = cfg.Retry
+	if cfg.InheritEnv != nil {
+		opts.inheritEnv = *cfg.InheritEnv
+	}
+	opts.envAllowlist = cfg.EnvAllowlist
+	opts.limits = cfg.Limits
+	opts.workingDirMarker, _ = parseWorkingDirStrategy(cfg.WorkingDirStrategy)
+	opts.artifacts = cfg.Artifacts
+	if cfg.MaxConcurrent > 0 {
+		opts.sem = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	if cfg.DecodeOptions != nil {
+		opts.decodeHeader = cfg.DecodeOptions.Header
+		opts.decodeDelimiter = cfg.DecodeOptions.Delimiter
+	}
+	if cfg.Stdin != nil {
+		opts.stdinMode = cfg.Stdin.Mode
+		opts.stdinSource = cfg.Stdin.Source
+		opts.stdinTemplate = cfg.Stdin.Template
+	}
 	wd := cfg.WorkingDir
 	if wd == "" {
 		wd = defaultShellWorkingDir
@@ -75,8 +120,13 @@ func buildShellOptions(in Envelope) shellOptions {
 }
 
 func validateShellOptions(opts shellOptions) error {
-	if len(opts.argsT) == 0 {
+	if len(opts.argsT) == 0 && len(opts.steps) == 0 {
 		return errors.New("missing argsTemplate")
 	}
+	for _, st := range opts.steps {
+		if len(st.argsT) == 0 {
+			return fmt.Errorf("step %s: missing argsTemplate", st.name)
+		}
+	}
 	return nil
 }
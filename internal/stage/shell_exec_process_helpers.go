@@ -1,7 +1,8 @@
 // File Guide for dev/ai agents:
 // Purpose: Convert one record plus shell settings into a final ShellResult and matching stage error outcome.
 // Responsibilities:
-// - Execute the rendered shell command for one record.
+// - Execute the rendered shell command for one record, unless map asked to skip it with `skipShell = true`.
+// - Apply per-record working directory and timeout overrides before running.
 // - Attach decoded JSON, timeouts, and diagnostic context onto the record.
 // - Translate shell failures into keep-going or fail-fast stage behavior.
 // Architecture notes:
@@ -19,25 +20,64 @@ func processShellRecord(ctx context.Context, rec Record, opts shellOptions, mode
 	if rec.Error != nil {
 		return rec, nil, nil
 	}
-	runRes, err := runCommand(ctx, opts, rec)
+	if shellSkipRequested(rec) {
+		rec.Shell = &ShellResult{Skipped: true}
+		return rec, nil, nil
+	}
+	opts, err := recordShellOptions(opts, rec)
 	if err != nil {
 		msg := sanitizeErrorMessage(err.Error())
-		if mode == "keep-going" {
-			rec.Shell = &ShellResult{
-				ExitCode:        -1,
-				Stdout:          nil,
-				Stderr:          nil,
-				StdoutTruncated: false,
-				StderrTruncated: false,
-				TimedOut:        false,
-				Error:           strPtr(msg),
-			}
-			rec.Error = &RecError{Stage: shellExecStage, Message: msg}
-			return rec, &Error{Stage: shellExecStage, Locator: rec.Locator, Message: msg}, nil
-		}
-		return Record{}, nil, fmt.Errorf("shell-exec: %s", msg)
+		rec.Shell = &ShellResult{ExitCode: -1, Error: strPtr(msg)}
+		return shellRecordFailure(rec, msg, mode)
+	}
+	if len(opts.artifacts) > 0 {
+		return processShellArtifacts(ctx, rec, opts, mode)
+	}
+	return runShellRecord(ctx, rec, opts, mode)
+}
+
+// runShellRecord runs the steps chain or the single command for a record.
+func runShellRecord(ctx context.Context, rec Record, opts shellOptions, mode string) (Record, *Error, error) {
+	if len(opts.steps) > 0 {
+		return processShellSteps(ctx, rec, opts, mode)
+	}
+	shell, failure := runShellCommand(ctx, opts, rec)
+	rec.Shell = shell
+	if failure != "" {
+		return shellRecordFailure(rec, failure, mode)
+	}
+	return rec, nil, nil
+}
+
+// shellSkipRequested reports whether map returned the reserved
+// `skipShell = true` key for this record.
+func shellSkipRequested(rec Record) bool {
+	m, ok := rec.Mapped.(map[string]any)
+	if !ok {
+		return false
+	}
+	skip, _ := m["skipShell"].(bool)
+	return skip
+}
+
+// runShellCommand executes one command and shapes its ShellResult. The
+// returned failure is the record-level error message, empty when the
+// command ran (a nonzero exit code alone is not a failure).
+func runShellCommand(ctx context.Context, opts shellOptions, rec Record) (*ShellResult, string) {
+	runRes, attempts, err := runCommandWithRetry(ctx, opts, rec)
+	if err != nil {
+		msg := sanitizeErrorMessage(err.Error())
+		return &ShellResult{
+			ExitCode:        -1,
+			Stdout:          nil,
+			Stderr:          nil,
+			StdoutTruncated: false,
+			StderrTruncated: false,
+			TimedOut:        false,
+			Error:           strPtr(msg),
+		}, msg
 	}
-	rec.Shell = &ShellResult{
+	shell := &ShellResult{
 		ExitCode:        runRes.exitCode,
 		Stdout:          runRes.stdout,
 		Stderr:          runRes.stderr,
@@ -45,45 +85,52 @@ func processShellRecord(ctx context.Context, rec Record, opts shellOptions, mode
 		StderrTruncated: runRes.stderrTruncated,
 		TimedOut:        runRes.timedOut,
 	}
+	if opts.retry != nil {
+		shell.Attempts = attempts
+	}
 	if runRes.errorMsg != "" {
 		msg := sanitizeErrorMessage(runRes.errorMsg)
-		rec.Shell.Error = strPtr(msg)
-		attachShellDiagnostics(rec.Shell, runRes)
+		shell.Error = strPtr(msg)
+		attachShellDiagnostics(shell, runRes)
 		runRes.errorMsg = msg
 	}
-	if !runRes.timedOut && runRes.errorMsg == "" && opts.decodeJSONStdout {
-		decoded, err := decodeShellStdoutJSON(runRes.stdout)
-		if err != nil {
-			msg := sanitizeErrorMessage("invalid JSON stdout: " + err.Error())
-			attachShellDiagnostics(rec.Shell, runRes)
-			rec.Error = &RecError{Stage: shellExecStage, Message: msg}
-			if mode == "keep-going" {
-				return rec, &Error{
-					Stage:   shellExecStage,
-					Locator: rec.Locator,
-					Message: msg,
-				}, nil
-			}
-			return Record{}, nil, fmt.Errorf("shell-exec: %s", msg)
+	if runRes.limitExceeded != "" {
+		shell.LimitExceeded = runRes.limitExceeded
+		attachShellDiagnostics(shell, runRes)
+		return shell, "limit exceeded: " + runRes.limitExceeded
+	}
+	if !runRes.timedOut && runRes.errorMsg == "" &&
+		len(opts.acceptExitCodes) > 0 &&
+		!exitCodeInRanges(runRes.exitCode, opts.acceptExitCodes) {
+		attachShellDiagnostics(shell, runRes)
+		return shell, fmt.Sprintf("exit code %d not accepted", runRes.exitCode)
+	}
+	format := shellDecodeFormat(opts)
+	if !runRes.timedOut && runRes.errorMsg == "" && format != "" {
+		decoded, decErr := decodeShellStdout(format, opts, runRes.stdout)
+		if decErr != nil {
+			decErr.Message = sanitizeErrorMessage(decErr.Message)
+			shell.DecodeError = decErr
+			attachShellDiagnostics(shell, runRes)
+			return shell, decErr.String()
 		}
-		rec.Shell.JSON = decoded
+		shell.JSON = decoded
 	}
 	if runRes.timedOut {
-		attachShellDiagnostics(rec.Shell, runRes)
-		if mode == "keep-going" {
-			rec.Error = &RecError{Stage: shellExecStage, Message: "timeout"}
-			return rec, &Error{Stage: shellExecStage, Locator: rec.Locator, Message: "timeout"}, nil
-		}
-		return Record{}, nil, fmt.Errorf("shell-exec: timeout")
+		attachShellDiagnostics(shell, runRes)
+		return shell, "timeout"
 	}
-	if runRes.errorMsg != "" {
-		if mode == "keep-going" {
-			rec.Error = &RecError{Stage: shellExecStage, Message: runRes.errorMsg}
-			return rec, &Error{Stage: shellExecStage, Locator: rec.Locator, Message: runRes.errorMsg}, nil
-		}
-		return Record{}, nil, fmt.Errorf("shell-exec: %s", runRes.errorMsg)
+	return shell, runRes.errorMsg
+}
+
+// shellRecordFailure applies the keep-going or fail-fast contract to a
+// failed shell record.
+func shellRecordFailure(rec Record, msg string, mode string) (Record, *Error, error) {
+	if mode == "keep-going" {
+		rec.Error = &RecError{Stage: shellExecStage, Message: msg}
+		return rec, &Error{Stage: shellExecStage, Locator: rec.Locator, Message: msg}, nil
 	}
-	return rec, nil, nil
+	return Record{}, nil, fmt.Errorf("shell-exec: %s", msg)
 }
 
 func attachShellDiagnostics(shell *ShellResult, runRes shellRunResult) {
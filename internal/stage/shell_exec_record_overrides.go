@@ -0,0 +1,123 @@
+// File Guide for dev/ai agents:
+// Purpose: Derive per-record shell options from map output and the working-directory strategy.
+// Responsibilities:
+// - Read the reserved map result key `shell = { workingDir, timeoutMs }` as per-record overrides.
+// - Resolve `shell.workingDirStrategy: "nearest:<marker>"` to the closest ancestor directory holding a marker file.
+// - Keep every resolved working directory inside the discovery root.
+// Architecture notes:
+// - Precedence is map override, then strategy, then the stage-level shell.workingDir; a strategy that finds no marker falls back to shell.workingDir.
+// - Overrides produce a copy of shellOptions so parallel records never share mutated state.
+// - Invalid overrides are record errors under errors.mode, because they come from per-record data rather than config.
+package stage
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// parseWorkingDirStrategy returns the marker file names of a
+// "nearest:<marker>[,<marker>...]" strategy; "" means no strategy.
+func parseWorkingDirStrategy(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	bad := fmt.Errorf(
+		"invalid shell.workingDirStrategy: must be \"nearest:<marker>[,<marker>...]\"",
+	)
+	spec, ok := strings.CutPrefix(s, "nearest:")
+	if !ok {
+		return nil, bad
+	}
+	markers := strings.Split(spec, ",")
+	for i, m := range markers {
+		m = strings.TrimSpace(m)
+		if m == "" || m == "." || m == ".." || strings.ContainsAny(m, `/\`) {
+			return nil, bad
+		}
+		markers[i] = m
+	}
+	return markers, nil
+}
+
+// recordShellOptions applies map overrides and the working-directory
+// strategy for one record.
+func recordShellOptions(opts shellOptions, rec Record) (shellOptions, error) {
+	if len(opts.workingDirMarker) > 0 {
+		if dir, ok := nearestMarkerDir(opts.root, rec.Locator, opts.workingDirMarker); ok {
+			opts.workingDir = dir
+		}
+	}
+	m, ok := rec.Mapped.(map[string]any)
+	if !ok {
+		return opts, nil
+	}
+	raw, ok := m["shell"]
+	if !ok || raw == nil {
+		return opts, nil
+	}
+	ov, ok := raw.(map[string]any)
+	if !ok {
+		return opts, fmt.Errorf("map shell override: must be a table")
+	}
+	if v, ok := ov["workingDir"]; ok {
+		wd, ok := v.(string)
+		if !ok {
+			return opts, fmt.Errorf("map shell override: workingDir must be a string")
+		}
+		rel, ok := confinedRelPath(wd)
+		if !ok {
+			return opts, fmt.Errorf(
+				"map shell override: workingDir %s must stay inside discovery root",
+				wd,
+			)
+		}
+		opts.workingDir = filepath.Join(opts.root, filepath.FromSlash(rel))
+	}
+	if v, ok := ov["timeoutMs"]; ok {
+		n, ok := toFloat64(v)
+		if !ok || n <= 0 || n != math.Trunc(n) {
+			return opts, fmt.Errorf("map shell override: timeoutMs must be an integer > 0")
+		}
+		opts.timeout = int(n)
+	}
+	return opts, nil
+}
+
+// confinedRelPath cleans a root-relative path and rejects absolute paths
+// and paths that climb out of the root.
+func confinedRelPath(p string) (string, bool) {
+	p = filepath.ToSlash(p)
+	if p == "" || path.IsAbs(p) || filepath.IsAbs(p) {
+		return "", false
+	}
+	clean := path.Clean(p)
+	if clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", false
+	}
+	return clean, true
+}
+
+// nearestMarkerDir walks from the locator's directory up to the discovery
+// root and returns the first directory containing one of the markers.
+func nearestMarkerDir(root, locator string, markers []string) (string, bool) {
+	dir, ok := confinedRelPath(path.Dir(locator))
+	if !ok {
+		return "", false
+	}
+	for {
+		abs := filepath.Join(root, filepath.FromSlash(dir))
+		for _, m := range markers {
+			if info, err := os.Stat(filepath.Join(abs, m)); err == nil && !info.IsDir() {
+				return abs, true
+			}
+		}
+		if dir == "." {
+			return "", false
+		}
+		dir = path.Dir(dir)
+	}
+}
@@ -0,0 +1,127 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestParseWorkingDirStrategy(t *testing.T) {
+	got, err := parseWorkingDirStrategy("nearest:go.mod, package.json")
+	if err != nil || len(got) != 2 || got[0] != "go.mod" || got[1] != "package.json" {
+		t.Fatalf("unexpected markers: %v %v", got, err)
+	}
+	for _, bad := range []string{"closest:go.mod", "nearest:", "nearest:a,,b", "nearest:../go.mod", "nearest:.."} {
+		if _, err := parseWorkingDirStrategy(bad); err == nil {
+			t.Fatalf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestNearestMarkerDir(t *testing.T) {
+	root := t.TempDir()
+	mod := filepath.Join(root, "svc", "api")
+	if err := os.MkdirAll(filepath.Join(mod, "internal", "x"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(mod, "go.mod"), []byte("module api\n"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	// A directory named like the marker must not match.
+	if err := os.Mkdir(filepath.Join(mod, "internal", "go.mod"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	dir, ok := nearestMarkerDir(root, "svc/api/internal/x/a.go", []string{"go.mod"})
+	if !ok || dir != mod {
+		t.Fatalf("unexpected dir: %q %v", dir, ok)
+	}
+	if _, ok := nearestMarkerDir(root, "other/b.go", []string{"go.mod"}); ok {
+		t.Fatalf("expected no marker outside module")
+	}
+	// Markers above the discovery root are never considered.
+	if _, ok := nearestMarkerDir(filepath.Join(mod, "internal"), "x/a.go", []string{"go.mod"}); ok {
+		t.Fatalf("expected search to stop at discovery root")
+	}
+}
+
+func TestProcessShellRecord_MapOverrides(t *testing.T) {
+	requirePOSIXShell(t)
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "pkg", "sub"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "pkg", "go.mod"), nil, 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	opts := baseShellOpts()
+	opts.root = root
+	opts.workingDir = root
+	opts.argsT = []string{"-c", "basename \"$(pwd)\""}
+	opts.workingDirMarker = []string{"go.mod"}
+
+	rec, envErr, err := processShellRecord(context.Background(), Record{Locator: "pkg/sub/a.go"}, opts, "keep-going")
+	if err != nil || envErr != nil || rec.Shell.Stdout == nil || strings.TrimSpace(*rec.Shell.Stdout) != "pkg" {
+		t.Fatalf("strategy: %v %+v %+v", err, envErr, rec.Shell)
+	}
+	rec, envErr, err = processShellRecord(context.Background(), Record{
+		Locator: "pkg/sub/a.go",
+		Mapped:  map[string]any{"shell": map[string]any{"workingDir": "pkg/sub"}},
+	}, opts, "keep-going")
+	if err != nil || envErr != nil || strings.TrimSpace(*rec.Shell.Stdout) != "sub" {
+		t.Fatalf("override: %v %+v %+v", err, envErr, rec.Shell)
+	}
+
+	opts.argsT = []string{"-c", "sleep 1"}
+	rec, _, err = processShellRecord(context.Background(), Record{
+		Locator: "a.go",
+		Mapped:  map[string]any{"shell": map[string]any{"timeoutMs": float64(20)}},
+	}, opts, "keep-going")
+	if err != nil || !rec.Shell.TimedOut {
+		t.Fatalf("expected per-record timeout: %v %+v", err, rec.Shell)
+	}
+
+	bad := []map[string]any{
+		{"workingDir": "../outside"},
+		{"workingDir": "/etc"},
+		{"timeoutMs": float64(-1)},
+		{"timeoutMs": "fast"},
+	}
+	for _, ov := range bad {
+		rec, envErr, err = processShellRecord(context.Background(), Record{
+			Locator: "a.go",
+			Mapped:  map[string]any{"shell": ov},
+		}, opts, "keep-going")
+		if err != nil || envErr == nil || rec.Error == nil ||
+			!strings.HasPrefix(envErr.Message, "map shell override:") {
+			t.Fatalf("%v: expected record error: %v %+v", ov, err, envErr)
+		}
+	}
+	_, _, err = processShellRecord(context.Background(), Record{
+		Locator: "a.go",
+		Mapped:  map[string]any{"shell": "nope"},
+	}, opts, "fail-fast")
+	if err == nil || err.Error() != "shell-exec: map shell override: must be a table" {
+		t.Fatalf("expected fail-fast error, got %v", err)
+	}
+}
+
+func TestValidateConfig_ShellWorkingDirStrategy(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: { enabled: true, argsTemplate: [\"x\"], workingDirStrategy: \"nearest:go.mod\" }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_workdir_strategy_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Shell.WorkingDirStrategy != "nearest:go.mod" {
+		t.Fatalf("unexpected strategy meta: %q", out.Meta.Shell.WorkingDirStrategy)
+	}
+	content = strings.Replace(content, "nearest:go.mod", "nearest:a/go.mod", 1)
+	_, err = runValidateConfigWithContent(t, "shell_workdir_strategy_bad_test.cue", content)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid shell.workingDirStrategy:") {
+		t.Fatalf("expected strategy error, got %v", err)
+	}
+}
@@ -1,11 +1,14 @@
 // File Guide for dev/ai agents:
 // Purpose: Render shell argument templates against the current record context in a deterministic way.
 // Responsibilities:
-// - Expand supported placeholders such as locator, file parts, `{json}`, and mapped fields.
-// - Enforce strict templating behavior when configured.
+// - Expand supported placeholders: locator, file parts, `{json}`, `{tmpdir}`, and nested paths under mapped, meta, fileInfo, git, env, and earlier shell.steps results.
+// - Apply `|default` literals and the explicit `shq`, `json`, and `join:<sep>` filters.
+// - Enforce strict templating behavior when configured; without it, unresolved meta, fileInfo, git, env, steps, and tmpdir placeholders stay literal.
+// - Resolve `{env.*}` from the child environment (inherited or allowlisted names plus shell.env), never from thoth's own environment.
 // - Convert mapped values into string-safe shell argument fragments.
 // Architecture notes:
-// - Placeholder support is intentionally explicit and limited; this is not a generic expression engine.
+// - Placeholder support is intentionally explicit and limited; this is not a generic expression engine. Paths support keys and `[n]` indexes only.
+// - Quoting is opt-in via `shq` because argv rendering never goes through a shell; it is for args that are themselves shell scripts (`sh -c`).
 // - Rendering stays argv-oriented on purpose to keep shell command construction safer and easier to reason about.
 package stage
 
@@ -13,15 +16,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"path"
+	"strconv"
 	"strings"
 )
 
 // renderArgs applies supported placeholders using the current record context.
-func renderArgs(argsT []string, rec Record, strict bool) ([]string, error) {
+func renderArgs(argsT []string, rec Record, opts shellOptions) ([]string, error) {
 	rendered := make([]string, len(argsT))
 	for i := range argsT {
 		a := argsT[i]
-		out, err := renderArg(a, rec, strict)
+		out, err := renderArg(a, rec, opts)
 		if err != nil {
 			return nil, err
 		}
@@ -30,7 +34,8 @@ func renderArgs(argsT []string, rec Record, strict bool) ([]string, error) {
 	return rendered, nil
 }
 
-func renderArg(s string, rec Record, strict bool) (string, error) {
+func renderArg(s string, rec Record, opts shellOptions) (string, error) {
+	strict := opts.strictTemplating
 	out := []byte{}
 	i := 0
 	for i < len(s) {
@@ -46,11 +51,11 @@ func renderArg(s string, rec Record, strict bool) (string, error) {
 			continue
 		}
 		placeholder := s[i : i+end+1]
-		value, handled, err := resolvePlaceholder(placeholder, rec)
-		if err != nil {
+		value, handled, err := resolvePlaceholder(placeholder, rec, opts)
+		if err != nil && (strict || !isPassThroughPlaceholder(placeholder)) {
 			return "", err
 		}
-		if !handled {
+		if !handled || err != nil {
 			if strict {
 				return "", fmt.Errorf(
 					"strict templating: invalid placeholder %s",
@@ -67,60 +72,252 @@ func renderArg(s string, rec Record, strict bool) (string, error) {
 	return string(out), nil
 }
 
+// resolvePlaceholder renders `{source.path|default|filter...}`. The first
+// pipe segment that is not a known filter is a default literal used when
+// the value is missing.
 func resolvePlaceholder(
 	placeholder string,
 	rec Record,
+	opts shellOptions,
 ) (string, bool, error) {
-	switch placeholder {
-	case "{json}":
-		mappedJSON, _ := json.Marshal(rec.Mapped)
-		return string(mappedJSON), true, nil
-	case "{locator}":
-		return rec.Locator, true, nil
-	case "{file.base}":
-		return path.Base(rec.Locator), true, nil
-	case "{file.dir}":
-		return path.Dir(rec.Locator), true, nil
-	case "{file.stem}":
-		base := path.Base(rec.Locator)
-		ext := path.Ext(base)
-		return strings.TrimSuffix(base, ext), true, nil
-	case "{file.ext}":
-		return path.Ext(rec.Locator), true, nil
-	}
-	if strings.HasPrefix(placeholder, "{mapped.") &&
-		strings.HasSuffix(placeholder, "}") {
-		keyPath := strings.TrimSuffix(
-			strings.TrimPrefix(placeholder, "{mapped."),
-			"}",
-		)
-		v, err := lookupMappedValue(rec.Mapped, keyPath)
+	expr := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{"), "}")
+	ref, mods, _ := strings.Cut(expr, "|")
+	v, handled, err := lookupPlaceholderValue(ref, rec, opts)
+	if !handled {
+		return "", false, nil
+	}
+	var filters []string
+	if mods != "" {
+		for i, mod := range strings.Split(mods, "|") {
+			if isTemplateFilter(mod) {
+				filters = append(filters, mod)
+				continue
+			}
+			if i > 0 {
+				return "", true, fmt.Errorf(
+					"template placeholder %s: unknown filter %s",
+					placeholder,
+					mod,
+				)
+			}
+			if err != nil || v == nil {
+				v, err = mod, nil
+			}
+		}
+	}
+	if err != nil {
+		return "", true, err
+	}
+	for _, f := range filters {
+		v, err = applyTemplateFilter(f, v, placeholder)
 		if err != nil {
 			return "", true, err
 		}
-		return placeholderValueString(v)
 	}
-	return "", false, nil
+	return placeholderValueString(v)
 }
 
-func lookupMappedValue(mapped any, keyPath string) (any, error) {
-	cur := mapped
-	for _, part := range strings.Split(keyPath, ".") {
-		m, ok := cur.(map[string]any)
-		if !ok {
-			return nil, fmt.Errorf(
-				"template placeholder {mapped.%s} requires object value",
+// lookupPlaceholderValue resolves the part of a placeholder before any
+// `|`; handled is false for names that are not placeholder sources.
+func lookupPlaceholderValue(
+	ref string,
+	rec Record,
+	opts shellOptions,
+) (any, bool, error) {
+	switch ref {
+	case "json":
+		mappedJSON, _ := json.Marshal(rec.Mapped)
+		return string(mappedJSON), true, nil
+	case "locator":
+		return rec.Locator, true, nil
+	case "tmpdir":
+		dir := shellTmpDir(rec)
+		if dir == "" {
+			return nil, true, fmt.Errorf("template placeholder {tmpdir} requires shell.artifacts")
+		}
+		return dir, true, nil
+	case "file.base":
+		return locatorParts(rec.Locator).base, true, nil
+	case "file.dir":
+		return locatorParts(rec.Locator).dir, true, nil
+	case "file.stem":
+		return locatorParts(rec.Locator).stem, true, nil
+	case "file.ext":
+		return locatorParts(rec.Locator).ext, true, nil
+	}
+	source, keyPath, ok := strings.Cut(ref, ".")
+	if !ok || keyPath == "" {
+		return nil, false, nil
+	}
+	switch source {
+	case "mapped":
+		v, err := lookupMappedValue(rec.Mapped, keyPath)
+		return v, true, err
+	case "meta":
+		v, err := lookupTemplatePath(rec.Meta, source, keyPath)
+		return v, true, err
+	case "fileInfo", "git":
+		v, err := lookupTemplatePath(luaRecordContext(rec)[source], source, keyPath)
+		return v, true, err
+	case "env":
+		v, found := shellChildEnvLookup(opts, keyPath)
+		if !found {
+			return nil, true, fmt.Errorf(
+				"template placeholder {env.%s} missing value",
 				keyPath,
 			)
 		}
-		next, ok := m[part]
+		return v, true, nil
+	case "steps":
+		v, err := resolveStepPlaceholder(keyPath, rec)
+		return v, true, err
+	}
+	return nil, false, nil
+}
+
+// isPassThroughPlaceholder reports whether a placeholder that fails to
+// resolve stays literal without strictTemplating. Only the original
+// sources (locator, file, json, mapped) fail outright.
+func isPassThroughPlaceholder(placeholder string) bool {
+	expr := strings.TrimSuffix(strings.TrimPrefix(placeholder, "{"), "}")
+	ref, _, _ := strings.Cut(expr, "|")
+	source, _, _ := strings.Cut(ref, ".")
+	switch source {
+	case "meta", "fileInfo", "git", "env", "steps", "tmpdir":
+		return true
+	}
+	return false
+}
+
+// shellChildEnvLookup reads name from the environment the child process
+// receives, so `{env.*}` cannot expose variables excluded by
+// inheritEnv/envAllowlist.
+func shellChildEnvLookup(opts shellOptions, name string) (string, bool) {
+	if v, ok := opts.env[name]; ok {
+		return v, true
+	}
+	prefix := name + "="
+	for _, kv := range shellBaseEnv(opts) {
+		if strings.HasPrefix(kv, prefix) {
+			return kv[len(prefix):], true
+		}
+	}
+	return "", false
+}
+
+func isTemplateFilter(mod string) bool {
+	return mod == "shq" || mod == "json" || strings.HasPrefix(mod, "join:")
+}
+
+// applyTemplateFilter applies shq (POSIX single-quoting), json (compact
+// JSON) or join:<sep> (list elements joined by sep).
+func applyTemplateFilter(f string, v any, placeholder string) (any, error) {
+	switch {
+	case f == "shq":
+		s, _, err := placeholderValueString(v)
+		if err != nil {
+			return nil, err
+		}
+		return shellQuote(s), nil
+	case f == "json":
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	default:
+		list, ok := v.([]any)
 		if !ok {
 			return nil, fmt.Errorf(
-				"template placeholder {mapped.%s} missing value",
-				keyPath,
+				"template placeholder %s: join requires list value",
+				placeholder,
 			)
 		}
-		cur = next
+		parts := make([]string, len(list))
+		for i, item := range list {
+			s, _, err := placeholderValueString(item)
+			if err != nil {
+				return nil, err
+			}
+			parts[i] = s
+		}
+		return strings.Join(parts, strings.TrimPrefix(f, "join:")), nil
+	}
+}
+
+// shellQuote wraps s in single quotes so a POSIX shell reads it literally.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// fileParts are the {file.*} placeholder values for a locator; thoth.path
+// uses the same split so Lua and shell templates agree.
+type fileParts struct {
+	dir  string
+	base string
+	stem string
+	ext  string
+}
+
+func locatorParts(locator string) fileParts {
+	base := path.Base(locator)
+	ext := path.Ext(base)
+	return fileParts{
+		dir:  path.Dir(locator),
+		base: base,
+		stem: strings.TrimSuffix(base, ext),
+		ext:  ext,
+	}
+}
+
+func lookupMappedValue(mapped any, keyPath string) (any, error) {
+	return lookupTemplatePath(mapped, "mapped", keyPath)
+}
+
+// lookupTemplatePath walks a dotted key path with optional list indexes
+// (`a.b[0].c`) below a placeholder source such as `mapped` or `steps`.
+func lookupTemplatePath(root any, source string, keyPath string) (any, error) {
+	fail := func(what string) (any, error) {
+		return nil, fmt.Errorf(
+			"template placeholder {%s.%s} %s",
+			source,
+			keyPath,
+			what,
+		)
+	}
+	cur := root
+	for _, part := range strings.Split(keyPath, ".") {
+		key, rest, _ := strings.Cut(part, "[")
+		if key != "" {
+			m, ok := asStringMap(cur)
+			if !ok {
+				return fail("requires object value")
+			}
+			next, ok := m[key]
+			if !ok {
+				return fail("missing value")
+			}
+			cur = next
+		} else if rest == "" {
+			return fail("invalid path")
+		}
+		for rest != "" {
+			idxText, after, ok := strings.Cut(rest, "]")
+			idx, err := strconv.Atoi(idxText)
+			if !ok || err != nil || idx < 0 ||
+				(after != "" && !strings.HasPrefix(after, "[")) {
+				return fail("invalid path")
+			}
+			list, ok := cur.([]any)
+			if !ok {
+				return fail("requires list value")
+			}
+			if idx >= len(list) {
+				return fail("missing value")
+			}
+			cur = list[idx]
+			rest = strings.TrimPrefix(after, "[")
+		}
 	}
 	return cur, nil
 }
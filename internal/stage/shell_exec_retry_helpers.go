@@ -0,0 +1,79 @@
+// File Guide for dev/ai agents:
+// Purpose: Apply shell.retry and shell.acceptExitCodes to command runs for one record.
+// Responsibilities:
+// - Re-run a command whose exit code or timeout matches shell.retry, with doubling backoff.
+// - Report how many attempts ran so ShellResult can record it.
+// - Match exit codes against inclusive ranges shared by acceptExitCodes and retry.onExitCodes.
+// Architecture notes:
+// - Only the final attempt is returned; keep-going, embedErrors, and exit code acceptance see that outcome alone.
+// - Template or stdin setup errors are not retried because a re-run would fail the same way.
+// - Backoff waits honor context cancellation so an aborted run does not sleep through remaining attempts.
+// - The shell.maxConcurrent slot is held per attempt, not across backoff waits, so sleeping retries do not starve other records.
+// - Waiting for a slot also honors cancellation, so a cancelled run never hangs behind busy commands.
+package stage
+
+import (
+	"context"
+	"time"
+)
+
+// runCommandWithRetry runs the command until it no longer matches the
+// retry policy or attempts run out, returning the last result.
+func runCommandWithRetry(ctx context.Context, opts shellOptions, rec Record) (shellRunResult, int, error) {
+	attempts := 1
+	if opts.retry != nil && opts.retry.Attempts > 1 {
+		attempts = opts.retry.Attempts
+	}
+	delay := 0
+	if opts.retry != nil {
+		delay = opts.retry.BackoffMs
+	}
+	for n := 1; ; n++ {
+		if opts.sem != nil {
+			select {
+			case opts.sem <- struct{}{}:
+			case <-ctx.Done():
+				return shellRunResult{}, n - 1, ctx.Err()
+			}
+		}
+		res, err := runCommand(ctx, opts, rec)
+		if opts.sem != nil {
+			<-opts.sem
+		}
+		if err != nil || n >= attempts || !shouldRetryShell(opts.retry, res) {
+			return res, n, err
+		}
+		if delay > 0 {
+			timer := time.NewTimer(time.Duration(delay) * time.Millisecond)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return res, n, nil
+			case <-timer.C:
+			}
+			delay *= 2
+		}
+	}
+}
+
+func shouldRetryShell(retry *ShellRetryMeta, res shellRunResult) bool {
+	if retry == nil {
+		return false
+	}
+	if res.timedOut {
+		return retry.OnTimeout
+	}
+	if res.errorMsg != "" {
+		return false
+	}
+	return exitCodeInRanges(res.exitCode, retry.OnExitCodes)
+}
+
+func exitCodeInRanges(code int, ranges []ShellExitCodeRange) bool {
+	for _, r := range ranges {
+		if code >= r.Min && code <= r.Max {
+			return true
+		}
+	}
+	return false
+}
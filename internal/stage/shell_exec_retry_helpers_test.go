@@ -0,0 +1,114 @@
+package stage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+// flakyShellArgs exits 75 until it has been called okAfter times in dir.
+func flakyShellArgs(okAfter string) []string {
+	return []string{"-c", "n=$(cat count 2>/dev/null || echo 0); n=$((n+1)); echo $n > count; [ $n -ge " + okAfter + " ] || exit 75; printf ok"}
+}
+
+func TestProcessShellRecord_RetryUntilSuccess(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.workingDir = t.TempDir()
+	opts.argsT = flakyShellArgs("3")
+	opts.retry = &ShellRetryMeta{Attempts: 4, BackoffMs: 1, OnExitCodes: []ShellExitCodeRange{{Min: 75, Max: 75}}}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "fail-fast")
+	if fatal != nil || envErr != nil {
+		t.Fatalf("unexpected failure: %v %+v", fatal, envErr)
+	}
+	if rec.Shell.Attempts != 3 || rec.Shell.ExitCode != 0 || *rec.Shell.Stdout != "ok" {
+		t.Fatalf("unexpected result: %+v", rec.Shell)
+	}
+}
+
+func TestProcessShellRecord_RetryExhaustedRespectsFinalOutcome(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.workingDir = t.TempDir()
+	opts.argsT = flakyShellArgs("9")
+	opts.retry = &ShellRetryMeta{Attempts: 2, OnExitCodes: []ShellExitCodeRange{{Min: 70, Max: 79}}}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if fatal != nil || envErr != nil || rec.Shell.Attempts != 2 || rec.Shell.ExitCode != 75 {
+		t.Fatalf("exit 75 without acceptExitCodes should not fail: %v %+v %+v", fatal, envErr, rec.Shell)
+	}
+	opts.workingDir = t.TempDir()
+	opts.acceptExitCodes = []ShellExitCodeRange{{Min: 0, Max: 0}}
+	rec, envErr, fatal = processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if fatal != nil || envErr == nil || envErr.Message != "exit code 75 not accepted" || rec.Error == nil {
+		t.Fatalf("unexpected outcome: %v %+v", fatal, envErr)
+	}
+}
+
+func TestProcessShellRecord_AcceptExitCodes(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.decodeJSONStdout = true
+	opts.acceptExitCodes = []ShellExitCodeRange{{Min: 0, Max: 0}, {Min: 1, Max: 2}}
+	opts.argsT = []string{"-c", "printf '[1]'; exit 1"}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "fail-fast")
+	if fatal != nil || envErr != nil || rec.Shell.ExitCode != 1 || rec.Shell.JSON == nil || rec.Shell.Attempts != 0 {
+		t.Fatalf("exit 1 should be accepted and decoded: %v %+v %+v", fatal, envErr, rec.Shell)
+	}
+	opts.argsT = []string{"-c", "printf 'oops'; exit 3"}
+	_, _, fatal = processShellRecord(context.Background(), Record{Locator: "x"}, opts, "fail-fast")
+	if fatal == nil || fatal.Error() != "shell-exec: exit code 3 not accepted" {
+		t.Fatalf("unexpected fail-fast error: %v", fatal)
+	}
+}
+
+func TestValidateConfig_ShellExitPolicy(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: { enabled: true, argsTemplate: [\"x\"], acceptExitCodes: [0, \"2-4\"],\n" +
+		"    retry: { attempts: 3, backoffMs: 100, onTimeout: true } }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_exit_policy_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	s := out.Meta.Shell
+	if len(s.AcceptExitCodes) != 2 || s.AcceptExitCodes[1] != (ShellExitCodeRange{Min: 2, Max: 4}) {
+		t.Fatalf("unexpected acceptExitCodes: %+v", s.AcceptExitCodes)
+	}
+	if s.Retry == nil || s.Retry.Attempts != 3 || s.Retry.BackoffMs != 100 || !s.Retry.OnTimeout {
+		t.Fatalf("unexpected retry: %+v", s.Retry)
+	}
+	bad := strings.Replace(content, `"2-4"`, `"4-2"`, 1)
+	_, err = runValidateConfigWithContent(t, "shell_exit_policy_bad_validate_test.cue", bad)
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid shell.acceptExitCodes:") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	noTrigger := strings.Replace(content, ", onTimeout: true", "", 1)
+	_, err = runValidateConfigWithContent(t, "shell_exit_policy_retry_validate_test.cue", noTrigger)
+	if err == nil || err.Error() != "invalid shell.retry: set onExitCodes or onTimeout" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestRunCommandWithRetry_CancelWhileWaitingForSlot(t *testing.T) {
+	opts := baseShellOpts()
+	opts.sem = make(chan struct{}, 1)
+	opts.sem <- struct{}{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := runCommandWithRetry(ctx, opts, Record{Locator: "x"})
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("expected context.Canceled, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("cancelled run kept waiting for a maxConcurrent slot")
+	}
+}
@@ -0,0 +1,153 @@
+// File Guide for dev/ai agents:
+// Purpose: Apply shell.limits rlimits to child processes before they exec the configured program.
+// Responsibilities:
+// - Encode limits into an environment marker and route the child through a re-exec shim of the current binary.
+// - In the shim, set CPU, address-space, open-file, and file-size rlimits, then exec the real program in place.
+// - Classify children killed by SIGXCPU/SIGXFSZ as limit violations distinct from timeouts.
+// Architecture notes:
+// - os/exec cannot set rlimits for a child, and setting them in the parent would leak into thoth itself; the shim runs in the child's own process before exec, so only the analyzer inherits them.
+// - The shim only runs when a binary's main calls MaybeRunRlimitShim first thing (cmd/thoth does; stage tests do from TestMain), so merely importing the package never hijacks a process.
+// - Binaries that never installed the shim get a record error for shell.limits instead of re-executing into an unprepared main.
+// - Address-space and open-file violations surface as allocation or EMFILE failures inside the child and are reported through its exit code, not as limitExceeded.
+package stage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const (
+	shellRlimitEnv     = "THOTH_SHELL_RLIMITS"
+	shellRlimitArgv0   = "thoth-rlimit-shim"
+	shellLimitExitCode = -3
+)
+
+// rlimitShimReady records that main called MaybeRunRlimitShim, so
+// re-executing the current binary as the shim is safe.
+var rlimitShimReady bool
+
+// MaybeRunRlimitShim turns the process into the shell.limits shim when it
+// was re-executed for that purpose, and returns otherwise. Binaries that run
+// shell-exec with shell.limits must call it at the start of main.
+func MaybeRunRlimitShim() {
+	rlimitShimReady = true
+	runRlimitShimIfRequested()
+}
+
+func runRlimitShimIfRequested() {
+	spec, ok := os.LookupEnv(shellRlimitEnv)
+	if !ok || len(os.Args) < 3 || os.Args[0] != shellRlimitArgv0 {
+		return
+	}
+	env := make([]string, 0, len(os.Environ()))
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, shellRlimitEnv+"=") {
+			env = append(env, kv)
+		}
+	}
+	if err := applyRlimitSpec(spec); err != nil {
+		fmt.Fprintf(os.Stderr, "thoth: shell limits: %v\n", err)
+		os.Exit(126)
+	}
+	err := syscall.Exec(os.Args[1], os.Args[2:], env)
+	fmt.Fprintf(os.Stderr, "thoth: exec %s: %v\n", os.Args[1], err)
+	os.Exit(127)
+}
+
+var shellRlimitResources = []struct {
+	key      string
+	resource int
+}{
+	{"cpu", syscall.RLIMIT_CPU},
+	{"as", syscall.RLIMIT_AS},
+	{"nofile", syscall.RLIMIT_NOFILE},
+	{"fsize", syscall.RLIMIT_FSIZE},
+}
+
+// rlimitSpec encodes limits as "cpu=10,as=1048576,..."; "" means none set.
+func rlimitSpec(l *ShellLimitsMeta) string {
+	if l == nil {
+		return ""
+	}
+	vals := map[string]int64{
+		"cpu":    l.CPUSeconds,
+		"as":     l.AddressSpaceBytes,
+		"nofile": l.OpenFiles,
+		"fsize":  l.FileSizeBytes,
+	}
+	parts := []string{}
+	for _, r := range shellRlimitResources {
+		if v := vals[r.key]; v > 0 {
+			parts = append(parts, r.key+"="+strconv.FormatInt(v, 10))
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func applyRlimitSpec(spec string) error {
+	for _, part := range strings.Split(spec, ",") {
+		key, val, _ := strings.Cut(part, "=")
+		n, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid limit %q", part)
+		}
+		for _, r := range shellRlimitResources {
+			if r.key != key {
+				continue
+			}
+			lim := syscall.Rlimit{Cur: n, Max: n}
+			if r.resource == syscall.RLIMIT_CPU {
+				// A hard limit one second above the soft one makes the
+				// kernel deliver SIGXCPU rather than SIGKILL first.
+				lim.Max = n + 1
+			}
+			if err := syscall.Setrlimit(r.resource, &lim); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// wrapWithRlimitShim reroutes cmd through the current binary so the shim
+// can apply limits before exec. A cmd whose program lookup failed is left
+// alone so Start reports the original not-found error.
+func wrapWithRlimitShim(cmd *exec.Cmd, limits *ShellLimitsMeta) error {
+	spec := rlimitSpec(limits)
+	if spec == "" || cmd.Err != nil {
+		return nil
+	}
+	if !rlimitShimReady {
+		return fmt.Errorf("shell limits: this binary does not install the rlimit shim")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("shell limits: %v", err)
+	}
+	cmd.Args = append([]string{shellRlimitArgv0, cmd.Path}, cmd.Args...)
+	cmd.Path = self
+	cmd.Env = append(cmd.Env, shellRlimitEnv+"="+spec)
+	return nil
+}
+
+// limitExceededBy names the rlimit that killed a child, or "".
+func limitExceededBy(ps *os.ProcessState) string {
+	if ps == nil {
+		return ""
+	}
+	ws, ok := ps.Sys().(syscall.WaitStatus)
+	if !ok || !ws.Signaled() {
+		return ""
+	}
+	switch ws.Signal() {
+	case syscall.SIGXCPU:
+		return "cpu"
+	case syscall.SIGXFSZ:
+		return "fileSize"
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+// TestMain installs the rlimit shim like cmd/thoth's main, because shell
+// limit tests re-execute this test binary as the shim.
+func TestMain(m *testing.M) {
+	MaybeRunRlimitShim()
+	os.Exit(m.Run())
+}
+
+func TestRunCommand_EnvIsolation(t *testing.T) {
+	requirePOSIXShell(t)
+	t.Setenv("THOTH_TEST_KEEP", "kept")
+	t.Setenv("THOTH_TEST_DROP", "dropped")
+	opts := baseShellOpts()
+	opts.argsT = []string{"-c", "printf '%s|%s|%s' \"$THOTH_TEST_KEEP\" \"$THOTH_TEST_DROP\" \"$EXTRA\""}
+	opts.env = map[string]string{"EXTRA": "x"}
+	opts.inheritEnv = false
+	opts.envAllowlist = []string{"THOTH_TEST_KEEP", "THOTH_TEST_MISSING"}
+	r, err := runCommand(context.Background(), opts, Record{})
+	if err != nil || r.stdout == nil || *r.stdout != "kept||x" {
+		t.Fatalf("unexpected isolated env: %v %+v", err, r)
+	}
+	opts.inheritEnv = true
+	r, _ = runCommand(context.Background(), opts, Record{})
+	if *r.stdout != "kept|dropped|x" {
+		t.Fatalf("unexpected inherited env: %q", *r.stdout)
+	}
+}
+
+func TestProcessShellRecord_RlimitViolations(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.workingDir = t.TempDir()
+	opts.timeout = 10000
+	opts.limits = &ShellLimitsMeta{FileSizeBytes: 1024, OpenFiles: 64}
+	opts.argsT = []string{"-c", "exec head -c 100000 /dev/zero > big.bin"}
+	rec, envErr, _ := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if envErr == nil || envErr.Message != "limit exceeded: fileSize" ||
+		rec.Shell.LimitExceeded != "fileSize" || rec.Shell.ExitCode != -3 || rec.Shell.TimedOut {
+		t.Fatalf("unexpected fileSize outcome: %+v %+v", envErr, rec.Shell)
+	}
+
+	opts.limits = &ShellLimitsMeta{CPUSeconds: 1}
+	opts.argsT = []string{"-c", "while :; do :; done"}
+	rec, envErr, _ = processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if envErr == nil || rec.Shell.LimitExceeded != "cpu" || rec.Shell.TimedOut {
+		t.Fatalf("unexpected cpu outcome: %+v %+v", envErr, rec.Shell)
+	}
+
+	opts.argsT = []string{"-c", "ulimit -n; printf '%s' \"$THOTH_SHELL_RLIMITS\""}
+	opts.limits = &ShellLimitsMeta{OpenFiles: 64}
+	rec, envErr, _ = processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if envErr != nil || rec.Shell.Stdout == nil || *rec.Shell.Stdout != "64\n" || rec.Shell.LimitExceeded != "" {
+		t.Fatalf("limits should reach the child without leaking the marker: %+v %+v", envErr, rec.Shell)
+	}
+}
+
+func TestProcessShellRecord_MaxConcurrent(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.workingDir = t.TempDir()
+	opts.sem = make(chan struct{}, 1)
+	opts.argsT = []string{"-c", "mkdir lock || exit 9; sleep 0.05; rmdir lock"}
+	var wg sync.WaitGroup
+	codes := make([]int, 4)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rec, _, _ := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+			codes[i] = rec.Shell.ExitCode
+		}(i)
+	}
+	wg.Wait()
+	for i, c := range codes {
+		if c != 0 {
+			t.Fatalf("command %d overlapped another (exit %d)", i, c)
+		}
+	}
+}
+
+func TestValidateConfig_ShellIsolation(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: { enabled: true, argsTemplate: [\"x\"], inheritEnv: false, envAllowlist: [\"PATH\"],\n" +
+		"    limits: { cpuSeconds: 30, openFiles: 256 }, maxConcurrent: 2 }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_isolation_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	s := out.Meta.Shell
+	if s.InheritEnv == nil || *s.InheritEnv || len(s.EnvAllowlist) != 1 || s.MaxConcurrent != 2 ||
+		s.Limits == nil || s.Limits.CPUSeconds != 30 || s.Limits.OpenFiles != 256 {
+		t.Fatalf("unexpected shell meta: %+v", s)
+	}
+	bad := strings.Replace(content, "inheritEnv: false, ", "", 1)
+	_, err = runValidateConfigWithContent(t, "shell_isolation_bad_validate_test.cue", bad)
+	if err == nil || err.Error() != "invalid shell.envAllowlist: requires shell.inheritEnv=false" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestWrapWithRlimitShim_RequiresInstalledShim(t *testing.T) {
+	rlimitShimReady = false
+	defer func() { rlimitShimReady = true }()
+	opts := baseShellOpts()
+	opts.limits = &ShellLimitsMeta{OpenFiles: 64}
+	rec, envErr, _ := processShellRecord(context.Background(), Record{Locator: "x"}, opts, "keep-going")
+	if envErr == nil || !strings.HasSuffix(envErr.Message, "shell limits: this binary does not install the rlimit shim") || rec.Shell.ExitCode != -1 {
+		t.Fatalf("unexpected outcome: %+v %+v", envErr, rec.Shell)
+	}
+}
@@ -1,11 +1,11 @@
 // File Guide for dev/ai agents:
 // Purpose: Spawn and supervise the actual OS process for one shell-exec record run.
 // Responsibilities:
-// - Start the command with rendered args, env overlay, output capture, and timeout control.
+// - Start the command with rendered args, env overlay, stdin, output capture, and timeout control.
 // - Terminate timed-out processes and optionally their process group.
 // - Return low-level shellRunResult details used by higher-level shell processing.
 // Architecture notes:
-// - Timeout handling is per record, not per stage, and the `-2` exit code convention for timeouts is intentional.
+// - Timeout handling is per record, not per stage, and the `-2` exit code convention for timeouts is intentional; `-3` marks rlimit kills.
 // - Process-group termination is deliberate to avoid leaving child processes behind for shell commands that spawn subcommands.
 // - Diagnostic context is captured here because the low-level spawn path knows the final program, args, and working directory.
 package stage
@@ -55,6 +55,7 @@ type shellRunResult struct {
 	stdoutTruncated bool
 	stderrTruncated bool
 	timedOut        bool
+	limitExceeded   string
 	errorMsg        string
 	program         string
 	workingDir      string
@@ -65,7 +66,7 @@ func strPtr(s string) *string { return &s }
 
 // runCommand executes the command with timeout/termination and returns result or error.
 func runCommand(ctx context.Context, opts shellOptions, rec Record) (shellRunResult, error) {
-	args, err := renderArgs(opts.argsT, rec, opts.strictTemplating)
+	args, err := renderArgs(opts.argsT, rec, opts)
 	if err != nil {
 		return shellRunResult{}, err
 	}
@@ -96,9 +97,20 @@ func runCommand(ctx context.Context, opts shellOptions, rec Record) (shellRunRes
 			return baseRes, nil
 		}
 	}
+	stdin, closeStdin, err := shellStdin(opts, rec)
+	if err != nil {
+		return shellRunResult{}, err
+	}
+	defer closeStdin()
 	cmd := exec.Command(opts.program, args...)
 	cmd.Dir = opts.workingDir
-	cmd.Env = applyEnvOverlay(os.Environ(), opts.env)
+	cmd.Stdin = stdin
+	cmd.Env = applyEnvOverlay(shellBaseEnv(opts), opts.env)
+	if err := wrapWithRlimitShim(cmd, opts.limits); err != nil {
+		baseRes.exitCode = -1
+		baseRes.errorMsg = fmt.Sprintf("program %s start failed: %v", opts.program, err)
+		return baseRes, nil
+	}
 	if opts.killProcessGroup {
 		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 	}
@@ -184,6 +196,11 @@ func runCommand(ctx context.Context, opts shellOptions, rec Record) (shellRunRes
 		var exitErr *exec.ExitError
 		if errors.As(runErr, &exitErr) {
 			res.exitCode = exitErr.ExitCode()
+			if opts.limits != nil {
+				if res.limitExceeded = limitExceededBy(exitErr.ProcessState); res.limitExceeded != "" {
+					res.exitCode = shellLimitExitCode
+				}
+			}
 			return res, nil
 		}
 		res.exitCode = -1
@@ -210,6 +227,22 @@ func signalProcess(cmd *exec.Cmd, killGroup bool, sig syscall.Signal) {
 	_ = cmd.Process.Signal(sig)
 }
 
+// shellBaseEnv is the child environment before shell.env is overlaid:
+// the full parent environment, or only allowlisted names when
+// shell.inheritEnv=false.
+func shellBaseEnv(opts shellOptions) []string {
+	if opts.inheritEnv {
+		return os.Environ()
+	}
+	out := []string{}
+	for _, name := range opts.envAllowlist {
+		if v, ok := os.LookupEnv(name); ok {
+			out = append(out, name+"="+v)
+		}
+	}
+	return out
+}
+
 func applyEnvOverlay(base []string, overlay map[string]string) []string {
 	if len(overlay) == 0 {
 		return append([]string(nil), base...)
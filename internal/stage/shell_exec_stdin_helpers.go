@@ -0,0 +1,56 @@
+// File Guide for dev/ai agents:
+// Purpose: Build the stdin stream handed to a shell-exec child process for one record.
+// Responsibilities:
+// - Encode the mapped value or the record context as JSON for `stdin.mode: "json"`.
+// - Open the record's source file for `stdin.mode: "file"`, confined to the discovery root like thoth.read_file.
+// - Render `stdin.template` with the same placeholders and strictness as argsTemplate.
+// Architecture notes:
+// - In-memory payloads are returned as readers, so os/exec copies them from its own goroutine while stdout/stderr are drained; a child that stops reading cannot deadlock the stage.
+// - File mode passes the *os.File through so the child reads the file descriptor directly without an intermediate copy.
+// - Mode "none" returns a nil reader, which keeps the historical /dev/null stdin.
+package stage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// shellStdin returns the stdin reader for one record and a close func that
+// must run after the process exits.
+func shellStdin(opts shellOptions, rec Record) (io.Reader, func(), error) {
+	noop := func() {}
+	switch opts.stdinMode {
+	case "json":
+		var payload any = rec.Mapped
+		if opts.stdinSource == "record" {
+			ctx := luaRecordContext(rec)
+			ctx["mapped"] = rec.Mapped
+			payload = ctx
+		}
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin json: %v", err)
+		}
+		return bytes.NewReader(b), noop, nil
+	case "file":
+		p, err := resolveRootedFile(opts.root, rec.Locator)
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin file: %v", err)
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, noop, fmt.Errorf("stdin file %s: %v", rec.Locator, err)
+		}
+		return f, func() { _ = f.Close() }, nil
+	case "template":
+		s, err := renderArg(opts.stdinTemplate, rec, opts)
+		if err != nil {
+			return nil, noop, err
+		}
+		return bytes.NewReader([]byte(s)), noop, nil
+	}
+	return nil, noop, nil
+}
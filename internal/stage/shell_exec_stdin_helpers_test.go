@@ -0,0 +1,112 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestRunCommand_StdinModes(t *testing.T) {
+	requirePOSIXShell(t)
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("file body"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	rec := Record{Locator: "a.txt", Mapped: map[string]any{"kind": "go"}}
+	cases := []struct {
+		mode, source, template, want string
+	}{
+		{mode: "json", source: "mapped", want: `{"kind":"go"}`},
+		{mode: "json", source: "record", want: `{"locator":"a.txt","mapped":{"kind":"go"},"meta":null}`},
+		{mode: "file", want: "file body"},
+		{mode: "template", template: "{file.stem}:{mapped.kind}", want: "a:go"},
+		{mode: "none", want: ""},
+	}
+	for _, tc := range cases {
+		opts := baseShellOpts()
+		opts.argsT = []string{"-c", "cat"}
+		opts.root = root
+		opts.stdinMode = tc.mode
+		opts.stdinSource = tc.source
+		opts.stdinTemplate = tc.template
+		r, err := runCommand(context.Background(), opts, rec)
+		if err != nil || r.exitCode != 0 {
+			t.Fatalf("%s: runCommand: %v %+v", tc.mode, err, r)
+		}
+		if r.stdout == nil || *r.stdout != tc.want {
+			t.Fatalf("%s: stdout=%q want %q", tc.mode, *r.stdout, tc.want)
+		}
+	}
+}
+
+func TestRunCommand_StdinIgnoredByChildDoesNotBlock(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.argsT = []string{"-c", "printf done"}
+	opts.stdinMode = "json"
+	opts.stdinSource = "mapped"
+	big := strings.Repeat("x", 4*1024*1024)
+	r, err := runCommand(context.Background(), opts, Record{Mapped: map[string]any{"big": big}})
+	if err != nil || r.timedOut || r.exitCode != 0 || r.errorMsg != "" {
+		t.Fatalf("unexpected result: %v %+v", err, r)
+	}
+}
+
+func TestProcessShellRecord_StdinFileMissing(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.root = t.TempDir()
+	opts.stdinMode = "file"
+	rec, envErr, _ := processShellRecord(context.Background(), Record{Locator: "missing.txt"}, opts, "keep-going")
+	if envErr == nil || !strings.Contains(envErr.Message, "stdin file") || rec.Shell.ExitCode != -1 {
+		t.Fatalf("unexpected outcome: %+v %+v", envErr, rec.Shell)
+	}
+}
+
+func TestValidateConfig_ShellStdin(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: { enabled: true, argsTemplate: [\"-c\", \"cat\"], stdin: { mode: \"json\" } }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_stdin_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Shell.Stdin == nil || out.Meta.Shell.Stdin.Mode != "json" || out.Meta.Shell.Stdin.Source != "mapped" {
+		t.Fatalf("unexpected stdin meta: %+v", out.Meta.Shell.Stdin)
+	}
+	bad := strings.Replace(content, `stdin: { mode: "json" }`, `stdin: { mode: "template" }`, 1)
+	_, err = runValidateConfigWithContent(t, "shell_stdin_bad_validate_test.cue", bad)
+	if err == nil || err.Error() != "invalid shell.stdin.template: required when shell.stdin.mode='template'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestShellStdin_FileConfinedToRoot(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	root := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(root, "link.txt")); err != nil {
+		t.Skipf("symlinks unavailable: %v", err)
+	}
+	opts := baseShellOpts()
+	opts.root = root
+	opts.stdinMode = "file"
+	want := map[string]string{
+		"link.txt":      "stdin file: symlink escapes discovery root: link.txt",
+		"../secret.txt": "stdin file: parent references ('..') are not allowed: ../secret.txt",
+	}
+	for loc, msg := range want {
+		if _, _, err := shellStdin(opts, Record{Locator: loc}); err == nil || err.Error() != msg {
+			t.Fatalf("%s: unexpected error: %v", loc, err)
+		}
+		var c shellPlanCommand
+		if err := planShellStdin(&c, opts, Record{Locator: loc}); err == nil || err.Error() != msg || c.StdinFile != "" {
+			t.Fatalf("%s: unexpected plan outcome: %v %q", loc, err, c.StdinFile)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// File Guide for dev/ai agents:
+// Purpose: Run a shell.steps chain of named commands in order for one record.
+// Responsibilities:
+// - Execute each step with the shared shell options plus its own program, args, and JSON decoding.
+// - Skip steps whose runIf condition on an earlier step's exit code does not hold.
+// - Expose earlier step results to later templates (`{steps.<name>.json.count}`) and to postMap.
+// Architecture notes:
+// - Step results accumulate on rec.Shell.Steps while the chain runs, so the renderer reads earlier results from the record like any other placeholder source.
+// - A nonzero exit does not stop the chain; only start failures, timeouts, and decode errors do, and they follow the single-command keep-going/fail-fast contract with the step name prefixed.
+// - Top-level ShellResult fields summarize the last step that ran so exit-code based consumers keep working.
+package stage
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func processShellSteps(ctx context.Context, rec Record, opts shellOptions, mode string) (Record, *Error, error) {
+	rec.Shell = &ShellResult{
+		Steps:  make(map[string]*ShellResult, len(opts.steps)),
+		tmpDir: shellTmpDir(rec),
+	}
+	for _, step := range opts.steps {
+		if !shellStepShouldRun(step, rec.Shell.Steps) {
+			rec.Shell.Steps[step.name] = &ShellResult{Skipped: true}
+			continue
+		}
+		stepOpts := opts
+		stepOpts.program = step.program
+		stepOpts.argsT = step.argsT
+		stepOpts.decodeJSONStdout = step.decodeJSONStdout
+		stepOpts.decode = step.decode
+		res, failure := runShellCommand(ctx, stepOpts, rec)
+		rec.Shell.Steps[step.name] = res
+		rec.Shell.ExitCode = res.ExitCode
+		rec.Shell.TimedOut = res.TimedOut
+		rec.Shell.Error = res.Error
+		if failure != "" {
+			return shellRecordFailure(rec, fmt.Sprintf("step %s: %s", step.name, failure), mode)
+		}
+	}
+	return rec, nil, nil
+}
+
+func shellStepShouldRun(step shellStep, done map[string]*ShellResult) bool {
+	if step.runIfStep == "" {
+		return true
+	}
+	prev, ok := done[step.runIfStep]
+	if !ok || prev == nil || prev.Skipped {
+		return false
+	}
+	return slices.Contains(step.runIfExitCodes, prev.ExitCode)
+}
+
+// shellStepsView returns step results as plain maps, the shape seen by
+// `{steps.*}` placeholders and postMap's shell.steps.
+func shellStepsView(steps map[string]*ShellResult) map[string]any {
+	if len(steps) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(steps))
+	for name, res := range steps {
+		if res == nil {
+			continue
+		}
+		m := map[string]any{
+			"exitCode": res.ExitCode,
+			"timedOut": res.TimedOut,
+			"skipped":  res.Skipped,
+		}
+		if res.Stdout != nil {
+			m["stdout"] = *res.Stdout
+		}
+		if res.Stderr != nil {
+			m["stderr"] = *res.Stderr
+		}
+		if res.JSON != nil {
+			m["json"] = res.JSON
+		}
+		if res.Error != nil {
+			m["error"] = *res.Error
+		}
+		if res.LimitExceeded != "" {
+			m["limitExceeded"] = res.LimitExceeded
+		}
+		if res.Attempts > 0 {
+			m["attempts"] = res.Attempts
+		}
+		if res.DecodeError != nil {
+			m["decodeError"] = res.DecodeError.luaView()
+		}
+		out[name] = m
+	}
+	return out
+}
+
+// resolveStepPlaceholder looks up `{steps.<name>.<field>[.<path>]}` in
+// the steps that already ran for this record.
+func resolveStepPlaceholder(keyPath string, rec Record) (any, error) {
+	name, _, _ := strings.Cut(keyPath, ".")
+	var steps map[string]*ShellResult
+	if rec.Shell != nil {
+		if rec.Shell.planning {
+			return "{steps." + keyPath + "}", nil
+		}
+		steps = rec.Shell.Steps
+	}
+	res, ok := steps[name]
+	if !ok {
+		return nil, fmt.Errorf(
+			"template placeholder {steps.%s} references step %s that has not run",
+			keyPath,
+			name,
+		)
+	}
+	if res.Skipped {
+		return nil, fmt.Errorf(
+			"template placeholder {steps.%s} references skipped step %s",
+			keyPath,
+			name,
+		)
+	}
+	return lookupTemplatePath(shellStepsView(steps), "steps", keyPath)
+}
@@ -0,0 +1,67 @@
+package stage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestProcessShellRecord_StepsChain(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.argsT = nil
+	opts.steps = []shellStep{
+		{name: "lint", program: "sh", argsT: []string{"-c", "printf '\\173\"count\":3\\175'; exit 1"}, decodeJSONStdout: true},
+		{name: "fix", program: "sh", argsT: []string{"-c", "printf never"}, runIfStep: "lint", runIfExitCodes: []int{0}},
+		{name: "report", program: "sh", argsT: []string{"-c", "printf '%s/%s' '{steps.lint.json.count}' '{steps.lint.exitCode}'"}, runIfStep: "lint", runIfExitCodes: []int{1, 2}},
+	}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+	if fatal != nil || envErr != nil {
+		t.Fatalf("unexpected failure: %v %+v", fatal, envErr)
+	}
+	steps := rec.Shell.Steps
+	if steps["lint"].ExitCode != 1 || steps["lint"].JSON.(map[string]any)["count"] != float64(3) {
+		t.Fatalf("unexpected lint step: %+v", steps["lint"])
+	}
+	if !steps["fix"].Skipped || steps["fix"].Stdout != nil {
+		t.Fatalf("expected fix to be skipped: %+v", steps["fix"])
+	}
+	if steps["report"].Stdout == nil || *steps["report"].Stdout != "3/1" {
+		t.Fatalf("unexpected report step: %+v", steps["report"])
+	}
+	if rec.Shell.ExitCode != 0 || rec.Shell.Stdout != nil {
+		t.Fatalf("top-level result should summarize the last step: %+v", rec.Shell)
+	}
+	view := shellStepsView(steps)
+	if view["fix"].(map[string]any)["skipped"] != true || view["report"].(map[string]any)["stdout"] != "3/1" {
+		t.Fatalf("unexpected steps view: %+v", view)
+	}
+}
+
+func TestProcessShellRecord_StepFailureStopsChain(t *testing.T) {
+	requirePOSIXShell(t)
+	opts := baseShellOpts()
+	opts.argsT = nil
+	opts.steps = []shellStep{
+		{name: "lint", program: "sh", argsT: []string{"-c", "printf nope"}, decodeJSONStdout: true},
+		{name: "after", program: "sh", argsT: []string{"-c", "true"}},
+	}
+	rec, envErr, fatal := processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "keep-going")
+	if fatal != nil || envErr == nil || !strings.HasPrefix(envErr.Message, "step lint: invalid JSON stdout:") {
+		t.Fatalf("unexpected outcome: %v %+v", fatal, envErr)
+	}
+	if _, ran := rec.Shell.Steps["after"]; ran || rec.Error == nil {
+		t.Fatalf("chain should stop after failed step: %+v", rec.Shell.Steps)
+	}
+	_, _, fatal = processShellRecord(context.Background(), Record{Locator: "a.go"}, opts, "fail-fast")
+	if fatal == nil || !strings.HasPrefix(fatal.Error(), "shell-exec: step lint:") {
+		t.Fatalf("unexpected fail-fast error: %v", fatal)
+	}
+}
+
+func TestRenderArgs_StepPlaceholderBeforeStepRuns(t *testing.T) {
+	_, err := renderArgs([]string{"{steps.lint.exitCode}"}, Record{}, baseShellOpts())
+	if err == nil || err.Error() != "template placeholder {steps.lint.exitCode} references step lint that has not run" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
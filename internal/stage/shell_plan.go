@@ -0,0 +1,188 @@
+// File Guide for dev/ai agents:
+// Purpose: Run the shell-plan stage that shows what shell-exec would run for each record without spawning any process.
+// Responsibilities:
+// - Resolve per-record options, skipShell, and steps exactly as shell-exec does, then render argv and stdin with renderArgs/renderArg.
+// - Collect one plan command per record (or per step) with program, args, workingDir, env overlay, timeout, and stdin.
+// - Write the plan as JSON or as a POSIX sh script to the configured output destination.
+// Architecture notes:
+// - The CLI runs this stage in place of shell-exec when shell.dryRun (or `thoth run --plan`) is set, and stops afterwards, so postMap, reduce, and persistence never see planned records.
+// - Rendering failures, including strict templating errors, follow the shell-exec keep-going/fail-fast contract and are listed in the plan.
+// - `{tmpdir}` and `{steps.*}` stay literal because they only exist while commands run; env values are shown as configured because they are not templated at runtime.
+package stage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+)
+
+const shellPlanStage = "shell-plan"
+
+// shellPlan is the document written by the shell-plan stage.
+type shellPlan struct {
+	Commands     []shellPlanCommand `json:"commands"`
+	InheritEnv   bool               `json:"inheritEnv"`
+	EnvAllowlist []string           `json:"envAllowlist,omitempty"`
+	Errors       []Error            `json:"errors,omitempty"`
+}
+
+// shellPlanCommand is one command shell-exec would start, or a record it
+// would skip or fail to render.
+type shellPlanCommand struct {
+	Locator    string              `json:"locator"`
+	Step       string              `json:"step,omitempty"`
+	RunIf      *ShellStepRunIfMeta `json:"runIf,omitempty"`
+	Skipped    bool                `json:"skipped,omitempty"`
+	Program    string              `json:"program,omitempty"`
+	Args       []string            `json:"args,omitempty"`
+	WorkingDir string              `json:"workingDir,omitempty"`
+	Env        map[string]string   `json:"env,omitempty"`
+	TimeoutMs  int                 `json:"timeoutMs,omitempty"`
+	Stdin      *string             `json:"stdin,omitempty"`
+	StdinFile  string              `json:"stdinFile,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+func shellPlanRunner(_ context.Context, in Envelope, _ Deps) (Envelope, error) {
+	opts := buildShellOptions(in)
+	if err := validateShellOptions(opts); err != nil {
+		return Envelope{}, fmt.Errorf("shell-plan: %v", err)
+	}
+	mode, _ := errorMode(in.Meta)
+	out := in
+	out.Records = make([]Record, 0, len(in.Records))
+	plan := shellPlan{
+		Commands:     []shellPlanCommand{},
+		InheritEnv:   opts.inheritEnv,
+		EnvAllowlist: opts.envAllowlist,
+	}
+	for _, rec := range in.Records {
+		cmds, failure := planShellRecord(rec, opts)
+		plan.Commands = append(plan.Commands, cmds...)
+		if failure != "" {
+			r, envErr, err := shellRecordFailure(rec, failure, mode)
+			if err != nil {
+				return Envelope{}, fmt.Errorf("shell-plan: %s", failure)
+			}
+			rec = r
+			out.Errors = append(out.Errors, *envErr)
+		}
+		out.Records = append(out.Records, rec)
+	}
+	SortEnvelopeErrors(&out)
+	plan.Errors = out.Errors
+	outPath, pretty, _ := getOutputSettings(in.Meta)
+	data, err := encodeShellPlan(plan, shellPlanFormat(in.Meta), pretty)
+	if err != nil {
+		return Envelope{}, fmt.Errorf("shell-plan: %v", err)
+	}
+	if err := writeTo(outPath, data); err != nil {
+		return Envelope{}, fmt.Errorf("shell-plan: %v", err)
+	}
+	return out, nil
+}
+
+// planShellRecord renders the commands shell-exec would run for one record.
+// The failure message is non-empty when rendering failed; the failing
+// command is still part of the plan with its error.
+func planShellRecord(rec Record, opts shellOptions) ([]shellPlanCommand, string) {
+	if rec.Error != nil {
+		return nil, ""
+	}
+	if shellSkipRequested(rec) {
+		return []shellPlanCommand{{Locator: rec.Locator, Skipped: true}}, ""
+	}
+	opts, err := recordShellOptions(opts, rec)
+	if err != nil {
+		msg := sanitizeErrorMessage(err.Error())
+		return []shellPlanCommand{{Locator: rec.Locator, Error: msg}}, msg
+	}
+	rec.Shell = &ShellResult{planning: true}
+	if len(opts.artifacts) > 0 {
+		rec.Shell.tmpDir = "{tmpdir}"
+	}
+	steps := opts.steps
+	if len(steps) == 0 {
+		steps = []shellStep{{program: opts.program, argsT: opts.argsT}}
+	}
+	cmds := make([]shellPlanCommand, 0, len(steps))
+	for _, step := range steps {
+		c := shellPlanCommand{
+			Locator:    rec.Locator,
+			Step:       step.name,
+			Program:    step.program,
+			WorkingDir: opts.workingDir,
+			TimeoutMs:  opts.timeout,
+		}
+		if step.runIfStep != "" {
+			c.RunIf = &ShellStepRunIfMeta{Step: step.runIfStep, ExitCodes: step.runIfExitCodes}
+		}
+		if len(opts.env) > 0 {
+			c.Env = maps.Clone(opts.env)
+		}
+		args, err := renderArgs(step.argsT, rec, opts)
+		if err == nil {
+			c.Args = args
+			err = planShellStdin(&c, opts, rec)
+		}
+		if err != nil {
+			msg := sanitizeErrorMessage(err.Error())
+			c.Error = msg
+			if step.name != "" {
+				msg = fmt.Sprintf("step %s: %s", step.name, msg)
+			}
+			return append(cmds, c), msg
+		}
+		cmds = append(cmds, c)
+	}
+	return cmds, ""
+}
+
+// planShellStdin records the stdin payload shell-exec would write; file
+// mode only names the file so the plan never reads record contents, but
+// applies the same root confinement as shell-exec.
+func planShellStdin(c *shellPlanCommand, opts shellOptions, rec Record) error {
+	if opts.stdinMode == "file" {
+		if _, err := resolveRootedFile(opts.root, rec.Locator); err != nil {
+			return fmt.Errorf("stdin file: %v", err)
+		}
+		c.StdinFile = filepath.Join(opts.root, filepath.FromSlash(rec.Locator))
+		return nil
+	}
+	r, closeStdin, err := shellStdin(opts, rec)
+	if err != nil || r == nil {
+		return err
+	}
+	defer closeStdin()
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s := string(b)
+	c.Stdin = &s
+	return nil
+}
+
+func shellPlanFormat(meta *Meta) string {
+	if meta != nil && meta.Shell != nil && meta.Shell.PlanFormat != "" {
+		return meta.Shell.PlanFormat
+	}
+	return "json"
+}
+
+func encodeShellPlan(plan shellPlan, format string, pretty bool) ([]byte, error) {
+	switch format {
+	case "sh":
+		return encodeShellPlanScript(plan), nil
+	case "json":
+		if pretty {
+			return encodeJSONPretty(plan)
+		}
+		return encodeJSONCompact(plan)
+	}
+	return nil, fmt.Errorf("unknown plan format %s", format)
+}
+
+func init() { Register(shellPlanStage, shellPlanRunner) }
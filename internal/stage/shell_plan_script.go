@@ -0,0 +1,118 @@
+// File Guide for dev/ai agents:
+// Purpose: Render a shell-plan as a POSIX sh script that a reviewer can read or run by hand.
+// Responsibilities:
+// - Emit one `(cd DIR && env ... PROGRAM ARGS)` line per planned command with every word single-quoted.
+// - Reproduce env isolation: `env -i` plus allowlisted names taken from the caller's environment when inheritEnv is false.
+// - Turn skipped records, runIf conditions, and render errors into comments instead of commands.
+// Architecture notes:
+// - Quoting reuses shellQuote (the `shq` template filter), so the script quotes exactly like templates do.
+// - Output is built from the already sorted plan, with env keys sorted, so the script is byte-for-byte deterministic.
+// - Timeouts, rlimits, retries, and artifacts are runtime behaviours of shell-exec and are not reproduced; the JSON plan carries timeouts.
+package stage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+func encodeShellPlanScript(plan shellPlan) []byte {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&b, "# thoth shell plan: %d entries, nothing was executed\n", len(plan.Commands))
+	for _, c := range plan.Commands {
+		b.WriteString("\n")
+		b.WriteString(shellPlanScriptHeader(c))
+		if c.Skipped || c.Error != "" {
+			continue
+		}
+		b.WriteString(shellPlanScriptLine(c, plan))
+	}
+	for _, e := range plan.Errors {
+		fmt.Fprintf(&b, "\n# error %s %s: %s\n", e.Stage, e.Locator, oneLine(e.Message))
+	}
+	return []byte(b.String())
+}
+
+func shellPlanScriptHeader(c shellPlanCommand) string {
+	h := "# " + oneLine(c.Locator)
+	if c.Step != "" {
+		h += " step " + oneLine(c.Step)
+	}
+	if c.RunIf != nil {
+		codes := make([]string, len(c.RunIf.ExitCodes))
+		for i, code := range c.RunIf.ExitCodes {
+			codes[i] = fmt.Sprint(code)
+		}
+		h += fmt.Sprintf(" (only if step %s exits %s)", c.RunIf.Step, strings.Join(codes, ","))
+	}
+	switch {
+	case c.Skipped:
+		h += ": skipped (skipShell)"
+	case c.Error != "":
+		h += ": not rendered: " + oneLine(c.Error)
+	}
+	return h + "\n"
+}
+
+func shellPlanScriptLine(c shellPlanCommand, plan shellPlan) string {
+	words := []string{}
+	if !plan.InheritEnv || len(c.Env) > 0 {
+		words = append(words, "env")
+		if !plan.InheritEnv {
+			words = append(words, "-i")
+			for _, name := range plan.EnvAllowlist {
+				if !isShellVarName(name) {
+					continue
+				}
+				// Expands to NAME=value only when NAME is set, like shell-exec.
+				words = append(words, fmt.Sprintf(`${%s+"%s=$%s"}`, name, name, name))
+			}
+		}
+		keys := make([]string, 0, len(c.Env))
+		for k := range c.Env {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			words = append(words, shellQuote(k+"="+c.Env[k]))
+		}
+	}
+	words = append(words, shellQuote(c.Program))
+	for _, a := range c.Args {
+		words = append(words, shellQuote(a))
+	}
+	line := strings.Join(words, " ")
+	if c.WorkingDir != "" {
+		line = "cd " + shellQuote(c.WorkingDir) + " && " + line
+	}
+	line = "(" + line + ")"
+	switch {
+	case c.Stdin != nil:
+		line = "printf '%s' " + shellQuote(*c.Stdin) + " | " + line
+	case c.StdinFile != "":
+		line += " < " + shellQuote(c.StdinFile)
+	default:
+		line += " < /dev/null"
+	}
+	return line + "\n"
+}
+
+// isShellVarName reports whether name can be expanded as $name in sh;
+// other allowlisted names are left out of the script.
+func isShellVarName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && r >= '0' && r <= '9':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// oneLine keeps locators and messages from breaking out of a comment.
+func oneLine(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
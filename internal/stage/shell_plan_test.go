@@ -0,0 +1,178 @@
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func shellPlanEnvelope(t *testing.T, shell *ShellMeta, mode string, records ...Record) (Envelope, string) {
+	t.Helper()
+	out := filepath.Join(t.TempDir(), "plan.out")
+	shell.Enabled = true
+	shell.DryRun = true
+	return Envelope{
+		Records: records,
+		Meta: &Meta{
+			Shell:  shell,
+			Output: &OutputMeta{Out: out},
+			Errors: &ErrorsMeta{Mode: mode},
+		},
+	}, out
+}
+
+func TestShellPlanRunner_JSONPlanDoesNotSpawn(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "ran")
+	in, outPath := shellPlanEnvelope(t, &ShellMeta{
+		Program:      "sh",
+		ArgsTemplate: []string{"-c", "touch " + marker, "{mapped.lang|go}", "{locator|shq}"},
+		Env:          map[string]string{"LINT": "1"},
+		TimeoutMs:    500,
+		Stdin:        &ShellStdinMeta{Mode: "json", Source: "mapped"},
+		PlanFormat:   "json",
+	}, "keep-going",
+		Record{Locator: "a b.go", Mapped: map[string]any{"lang": "golang"}},
+		Record{Locator: "gen.go", Mapped: map[string]any{"skipShell": true}},
+	)
+	out, err := shellPlanRunner(context.Background(), in, Deps{})
+	if err != nil || len(out.Errors) != 0 {
+		t.Fatalf("shell-plan: %v %+v", err, out.Errors)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatalf("plan must not spawn the command")
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read plan: %v", err)
+	}
+	var plan shellPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	if len(plan.Commands) != 2 || !plan.InheritEnv {
+		t.Fatalf("unexpected plan: %s", data)
+	}
+	c := plan.Commands[0]
+	wantArgs := []string{"-c", "touch " + marker, "golang", "'a b.go'"}
+	if c.Locator != "a b.go" || c.Program != "sh" || !reflect.DeepEqual(c.Args, wantArgs) ||
+		c.Env["LINT"] != "1" || c.TimeoutMs != 500 || c.Stdin == nil || *c.Stdin != `{"lang":"golang"}` {
+		t.Fatalf("unexpected command: %+v", c)
+	}
+	if !plan.Commands[1].Skipped || plan.Commands[1].Program != "" {
+		t.Fatalf("expected skipped entry: %+v", plan.Commands[1])
+	}
+}
+
+func TestShellPlanRunner_StepsAndTmpdirStayLiteral(t *testing.T) {
+	in, outPath := shellPlanEnvelope(t, &ShellMeta{
+		Program: "sh",
+		Steps: []ShellStepMeta{
+			{Name: "build", Program: "make", ArgsTemplate: []string{"build", "--out", "{tmpdir}/r.json"}},
+			{Name: "lint", Program: "lint", ArgsTemplate: []string{"{steps.build.exitCode}"},
+				RunIf: &ShellStepRunIfMeta{Step: "build", ExitCodes: []int{0}}},
+		},
+		Artifacts:  []ShellArtifactMeta{{Name: "r", Path: "r.json", Decode: "json"}},
+		PlanFormat: "json",
+	}, "keep-going", Record{Locator: "a.go"})
+	if _, err := shellPlanRunner(context.Background(), in, Deps{}); err != nil {
+		t.Fatalf("shell-plan: %v", err)
+	}
+	data, _ := os.ReadFile(outPath)
+	var plan shellPlan
+	if err := json.Unmarshal(data, &plan); err != nil || len(plan.Commands) != 2 {
+		t.Fatalf("unexpected plan: %s %v", data, err)
+	}
+	if plan.Commands[0].Step != "build" || plan.Commands[0].Args[2] != "{tmpdir}/r.json" {
+		t.Fatalf("unexpected build step: %+v", plan.Commands[0])
+	}
+	if plan.Commands[1].RunIf == nil || plan.Commands[1].Args[0] != "{steps.build.exitCode}" {
+		t.Fatalf("unexpected lint step: %+v", plan.Commands[1])
+	}
+}
+
+func TestShellPlanRunner_StrictTemplatingErrors(t *testing.T) {
+	shell := &ShellMeta{Program: "sh", ArgsTemplate: []string{"{mapped.missing}"}, StrictTemplating: true, PlanFormat: "json"}
+	in, outPath := shellPlanEnvelope(t, shell, "keep-going", Record{Locator: "a.go", Mapped: map[string]any{}})
+	out, err := shellPlanRunner(context.Background(), in, Deps{})
+	if err != nil || len(out.Errors) != 1 || out.Records[0].Error == nil {
+		t.Fatalf("expected keep-going record error: %v %+v", err, out.Errors)
+	}
+	data, _ := os.ReadFile(outPath)
+	var plan shellPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		t.Fatalf("decode plan: %v", err)
+	}
+	if len(plan.Errors) != 1 || plan.Commands[0].Error == "" || plan.Commands[0].Error != plan.Errors[0].Message {
+		t.Fatalf("expected error in plan: %s", data)
+	}
+
+	in, _ = shellPlanEnvelope(t, shell, "fail-fast", Record{Locator: "a.go", Mapped: map[string]any{}})
+	if _, err := shellPlanRunner(context.Background(), in, Deps{}); err == nil ||
+		!strings.HasPrefix(err.Error(), "shell-plan: template placeholder") {
+		t.Fatalf("expected fail-fast error, got %v", err)
+	}
+}
+
+func TestShellPlanRunner_ShellScript(t *testing.T) {
+	inherit := false
+	in, outPath := shellPlanEnvelope(t, &ShellMeta{
+		Program:      "lint",
+		ArgsTemplate: []string{"--file", "{locator}"},
+		WorkingDir:   "repo",
+		Env:          map[string]string{"B": "2", "A": "it's"},
+		InheritEnv:   &inherit,
+		EnvAllowlist: []string{"PATH", "BAD-NAME"},
+		PlanFormat:   "sh",
+	}, "keep-going",
+		Record{Locator: "x'y.go"},
+		Record{Locator: "gen.go", Mapped: map[string]any{"skipShell": true}},
+	)
+	if _, err := shellPlanRunner(context.Background(), in, Deps{}); err != nil {
+		t.Fatalf("shell-plan: %v", err)
+	}
+	data, _ := os.ReadFile(outPath)
+	want := "#!/bin/sh\n" +
+		"# thoth shell plan: 2 entries, nothing was executed\n" +
+		"\n# x'y.go\n" +
+		`(cd 'repo' && env -i ${PATH+"PATH=$PATH"} 'A=it'\''s' 'B=2' 'lint' '--file' 'x'\''y.go') < /dev/null` + "\n" +
+		"\n# gen.go: skipped (skipShell)\n"
+	if string(data) != want {
+		t.Fatalf("unexpected script:\n%s", data)
+	}
+}
+
+func TestValidateConfig_ShellDryRun(t *testing.T) {
+	wrap := func(extra string) string {
+		return "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+			"  shell: { argsTemplate: [\"x\"], " + extra + " }\n}\n"
+	}
+	out, err := runValidateConfigWithContent(t, "shell_dryrun_test.cue", wrap("enabled: true, dryRun: true"))
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if !out.Meta.Shell.DryRun || out.Meta.Shell.PlanFormat != "json" {
+		t.Fatalf("unexpected dry-run meta: %+v", out.Meta.Shell)
+	}
+	bad := map[string]string{
+		"shell_dryrun_format_test.cue":   "enabled: true, dryRun: true, planFormat: \"yaml\"",
+		"shell_dryrun_noplan_test.cue":   "enabled: true, planFormat: \"sh\"",
+		"shell_dryrun_disabled_test.cue": "enabled: false, dryRun: true",
+	}
+	want := map[string]string{
+		"shell_dryrun_format_test.cue":   "invalid shell.planFormat: must be one of json, sh",
+		"shell_dryrun_noplan_test.cue":   "invalid shell.planFormat: requires shell.dryRun=true",
+		"shell_dryrun_disabled_test.cue": "invalid shell.dryRun: requires shell.enabled=true",
+	}
+	for name, extra := range bad {
+		_, err := runValidateConfigWithContent(t, name, wrap(extra))
+		if err == nil || err.Error() != want[name] {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
@@ -2,7 +2,7 @@
 // Purpose: Copy validated discovery, validation, and limits config fields into the runtime envelope metadata model.
 // Responsibilities:
 // - Apply discovery root and include/exclude settings when present in the parsed config.
-// - Apply validation flags such as allowUnknownTopLevel.
+// - Apply validation flags such as allowUnknownTopLevel and reference checks.
 // - Apply memory and YAML size limits with the runtime defaults preserved elsewhere.
 // Architecture notes:
 // - Config application is split by concern so schema growth does not force one monolithic applyMinimalToMeta function.
@@ -51,6 +51,16 @@ func applyValidationMeta(out *Envelope, min config.Minimal) {
 		}
 		out.Meta.Validation.AllowUnknownTopLevel = min.Validation.AllowUnknownTopLevel
 	}
+	if min.Validation.HasReferences {
+		if out.Meta.Validation == nil {
+			out.Meta.Validation = &ValidationMeta{}
+		}
+		pointers := append([]string{}, min.Validation.References.Pointers...)
+		out.Meta.Validation.References = &ReferencesMeta{
+			Pointers: pointers,
+			Graph:    min.Validation.References.Graph,
+		}
+	}
 }
 
 func applyLimitsMeta(out *Envelope, min config.Minimal) {
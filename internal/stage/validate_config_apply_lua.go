@@ -36,6 +36,23 @@ func applyLuaMeta(out *Envelope, min config.Minimal) {
 		}
 		out.Meta.Lua.ReduceInline = min.Reduce.Inline
 	}
+	if min.Rollup.HasSection {
+		if out.Meta.Lua == nil {
+			out.Meta.Lua = &LuaMeta{}
+		}
+		out.Meta.Lua.RollupInline = min.Rollup.Inline
+	}
+	if min.Reduce.HasGroupBy {
+		out.Meta.Lua.ReduceGroupBy = min.Reduce.GroupBy
+	}
+	if min.Reduce.HasNamed {
+		if out.Meta.Lua == nil {
+			out.Meta.Lua = &LuaMeta{}
+		}
+		for _, r := range min.Reduce.Named {
+			out.Meta.Lua.Reducers = append(out.Meta.Lua.Reducers, ReducerMeta{Name: r.Name, Inline: r.Inline, GroupBy: r.GroupBy})
+		}
+	}
 }
 
 func applyLuaSandboxMeta(out *Envelope, min config.Minimal) {
@@ -68,6 +85,15 @@ func applyLuaSandboxMeta(out *Envelope, min config.Minimal) {
 	if min.LuaSandbox.HasDeterministicRandom {
 		out.Meta.LuaSandbox.DeterministicRandom = min.LuaSandbox.DeterministicRandom
 	}
+	if min.LuaSandbox.HasFileRead {
+		out.Meta.LuaSandbox.FileRead = min.LuaSandbox.FileRead
+	}
+	if min.LuaSandbox.HasFileReadMaxBytes {
+		out.Meta.LuaSandbox.FileReadMaxBytes = min.LuaSandbox.FileReadMaxBytes
+	}
+	if min.LuaSandbox.HasLog {
+		out.Meta.LuaSandbox.Log = min.LuaSandbox.Log
+	}
 	if min.LuaSandbox.Libs.HasBase {
 		out.Meta.LuaSandbox.Libs.Base = min.LuaSandbox.Libs.Base
 	}
@@ -1,8 +1,8 @@
 // File Guide for dev/ai agents:
 // Purpose: Copy the remaining non-shell runtime config sections into metadata after minimal config parsing succeeds.
 // Responsibilities:
-// - Apply persistence, update-meta, and diff-meta settings.
-// - Apply errors, fileInfo, git, workers, UI, and locator policy settings.
+// - Apply persistence, update-meta, diff-meta, and metadata rule settings.
+// - Apply errors, fileInfo, git, workers, UI, run clock, and locator policy settings.
 // - Rehydrate section defaults where actions require a full runtime struct.
 // Architecture notes:
 // - These helpers are grouped as “misc” because they are independent knobs whose only shared job is metadata projection, not shared runtime behavior.
@@ -84,8 +84,37 @@ func applyDiffMeta(out *Envelope, min config.Minimal) {
 	}
 }
 
+func applyRulesMeta(out *Envelope, min config.Minimal) {
+	if !min.HasRules {
+		return
+	}
+	out.Meta.Rules = make([]RuleMeta, 0, len(min.Rules))
+	for _, r := range min.Rules {
+		rm := RuleMeta{
+			Name:      r.Name,
+			Match:     r.Match,
+			Required:  append([]string(nil), r.Required...),
+			Forbidden: append([]string(nil), r.Forbidden...),
+			Severity:  r.Severity,
+		}
+		if len(r.Enum) > 0 {
+			rm.Enum = map[string][]any{}
+			for k, vals := range r.Enum {
+				rm.Enum[k] = append([]any(nil), vals...)
+			}
+		}
+		if len(r.Regex) > 0 {
+			rm.Regex = map[string]string{}
+			for k, p := range r.Regex {
+				rm.Regex[k] = p
+			}
+		}
+		out.Meta.Rules = append(out.Meta.Rules, rm)
+	}
+}
+
 func applyErrorsMeta(out *Envelope, min config.Minimal) {
-	if min.Errors.HasMode || min.Errors.HasEmbed {
+	if min.Errors.HasMode || min.Errors.HasEmbed || min.Errors.HasFailOn {
 		if out.Meta.Errors == nil {
 			out.Meta.Errors = &ErrorsMeta{}
 		}
@@ -95,6 +124,9 @@ func applyErrorsMeta(out *Envelope, min config.Minimal) {
 		if min.Errors.HasEmbed {
 			out.Meta.Errors.EmbedErrors = min.Errors.EmbedErrors
 		}
+		if min.Errors.HasFailOn {
+			out.Meta.Errors.FailOn = min.Errors.FailOn
+		}
 	}
 }
 
@@ -142,8 +174,15 @@ func applyUIMeta(out *Envelope, min config.Minimal) {
 	}
 }
 
+func applyRunMeta(out *Envelope, min config.Minimal) {
+	if min.Run.HasNow {
+		out.Meta.Run = &RunMeta{Now: min.Run.Now}
+	}
+}
+
 func applyLocatorPolicyMeta(out *Envelope, min config.Minimal) {
-	if (min.LocatorPolicy.HasAllowAbs || min.LocatorPolicy.HasAllowParent || min.LocatorPolicy.HasPosix || min.LocatorPolicy.HasAllowURLs) || out.Meta.LocatorPolicy != nil {
+	lp := min.LocatorPolicy
+	if (lp.HasAllowAbs || lp.HasAllowParent || lp.HasPosix || lp.HasAllowURLs || lp.HasDetectCaseCollisions || lp.HasUnicodeNormalization) || out.Meta.LocatorPolicy != nil {
 		if out.Meta.LocatorPolicy == nil {
 			out.Meta.LocatorPolicy = &LocatorPolicy{
 				AllowAbsolute:   false,
@@ -164,5 +203,11 @@ func applyLocatorPolicyMeta(out *Envelope, min config.Minimal) {
 		if min.LocatorPolicy.HasAllowURLs {
 			out.Meta.LocatorPolicy.AllowURLs = min.LocatorPolicy.AllowURLs
 		}
+		if lp.HasDetectCaseCollisions {
+			out.Meta.LocatorPolicy.DetectCaseCollisions = lp.DetectCaseCollisions
+		}
+		if lp.HasUnicodeNormalization && lp.UnicodeNormalization != "none" {
+			out.Meta.LocatorPolicy.UnicodeNormalization = lp.UnicodeNormalization
+		}
 	}
 }
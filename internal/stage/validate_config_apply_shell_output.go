@@ -1,8 +1,9 @@
 // File Guide for dev/ai agents:
 // Purpose: Copy shell-exec and output serialization config into the runtime metadata contract.
 // Responsibilities:
-// - Apply shell program, args, capture, templating, and timeout settings.
+// - Apply shell program, args, capture, stdin, decode, exit policy, isolation, templating, and timeout settings.
 // - Rehydrate shell defaults for omitted values when the shell section is present.
+// - Resolve shell.steps against shell-level program, decodeJsonStdout, and decode.
 // - Apply output path and formatting settings for final JSON emission.
 // Architecture notes:
 // - Shell and output settings share this file because both are edge-of-pipeline runtime I/O concerns and mostly involve defaulting.
@@ -89,10 +90,136 @@ func applyShellMeta(out *Envelope, min config.Minimal) {
 	if out.Meta.Shell.TermGraceMs < 0 {
 		out.Meta.Shell.TermGraceMs = defaultShellTermGraceMs
 	}
+	if min.Shell.HasStdin {
+		stdin := &ShellStdinMeta{Mode: "none"}
+		if min.Shell.HasStdinMode {
+			stdin.Mode = min.Shell.StdinMode
+		}
+		if stdin.Mode == "json" {
+			stdin.Source = "mapped"
+			if min.Shell.HasStdinSource {
+				stdin.Source = min.Shell.StdinSource
+			}
+		}
+		if stdin.Mode == "template" {
+			stdin.Template = min.Shell.StdinTemplate
+		}
+		out.Meta.Shell.Stdin = stdin
+	}
+	if min.Shell.HasDecode {
+		out.Meta.Shell.Decode = min.Shell.Decode
+	}
+	if min.Shell.HasDecodeOptions {
+		opts := &ShellDecodeOptionsMeta{Header: true}
+		if min.Shell.HasDecodeHeader {
+			opts.Header = min.Shell.DecodeHeader
+		}
+		if min.Shell.HasDecodeDelim {
+			opts.Delimiter = min.Shell.DecodeDelimiter
+		}
+		out.Meta.Shell.DecodeOptions = opts
+	}
+	if min.Shell.HasAcceptExit {
+		out.Meta.Shell.AcceptExitCodes = exitCodeRangesMeta(min.Shell.AcceptExitCodes)
+	}
+	if min.Shell.HasRetry {
+		out.Meta.Shell.Retry = &ShellRetryMeta{
+			Attempts:    min.Shell.Retry.Attempts,
+			BackoffMs:   min.Shell.Retry.BackoffMs,
+			OnExitCodes: exitCodeRangesMeta(min.Shell.Retry.OnExitCodes),
+			OnTimeout:   min.Shell.Retry.OnTimeout,
+		}
+	}
+	if min.Shell.HasInheritEnv && !min.Shell.InheritEnv {
+		inherit := false
+		out.Meta.Shell.InheritEnv = &inherit
+	}
+	if min.Shell.HasEnvAllowlist {
+		out.Meta.Shell.EnvAllowlist = append([]string(nil), min.Shell.EnvAllowlist...)
+	}
+	if min.Shell.HasLimits {
+		out.Meta.Shell.Limits = &ShellLimitsMeta{
+			CPUSeconds:        min.Shell.Limits.CPUSeconds,
+			AddressSpaceBytes: min.Shell.Limits.AddressSpaceBytes,
+			OpenFiles:         min.Shell.Limits.OpenFiles,
+			FileSizeBytes:     min.Shell.Limits.FileSizeBytes,
+		}
+	}
+	if min.Shell.HasWorkDirStrat {
+		out.Meta.Shell.WorkingDirStrategy = min.Shell.WorkDirStrategy
+	}
+	if min.Shell.HasMaxConcurrent {
+		out.Meta.Shell.MaxConcurrent = min.Shell.MaxConcurrent
+	}
+	if min.Shell.HasSteps {
+		out.Meta.Shell.Steps = shellStepsMeta(min.Shell.Steps, out.Meta.Shell)
+	}
+	if min.Shell.HasDryRun {
+		out.Meta.Shell.DryRun = min.Shell.DryRun
+	}
+	if out.Meta.Shell.DryRun {
+		out.Meta.Shell.PlanFormat = "json"
+		if min.Shell.HasPlanFormat {
+			out.Meta.Shell.PlanFormat = min.Shell.PlanFormat
+		}
+	}
+	for _, a := range min.Shell.Artifacts {
+		out.Meta.Shell.Artifacts = append(out.Meta.Shell.Artifacts, ShellArtifactMeta{
+			Name:     a.Name,
+			Path:     a.Path,
+			Decode:   a.Decode,
+			Optional: a.Optional,
+		})
+	}
+}
+
+func exitCodeRangesMeta(in []config.ExitCodeRange) []ShellExitCodeRange {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]ShellExitCodeRange, len(in))
+	for i, r := range in {
+		out[i] = ShellExitCodeRange{Min: r.Min, Max: r.Max}
+	}
+	return out
+}
+
+// shellStepsMeta resolves omitted step fields against the shell-level
+// program, decodeJsonStdout and decode so the stage sees complete steps.
+// A step that sets decodeJsonStdout does not inherit the shell decode.
+func shellStepsMeta(steps []config.ShellStep, shell *ShellMeta) []ShellStepMeta {
+	out := make([]ShellStepMeta, 0, len(steps))
+	for _, st := range steps {
+		sm := ShellStepMeta{
+			Name:             st.Name,
+			Program:          shell.Program,
+			ArgsTemplate:     append([]string(nil), st.ArgsTemplate...),
+			DecodeJSONStdout: shell.DecodeJSONStdout,
+		}
+		if st.HasProgram && st.Program != "" {
+			sm.Program = st.Program
+		}
+		if st.HasDecodeJSON {
+			sm.DecodeJSONStdout = st.DecodeJSONStdout
+		} else {
+			sm.Decode = shell.Decode
+		}
+		if st.HasDecode {
+			sm.Decode = st.Decode
+		}
+		if st.RunIf != nil {
+			sm.RunIf = &ShellStepRunIfMeta{
+				Step:      st.RunIf.Step,
+				ExitCodes: append([]int(nil), st.RunIf.ExitCodes...),
+			}
+		}
+		out = append(out, sm)
+	}
+	return out
 }
 
 func applyOutputMeta(out *Envelope, min config.Minimal) {
-	if min.Output.HasOut || min.Output.HasPretty || min.Output.HasLines {
+	if min.Output.HasOut || min.Output.HasPretty || min.Output.HasLines || min.Output.HasFormat {
 		if out.Meta.Output == nil {
 			out.Meta.Output = &OutputMeta{}
 		}
@@ -109,5 +236,8 @@ func applyOutputMeta(out *Envelope, min config.Minimal) {
 		if min.Output.HasLines {
 			out.Meta.Output.Lines = min.Output.Lines
 		}
+		if min.Output.HasFormat {
+			out.Meta.Output.Format = min.Output.Format
+		}
 	}
 }
@@ -2,7 +2,7 @@
 // Purpose: Enforce cross-section config rules that must hold before stage metadata is applied or execution begins.
 // Responsibilities:
 // - Validate shared numeric bounds such as workers and in-memory limits.
-// - Validate cross-field shell and persistMeta requirements.
+// - Validate cross-field shell (including stdin modes) and persistMeta requirements.
 // - Reject malformed include/exclude patterns before any stage is built.
 // Architecture notes:
 // - This validation intentionally focuses on shared semantic rules that the schema alone cannot express cleanly.
@@ -11,7 +11,10 @@ package stage
 
 import (
 	"fmt"
+	"slices"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/flarebyte/thoth-ostraca/internal/config"
 )
@@ -23,9 +26,15 @@ func validateCommonConfig(min config.Minimal) error {
 	if min.Limits.HasMaxRecordsInMemory && min.Limits.MaxRecordsInMemory < 1 {
 		return fmt.Errorf("invalid limits.maxRecordsInMemory: must be >= 1")
 	}
-	if min.Shell.HasEnabled && min.Shell.Enabled && len(min.Shell.ArgsTemplate) == 0 {
+	if min.Shell.HasEnabled && min.Shell.Enabled &&
+		len(min.Shell.ArgsTemplate) == 0 && !min.Shell.HasSteps {
 		return fmt.Errorf("invalid shell.argsTemplate: required when shell.enabled=true")
 	}
+	if min.Shell.HasArgs && min.Shell.HasSteps {
+		return fmt.Errorf(
+			"invalid shell.steps: cannot be combined with shell.argsTemplate",
+		)
+	}
 	if min.Shell.DecodeJSONStdout &&
 		min.Shell.HasCaptureStdout &&
 		!min.Shell.CaptureStdout {
@@ -34,12 +43,68 @@ func validateCommonConfig(min config.Minimal) error {
 				"shell.decodeJsonStdout=true",
 		)
 	}
+	if err := validateShellStdin(min.Shell); err != nil {
+		return err
+	}
+	if err := validateShellDecode(min.Shell); err != nil {
+		return err
+	}
+	if min.Shell.HasPlanFormat {
+		if min.Shell.PlanFormat != "json" && min.Shell.PlanFormat != "sh" {
+			return fmt.Errorf("invalid shell.planFormat: must be one of json, sh")
+		}
+		if !min.Shell.DryRun {
+			return fmt.Errorf("invalid shell.planFormat: requires shell.dryRun=true")
+		}
+	}
+	if min.Shell.HasDryRun && min.Shell.DryRun && !min.Shell.Enabled {
+		return fmt.Errorf("invalid shell.dryRun: requires shell.enabled=true")
+	}
+	if min.Shell.HasWorkDirStrat {
+		if _, err := parseWorkingDirStrategy(min.Shell.WorkDirStrategy); err != nil {
+			return err
+		}
+	}
+	if min.Shell.HasEnvAllowlist {
+		if !min.Shell.HasInheritEnv || min.Shell.InheritEnv {
+			return fmt.Errorf(
+				"invalid shell.envAllowlist: requires shell.inheritEnv=false",
+			)
+		}
+		for _, name := range min.Shell.EnvAllowlist {
+			if strings.TrimSpace(name) == "" || strings.Contains(name, "=") {
+				return fmt.Errorf(
+					"invalid shell.envAllowlist: names must be non-empty and contain no '='",
+				)
+			}
+		}
+	}
+	for i, st := range min.Shell.Steps {
+		if st.HasDecodeJSON && st.DecodeJSONStdout &&
+			min.Shell.HasCaptureStdout && !min.Shell.CaptureStdout {
+			return fmt.Errorf(
+				"invalid shell.capture.stdout: must be true when "+
+					"shell.steps[%d].decodeJsonStdout=true",
+				i,
+			)
+		}
+	}
 	if min.PersistMeta.Enabled && min.Action != "input-pipeline" {
 		return fmt.Errorf(
 			"invalid persistMeta: only supported for action " +
 				"'input-pipeline'",
 		)
 	}
+	if min.Rollup.HasSection && min.Action != "input-pipeline" {
+		return fmt.Errorf(
+			"invalid rollup: only supported for action 'input-pipeline'",
+		)
+	}
+	if min.Rollup.HasSection && !min.PersistMeta.Enabled {
+		return fmt.Errorf(
+			"invalid rollup: requires persistMeta.enabled=true",
+		)
+	}
 	if min.PersistMeta.HasOutDir &&
 		strings.TrimSpace(min.PersistMeta.OutDir) == "" {
 		return fmt.Errorf("invalid persistMeta.outDir: must be non-empty")
@@ -54,6 +119,65 @@ func validateCommonConfig(min config.Minimal) error {
 			"invalid persistMeta.dryRun: requires persistMeta.enabled=true",
 		)
 	}
+	if min.LocatorPolicy.HasUnicodeNormalization &&
+		min.LocatorPolicy.UnicodeNormalization != "NFC" &&
+		min.LocatorPolicy.UnicodeNormalization != "none" {
+		return fmt.Errorf(
+			"invalid locatorPolicy.unicodeNormalization: must be 'NFC' or 'none'",
+		)
+	}
+	if min.Errors.HasFailOn &&
+		min.Errors.FailOn != "error" &&
+		min.Errors.FailOn != "warning" {
+		return fmt.Errorf(
+			"invalid errors.failOn: must be 'error' or 'warning'",
+		)
+	}
+	if min.LuaSandbox.HasLog &&
+		min.LuaSandbox.Log != "stderr" &&
+		min.LuaSandbox.Log != "envelope" &&
+		min.LuaSandbox.Log != "off" {
+		return fmt.Errorf(
+			"invalid lua.log: must be 'stderr', 'envelope', or 'off'",
+		)
+	}
+	if min.Run.HasNow {
+		if _, err := time.Parse(time.RFC3339, min.Run.Now); err != nil {
+			return fmt.Errorf(
+				"invalid run.now: must be an RFC 3339 timestamp",
+			)
+		}
+	}
+	if min.HasRules &&
+		min.Action != "validate" &&
+		min.Action != "diff-meta" {
+		return fmt.Errorf(
+			"invalid rules: only supported for actions 'validate' " +
+				"and 'diff-meta'",
+		)
+	}
+	if min.Output.HasFormat &&
+		min.Output.Format != "json" &&
+		min.Output.Format != "sarif" &&
+		min.Output.Format != "junit" {
+		return fmt.Errorf(
+			"invalid output.format: must be 'json', 'sarif', or 'junit'",
+		)
+	}
+	if min.Output.Lines &&
+		(min.Output.Format == "sarif" || min.Output.Format == "junit") {
+		return fmt.Errorf(
+			"invalid output.lines: only supported when output.format='json'",
+		)
+	}
+	for _, p := range min.Validation.References.Pointers {
+		if !strings.HasPrefix(p, "/") {
+			return fmt.Errorf(
+				"invalid validation.references.pointers: " +
+					"must be JSON pointers starting with '/'",
+			)
+		}
+	}
 	for _, p := range min.Discovery.Include {
 		if strings.TrimSpace(p) == "" {
 			return fmt.Errorf(
@@ -70,3 +194,90 @@ func validateCommonConfig(min config.Minimal) error {
 	}
 	return nil
 }
+
+func validateShellStdin(sh config.Shell) error {
+	if !sh.HasStdin {
+		return nil
+	}
+	mode := sh.StdinMode
+	if !sh.HasStdinMode {
+		mode = "none"
+	}
+	switch mode {
+	case "none", "json", "file", "template":
+	default:
+		return fmt.Errorf(
+			"invalid shell.stdin.mode: must be 'none', 'json', 'file', or 'template'",
+		)
+	}
+	if sh.HasStdinSource {
+		if mode != "json" {
+			return fmt.Errorf("invalid shell.stdin.source: requires shell.stdin.mode='json'")
+		}
+		if sh.StdinSource != "mapped" && sh.StdinSource != "record" {
+			return fmt.Errorf("invalid shell.stdin.source: must be 'mapped' or 'record'")
+		}
+	}
+	if mode == "template" && !sh.HasStdinTemplate {
+		return fmt.Errorf("invalid shell.stdin.template: required when shell.stdin.mode='template'")
+	}
+	if mode != "template" && sh.HasStdinTemplate {
+		return fmt.Errorf("invalid shell.stdin.template: requires shell.stdin.mode='template'")
+	}
+	return nil
+}
+
+var shellDecodeFormats = []string{
+	"none", "json", "ndjson", "yaml", "csv", "tsv", "kv", "lines",
+}
+
+func validateShellDecode(sh config.Shell) error {
+	check := func(field, format string, decodeJSON bool) error {
+		if !slices.Contains(shellDecodeFormats, format) {
+			return fmt.Errorf(
+				"invalid %s: must be one of %s",
+				field,
+				strings.Join(shellDecodeFormats, ", "),
+			)
+		}
+		if decodeJSON && format != "json" {
+			return fmt.Errorf(
+				"invalid %s: conflicts with decodeJsonStdout=true",
+				field,
+			)
+		}
+		if format != "none" && sh.HasCaptureStdout && !sh.CaptureStdout {
+			return fmt.Errorf(
+				"invalid shell.capture.stdout: must be true when %s is set",
+				field,
+			)
+		}
+		return nil
+	}
+	if sh.HasDecode {
+		if err := check("shell.decode", sh.Decode, sh.DecodeJSONStdout); err != nil {
+			return err
+		}
+	}
+	for i, st := range sh.Steps {
+		if st.HasDecode {
+			field := fmt.Sprintf("shell.steps[%d].decode", i)
+			if err := check(field, st.Decode, st.HasDecodeJSON && st.DecodeJSONStdout); err != nil {
+				return err
+			}
+		}
+	}
+	if sh.HasDecodeDelim {
+		if sh.DecodeDelimiter == "" {
+			return fmt.Errorf("invalid shell.decodeOptions.delimiter: must be non-empty")
+		}
+		if (sh.Decode == "csv" || sh.Decode == "tsv") &&
+			(utf8.RuneCountInString(sh.DecodeDelimiter) != 1 ||
+				strings.ContainsAny(sh.DecodeDelimiter, "\r\n\"")) {
+			return fmt.Errorf(
+				"invalid shell.decodeOptions.delimiter: must be a single character for csv/tsv",
+			)
+		}
+	}
+	return nil
+}
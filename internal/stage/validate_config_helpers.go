@@ -15,6 +15,7 @@ const defaultMaxRecordsInMemory = 10000
 const defaultLuaTimeoutMs = 2000
 const defaultLuaInstructionLimit = 1000000
 const defaultLuaMemoryLimitBytes = 8388608
+const defaultLuaFileReadMaxBytes = 1048576
 const defaultShellProgram = "bash"
 const defaultShellWorkingDir = "."
 const defaultShellTimeoutMs = 60000
@@ -60,10 +61,12 @@ func applyMinimalToMeta(out *Envelope, min config.Minimal) {
 	applyPersistMeta(out, min)
 	applyUpdateMeta(out, min)
 	applyDiffMeta(out, min)
+	applyRulesMeta(out, min)
 	applyErrorsMeta(out, min)
 	applyFileInfoMeta(out, min)
 	applyGitMeta(out, min)
 	applyWorkersMeta(out, min)
 	applyUIMeta(out, min)
+	applyRunMeta(out, min)
 	applyLocatorPolicyMeta(out, min)
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/flarebyte/thoth-ostraca/internal/config"
@@ -71,3 +72,42 @@ func TestValidateConfig_RejectsDecodeJSONWithoutStdoutCapture(t *testing.T) {
 		t.Fatalf("unexpected error: %s", got)
 	}
 }
+
+func TestValidateConfig_ShellSteps(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n" +
+		"  shell: {\n    enabled: true\n    program: \"sh\"\n    steps: [\n" +
+		"      { name: \"lint\", argsTemplate: [\"-c\", \"echo {}\"], decodeJsonStdout: true },\n" +
+		"      { name: \"cov\", program: \"bash\", argsTemplate: [\"-c\", \"true\"], runIf: { step: \"lint\" } },\n" +
+		"    ]\n  }\n}\n"
+	out, err := runValidateConfigWithContent(t, "shell_steps_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	steps := out.Meta.Shell.Steps
+	if len(steps) != 2 || steps[0].Program != "sh" || !steps[0].DecodeJSONStdout ||
+		steps[1].Program != "bash" || steps[1].DecodeJSONStdout {
+		t.Fatalf("unexpected steps: %+v", steps)
+	}
+	if steps[1].RunIf == nil || steps[1].RunIf.Step != "lint" ||
+		len(steps[1].RunIf.ExitCodes) != 1 || steps[1].RunIf.ExitCodes[0] != 0 {
+		t.Fatalf("unexpected runIf: %+v", steps[1].RunIf)
+	}
+
+	cases := map[string]string{
+		"shell_steps_forward_test.cue": "steps: [{ name: \"a\", argsTemplate: [\"x\"], runIf: { step: \"b\" } }, { name: \"b\", argsTemplate: [\"x\"] }]",
+		"shell_steps_dup_test.cue":     "steps: [{ name: \"a\", argsTemplate: [\"x\"] }, { name: \"a\", argsTemplate: [\"x\"] }]",
+		"shell_steps_args_test.cue":    "argsTemplate: [\"x\"], steps: [{ name: \"a\", argsTemplate: [\"x\"] }]",
+	}
+	want := map[string]string{
+		"shell_steps_forward_test.cue": "invalid shell.steps[0].runIf.step: \"b\" must name an earlier step",
+		"shell_steps_dup_test.cue":     "invalid shell.steps[1].name: duplicate step \"a\"",
+		"shell_steps_args_test.cue":    "invalid shell.steps: cannot be combined with shell.argsTemplate",
+	}
+	for name, shell := range cases {
+		bad := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"nop\"\n  shell: { enabled: true, " + shell + " }\n}\n"
+		_, err := runValidateConfigWithContent(t, name, bad)
+		if err == nil || !strings.Contains(err.Error(), want[name]) {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
@@ -4,6 +4,7 @@
 // - Derive locator validation policy from the envelope metadata.
 // - Normalize allowed HTTP(S) URL locators into a stable canonical form.
 // - Reject or annotate records whose locators violate path or URL policy.
+// - Report case/Unicode collisions across the sidecar locator set when enabled.
 // Architecture notes:
 // - Locator validation runs in parallel but always resorts records by locator before returning so later stages keep deterministic ordering.
 // - URL normalization is intentionally limited to HTTP(S); this prevents the locator contract from silently widening to arbitrary schemes.
@@ -176,6 +177,13 @@ func validateLocatorsRunner(ctx context.Context, in Envelope, deps Deps) (Envelo
 	sort.Slice(out.Records, func(i, j int) bool {
 		return out.Records[i].Locator < out.Records[j].Locator
 	})
+	var inputs []string
+	if out.Meta != nil {
+		inputs = out.Meta.Inputs
+	}
+	if err := applyLocatorCollisions(&out, validateLocatorsStage, inputs); err != nil {
+		return Envelope{}, err
+	}
 	return out, nil
 }
 
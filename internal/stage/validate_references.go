@@ -0,0 +1,86 @@
+// File Guide for dev/ai agents:
+// Purpose: Check that locators referenced from sidecar metadata point at files that actually exist in the run.
+// Responsibilities:
+// - Read configured JSON pointers from each parsed record and resolve their values as locators.
+// - Report dangling or policy-violating references as stage errors.
+// - Optionally report reference cycles when validation.references.graph is enabled.
+// Architecture notes:
+// - This stage runs after validate-locators so references are checked against the same normalized locator set the rest of the pipeline uses.
+// - Each bad reference becomes its own envelope error so SARIF/JUnit reports can point at every problem; the embedded record error keeps only the first message.
+package stage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+const validateReferencesStage = "validate-references"
+
+func referencesFromMeta(meta *Meta) *ReferencesMeta {
+	if meta == nil || meta.Validation == nil {
+		return nil
+	}
+	return meta.Validation.References
+}
+
+func validateReferencesRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error) {
+	refs := referencesFromMeta(in.Meta)
+	if refs == nil || len(refs.Pointers) == 0 {
+		return in, nil
+	}
+	mode, embed := errorMode(in.Meta)
+	p := policyFromMeta(in.Meta)
+	root := determineRoot(in)
+	known := knownLocatorSet(in)
+	out := in
+	out.Records = append([]Record(nil), in.Records...)
+	edges := map[string][]string{}
+	var envErrs []Error
+	for i, r := range in.Records {
+		if r.Error != nil || r.Meta == nil {
+			continue
+		}
+		targets, problems := collectReferenceTargets(r.Meta, refs.Pointers)
+		seenTarget := map[string]struct{}{}
+		for _, t := range targets {
+			loc, msg := resolveReferenceLocator(r.Locator, t.raw, p)
+			if msg != "" {
+				problems = append(problems, fmt.Sprintf("invalid reference at %s: %s (%s)", t.pointer, msg, t.raw))
+				continue
+			}
+			if !referenceExists(in, known, root, loc) {
+				problems = append(problems, fmt.Sprintf("dangling reference at %s: %s", t.pointer, loc))
+				continue
+			}
+			if _, dup := seenTarget[loc]; !dup {
+				seenTarget[loc] = struct{}{}
+				edges[r.Locator] = append(edges[r.Locator], loc)
+			}
+		}
+		if len(problems) == 0 {
+			continue
+		}
+		if mode != "keep-going" {
+			return Envelope{}, fmt.Errorf("%s: %s (%s)", validateReferencesStage, problems[0], r.Locator)
+		}
+		rr, _ := recordFailure(r, validateReferencesStage, problems[0], embed)
+		out.Records[i] = rr
+		for _, msg := range problems {
+			envErrs = append(envErrs, Error{Stage: validateReferencesStage, Locator: r.Locator, Message: msg})
+		}
+	}
+	if refs.Graph {
+		for _, cycle := range findReferenceCycles(edges) {
+			msg := "reference cycle: " + strings.Join(cycle, " -> ")
+			if mode != "keep-going" {
+				return Envelope{}, fmt.Errorf("%s: %s", validateReferencesStage, msg)
+			}
+			envErrs = append(envErrs, Error{Stage: validateReferencesStage, Locator: cycle[0], Message: msg})
+		}
+	}
+	appendSanitizedErrors(&out, envErrs)
+	return out, nil
+}
+
+func init() { Register(validateReferencesStage, validateReferencesRunner) }
@@ -0,0 +1,214 @@
+// File Guide for dev/ai agents:
+// Purpose: Provide the pointer, locator, and graph helpers behind the validate-references stage.
+// Responsibilities:
+// - Resolve RFC 6901 JSON pointers against parsed sidecar metadata.
+// - Turn raw reference values into policy-checked locators relative to the referencing record.
+// - Detect reference cycles in a deterministic order.
+// Architecture notes:
+// - Relative references ("./", "../") resolve against the referencing locator's directory; other values are treated as root-relative locators like the ones discovery produces.
+// - Cycle detection searches from every node in sorted order over sorted edges, so the same graph always reports the same cycles in the same rotation regardless of entry points.
+package stage
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type referenceTarget struct {
+	pointer string
+	raw     string
+}
+
+// resolveJSONPointer returns the value at ptr inside doc and whether it exists.
+func resolveJSONPointer(doc any, ptr string) (any, bool) {
+	if ptr == "" {
+		return doc, true
+	}
+	cur := doc
+	for _, tok := range strings.Split(strings.TrimPrefix(ptr, "/"), "/") {
+		tok = strings.ReplaceAll(strings.ReplaceAll(tok, "~1", "/"), "~0", "~")
+		if m, ok := asStringMap(cur); ok {
+			next, ok := m[tok]
+			if !ok {
+				return nil, false
+			}
+			cur = next
+			continue
+		}
+		arr, ok := cur.([]any)
+		if !ok {
+			return nil, false
+		}
+		idx, err := strconv.Atoi(tok)
+		if err != nil || idx < 0 || idx >= len(arr) {
+			return nil, false
+		}
+		cur = arr[idx]
+	}
+	return cur, true
+}
+
+// collectReferenceTargets lists the raw reference values found at the
+// configured pointers. A missing pointer is not an error: references are
+// optional per sidecar.
+func collectReferenceTargets(meta map[string]any, pointers []string) ([]referenceTarget, []string) {
+	var targets []referenceTarget
+	var problems []string
+	for _, ptr := range pointers {
+		v, ok := resolveJSONPointer(meta, ptr)
+		if !ok || v == nil {
+			continue
+		}
+		switch x := v.(type) {
+		case string:
+			targets = append(targets, referenceTarget{pointer: ptr, raw: x})
+		case []any:
+			for i, item := range x {
+				itemPtr := joinJSONPointer(ptr, strconv.Itoa(i))
+				s, ok := item.(string)
+				if !ok {
+					problems = append(problems, fmt.Sprintf("reference at %s must be a string", itemPtr))
+					continue
+				}
+				targets = append(targets, referenceTarget{pointer: itemPtr, raw: s})
+			}
+		default:
+			problems = append(problems, fmt.Sprintf("reference at %s must be a string or list of strings", ptr))
+		}
+	}
+	return targets, problems
+}
+
+// resolveReferenceLocator normalizes a raw reference from the record at
+// `from` using the same locator policy applied to discovered records.
+func resolveReferenceLocator(from, raw string, p locatorPolicy) (string, string) {
+	ref := strings.TrimSpace(raw)
+	if ref == "" {
+		return "", "empty reference"
+	}
+	if _, isURL := parseHTTPURLLocator(ref); isURL {
+		if !p.allowURLs {
+			return "", "URL locators are not allowed"
+		}
+		normalized, err := normalizeHTTPURLLocator(ref)
+		if err != nil {
+			return "", "invalid URL locator"
+		}
+		return normalized, ""
+	}
+	if p.posix && strings.Contains(ref, "\\") {
+		return "", "backslashes are not allowed in POSIX style"
+	}
+	resolved := path.Clean(ref)
+	if strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../") {
+		resolved = path.Join(path.Dir(from), ref)
+	}
+	if bad, msg := violatesPathPolicy(resolved, p); bad {
+		return "", msg
+	}
+	return resolved, ""
+}
+
+// knownLocatorSet gathers every locator discovered so far: record locators,
+// discovered inputs, meta files, and the inputs those meta files describe.
+func knownLocatorSet(in Envelope) map[string]struct{} {
+	known := map[string]struct{}{}
+	for _, r := range in.Records {
+		known[r.Locator] = struct{}{}
+	}
+	if in.Meta == nil {
+		return known
+	}
+	for _, s := range in.Meta.Inputs {
+		known[s] = struct{}{}
+	}
+	for _, m := range in.Meta.MetaFiles {
+		known[m] = struct{}{}
+		known[sidecarLocator(m)] = struct{}{}
+	}
+	return known
+}
+
+// referenceExists checks the discovered sets first. When the action did not
+// discover input files (validate, pipeline), the file system under the
+// discovery root is the only remaining source of truth for plain files;
+// directories and other non-regular files never count.
+func referenceExists(in Envelope, known map[string]struct{}, root, loc string) bool {
+	if _, ok := known[loc]; ok {
+		return true
+	}
+	if in.Meta != nil && len(in.Meta.Inputs) > 0 {
+		return false
+	}
+	if _, isURL := parseHTTPURLLocator(loc); isURL {
+		return false
+	}
+	full := loc
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(root, filepath.FromSlash(loc))
+	}
+	info, err := os.Stat(full)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// findReferenceCycles returns, for every locator that is the smallest node
+// of some cycle, the shortest such cycle, starting and ending at that
+// locator. Each start runs its own search, so a cycle is found no matter
+// which other nodes reach it first.
+func findReferenceCycles(edges map[string][]string) [][]string {
+	nodes := make([]string, 0, len(edges))
+	for n := range edges {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+	next := make(map[string][]string, len(edges))
+	for n, targets := range edges {
+		sorted := append([]string(nil), targets...)
+		sort.Strings(sorted)
+		next[n] = sorted
+	}
+	var cycles [][]string
+	for _, start := range nodes {
+		if cycle := shortestCycleFrom(start, next); cycle != nil {
+			cycles = append(cycles, cycle)
+		}
+	}
+	return cycles
+}
+
+// shortestCycleFrom searches breadth first from start through locators
+// greater than start, so the cycle it returns is already in canonical
+// rotation. Ties resolve by sorted edge order.
+func shortestCycleFrom(start string, next map[string][]string) []string {
+	parent := map[string]string{}
+	queue := []string{start}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		for _, m := range next[n] {
+			if m == start {
+				cycle := []string{start}
+				for c := n; c != start; c = parent[c] {
+					cycle = append(cycle, c)
+				}
+				slices.Reverse(cycle[1:])
+				return append(cycle, start)
+			}
+			if m < start {
+				continue
+			}
+			if _, seen := parent[m]; seen {
+				continue
+			}
+			parent[m] = n
+			queue = append(queue, m)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package stage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func referencesEnvelope(mode string, graph bool, records ...Record) Envelope {
+	return Envelope{
+		Records: records,
+		Meta: &Meta{
+			Config:    &ConfigMeta{Action: "diff-meta"},
+			Inputs:    []string{"src/a.go", "src/a_test.go", "src/b.go"},
+			MetaFiles: []string{"src/a.go.thoth.yaml", "src/b.go.thoth.yaml"},
+			Errors:    &ErrorsMeta{Mode: mode, EmbedErrors: true},
+			Validation: &ValidationMeta{References: &ReferencesMeta{
+				Pointers: []string{"/dependsOn", "/test"},
+				Graph:    graph,
+			}},
+		},
+	}
+}
+
+func TestResolveJSONPointer_EscapesAndIndices(t *testing.T) {
+	doc := map[string]any{
+		"a/b": map[string]any{"~x": []any{"zero", "one"}},
+	}
+	v, ok := resolveJSONPointer(doc, "/a~1b/~0x/1")
+	if !ok || v != "one" {
+		t.Fatalf("unexpected value: %#v ok=%v", v, ok)
+	}
+	if _, ok := resolveJSONPointer(doc, "/a~1b/~0x/9"); ok {
+		t.Fatalf("expected out-of-range index to be missing")
+	}
+}
+
+func TestResolveReferenceLocator_RelativeAndPolicy(t *testing.T) {
+	p := locatorPolicy{posix: true}
+	cases := []struct {
+		from, raw, want, msg string
+	}{
+		{"src/a.go", "./a_test.go", "src/a_test.go", ""},
+		{"src/a.go", "../docs/x.md", "docs/x.md", ""},
+		{"src/a.go", "src//b.go", "src/b.go", ""},
+		{"src/a.go", "../../x.go", "", "parent references ('..') are not allowed"},
+		{"src/a.go", "/etc/passwd", "", "absolute paths are not allowed"},
+		{"src/a.go", "https://example.com", "", "URL locators are not allowed"},
+		{"src/a.go", "  ", "", "empty reference"},
+	}
+	for _, c := range cases {
+		got, msg := resolveReferenceLocator(c.from, c.raw, p)
+		if got != c.want || msg != c.msg {
+			t.Fatalf("%q from %q: got (%q, %q) want (%q, %q)", c.raw, c.from, got, msg, c.want, c.msg)
+		}
+	}
+}
+
+func TestFindReferenceCycles_DeterministicRotation(t *testing.T) {
+	edges := map[string][]string{
+		"c": {"a"},
+		"a": {"b"},
+		"b": {"c"},
+		"d": {"d"},
+		"e": {"a"},
+	}
+	got := findReferenceCycles(edges)
+	if len(got) != 2 {
+		t.Fatalf("unexpected cycles: %v", got)
+	}
+	if strings.Join(got[0], ",") != "a,b,c,a" || strings.Join(got[1], ",") != "d,d" {
+		t.Fatalf("unexpected cycles: %v", got)
+	}
+}
+
+func TestFindReferenceCycles_CycleEnteredFromTwoNodes(t *testing.T) {
+	// c <-> d is reached through d (from a) and through c (from b); both
+	// are finished by the time the second entry point is walked.
+	edges := map[string][]string{
+		"a": {"d"},
+		"b": {"c"},
+		"c": {"a", "d"},
+		"d": {"b", "c"},
+	}
+	var got []string
+	for _, c := range findReferenceCycles(edges) {
+		got = append(got, strings.Join(c, ","))
+	}
+	want := "a,d,c,a|b,c,d,b|c,d,c"
+	if strings.Join(got, "|") != want {
+		t.Fatalf("cycles=%v want %s", got, want)
+	}
+}
+
+func TestReferenceExists_DirectoryIsNotATarget(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "docs", "a.md"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	in := Envelope{Meta: &Meta{}}
+	if referenceExists(in, map[string]struct{}{}, root, "docs") {
+		t.Fatalf("a directory must not satisfy a reference")
+	}
+	if !referenceExists(in, map[string]struct{}{}, root, "docs/a.md") {
+		t.Fatalf("expected regular file to satisfy a reference")
+	}
+}
+
+func TestValidateReferences_KeepGoingReportsDanglingAndCycles(t *testing.T) {
+	in := referencesEnvelope("keep-going", true,
+		Record{Locator: "src/a.go", Meta: map[string]any{
+			"dependsOn": []any{"src/b.go", "src/missing.go"},
+			"test":      "./a_test.go",
+		}},
+		Record{Locator: "src/b.go", Meta: map[string]any{
+			"dependsOn": []any{"src/a.go", 3},
+		}},
+	)
+	out, err := Run(context.Background(), validateReferencesStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-references: %v", err)
+	}
+	want := []string{
+		"dangling reference at /dependsOn/1: src/missing.go",
+		"reference cycle: src/a.go -> src/b.go -> src/a.go",
+		"reference at /dependsOn/1 must be a string",
+	}
+	if len(out.Errors) != len(want) {
+		t.Fatalf("unexpected errors: %+v", out.Errors)
+	}
+	for i, e := range out.Errors {
+		if e.Stage != validateReferencesStage || e.Message != want[i] {
+			t.Fatalf("error[%d]=%+v want message %q", i, e, want[i])
+		}
+	}
+	if out.Records[0].Error == nil ||
+		out.Records[0].Error.Message != "dangling reference at /dependsOn/1: src/missing.go" {
+		t.Fatalf("expected embedded record error, got %+v", out.Records[0].Error)
+	}
+}
+
+func TestValidateReferences_NoCycleReportWithoutGraph(t *testing.T) {
+	in := referencesEnvelope("keep-going", false,
+		Record{Locator: "src/a.go", Meta: map[string]any{"dependsOn": "src/b.go"}},
+		Record{Locator: "src/b.go", Meta: map[string]any{"dependsOn": "src/a.go"}},
+	)
+	out, err := Run(context.Background(), validateReferencesStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-references: %v", err)
+	}
+	if len(out.Errors) != 0 {
+		t.Fatalf("expected no errors, got %+v", out.Errors)
+	}
+}
+
+func TestValidateReferences_FailFastStopsOnFirstDangling(t *testing.T) {
+	in := referencesEnvelope("fail-fast", false,
+		Record{Locator: "src/a.go", Meta: map[string]any{"test": "nope.go"}},
+	)
+	_, err := Run(context.Background(), validateReferencesStage, in, Deps{})
+	if err == nil || err.Error() != "validate-references: dangling reference at /test: nope.go (src/a.go)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_RejectsReferencePointerWithoutSlash(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  validation: { references: { pointers: [\"dependsOn\"] } }\n}\n"
+	_, err := runValidateConfigWithContent(t, "references_bad_pointer_validate_test.cue", content)
+	if err == nil || err.Error() != "invalid validation.references.pointers: must be JSON pointers starting with '/'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_ExposesReferences(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  validation: { references: { pointers: [\"/dependsOn\"], graph: true } }\n}\n"
+	out, err := runValidateConfigWithContent(t, "references_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	refs := referencesFromMeta(out.Meta)
+	if refs == nil || !refs.Graph || len(refs.Pointers) != 1 || refs.Pointers[0] != "/dependsOn" {
+		t.Fatalf("unexpected references meta: %+v", refs)
+	}
+}
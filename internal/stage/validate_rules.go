@@ -0,0 +1,168 @@
+// File Guide for dev/ai agents:
+// Purpose: Apply per-glob metadata rules to parsed sidecars for the validate and diff-meta actions.
+// Responsibilities:
+// - Match each record locator against configured rule globs.
+// - Check required/forbidden keys, enum values, and regex constraints on top-level meta keys.
+// - Publish every violation in meta.rulesReport and escalate error-severity ones as stage errors.
+// Architecture notes:
+// - Rule globs reuse matchesDiscoveryPattern so `rules[].match` behaves exactly like discovery.include/exclude.
+// - Warning-severity violations go to the report and the envelope warnings channel; they never mark records as failed.
+package stage
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const validateRulesStage = "validate-rules"
+
+type compiledRule struct {
+	RuleMeta
+	regex map[string]*regexp.Regexp
+}
+
+func compileRules(rules []RuleMeta) ([]compiledRule, error) {
+	out := make([]compiledRule, 0, len(rules))
+	for _, r := range rules {
+		cr := compiledRule{RuleMeta: r, regex: map[string]*regexp.Regexp{}}
+		for k, p := range r.Regex {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rule %s regex for %s: %v", r.Name, k, err)
+			}
+			cr.regex[k] = re
+		}
+		out = append(out, cr)
+	}
+	return out, nil
+}
+
+func validateRulesRunner(ctx context.Context, in Envelope, deps Deps) (Envelope, error) {
+	if in.Meta == nil || len(in.Meta.Rules) == 0 {
+		return in, nil
+	}
+	rules, err := compileRules(in.Meta.Rules)
+	if err != nil {
+		return Envelope{}, err
+	}
+	mode, embed := errorMode(in.Meta)
+	out := in
+	out.Records = append([]Record(nil), in.Records...)
+	report := &RulesReport{Violations: make([]RuleViolation, 0)}
+	var envErrs, warns []Error
+	for i, r := range in.Records {
+		if r.Error != nil {
+			continue
+		}
+		var firstErrMsg string
+		for _, rule := range rules {
+			if !matchesDiscoveryPattern(rule.Match, r.Locator) {
+				continue
+			}
+			for _, v := range checkRule(rule, r) {
+				report.Violations = append(report.Violations, v)
+				msg := "rule " + v.Rule + ": " + v.Message
+				if v.Severity == "warning" {
+					report.WarningCount++
+					warns = append(warns, Error{Stage: validateRulesStage, Locator: r.Locator, Message: msg})
+					continue
+				}
+				report.ErrorCount++
+				if mode != "keep-going" {
+					return Envelope{}, fmt.Errorf("%s: %s (%s)", validateRulesStage, msg, r.Locator)
+				}
+				if firstErrMsg == "" {
+					firstErrMsg = msg
+				}
+				envErrs = append(envErrs, Error{Stage: validateRulesStage, Locator: r.Locator, Message: msg})
+			}
+		}
+		if firstErrMsg != "" {
+			out.Records[i], _ = recordFailure(r, validateRulesStage, firstErrMsg, embed)
+		}
+	}
+	sort.SliceStable(report.Violations, func(i, j int) bool {
+		return report.Violations[i].Locator < report.Violations[j].Locator
+	})
+	out.Meta.RulesReport = report
+	appendSanitizedErrors(&out, envErrs)
+	appendWarnings(&out, warns)
+	return out, nil
+}
+
+// checkRule returns the violations of one rule for one record in a fixed
+// order: required, forbidden, enum, then regex, each by sorted key.
+func checkRule(rule compiledRule, r Record) []RuleViolation {
+	var out []RuleViolation
+	add := func(key, msg string) {
+		out = append(out, RuleViolation{
+			Locator:  r.Locator,
+			Rule:     rule.Name,
+			Key:      key,
+			Severity: rule.Severity,
+			Message:  msg,
+		})
+	}
+	for _, k := range uniqueSortedStrings(rule.Required) {
+		if _, ok := r.Meta[k]; !ok {
+			add(k, fmt.Sprintf("missing required key '%s'", k))
+		}
+	}
+	for _, k := range uniqueSortedStrings(rule.Forbidden) {
+		if _, ok := r.Meta[k]; ok {
+			add(k, fmt.Sprintf("forbidden key '%s' is present", k))
+		}
+	}
+	for _, k := range sortedKeys(rule.Enum) {
+		v, ok := r.Meta[k]
+		if !ok {
+			continue
+		}
+		allowed := rule.Enum[k]
+		if !enumContains(allowed, v) {
+			add(k, fmt.Sprintf("key '%s' value %v is not one of %s", k, v, formatEnum(allowed)))
+		}
+	}
+	for _, k := range sortedKeys(rule.regex) {
+		v, ok := r.Meta[k]
+		if !ok {
+			continue
+		}
+		s, isStr := v.(string)
+		if !isStr || !rule.regex[k].MatchString(s) {
+			add(k, fmt.Sprintf("key '%s' value %v does not match /%s/", k, v, rule.Regex[k]))
+		}
+	}
+	return out
+}
+
+func enumContains(allowed []any, v any) bool {
+	for _, a := range allowed {
+		if metaScalarEqual(a, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(allowed []any) string {
+	parts := make([]string, 0, len(allowed))
+	for _, a := range allowed {
+		parts = append(parts, fmt.Sprint(a))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func init() { Register(validateRulesStage, validateRulesRunner) }
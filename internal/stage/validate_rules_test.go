@@ -0,0 +1,141 @@
+package stage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func rulesEnvelope(mode string, rules []RuleMeta, records ...Record) Envelope {
+	return Envelope{
+		Records: records,
+		Meta: &Meta{
+			Config: &ConfigMeta{Action: "validate"},
+			Errors: &ErrorsMeta{Mode: mode, EmbedErrors: true},
+			Rules:  rules,
+		},
+	}
+}
+
+func sampleRules() []RuleMeta {
+	return []RuleMeta{
+		{
+			Name:      "go-files",
+			Match:     "**/*.go",
+			Required:  []string{"purpose", "owner"},
+			Forbidden: []string{"legacy"},
+			Enum:      map[string][]any{"status": {"draft", "stable"}, "tier": {1, 2}},
+			Regex:     map[string]string{"owner": "^team-"},
+			Severity:  "error",
+		},
+		{
+			Name:     "docs",
+			Match:    "docs/**",
+			Required: []string{"audience"},
+			Severity: "warning",
+		},
+	}
+}
+
+func TestValidateRules_KeepGoingReportsViolationsBySeverity(t *testing.T) {
+	in := rulesEnvelope("keep-going", sampleRules(),
+		Record{Locator: "docs/intro.md", Meta: map[string]any{"owner": "x"}},
+		Record{Locator: "src/a.go", Meta: map[string]any{
+			"owner":  "bob",
+			"legacy": true,
+			"status": "wip",
+			"tier":   2,
+		}},
+		Record{Locator: "src/b.go", Meta: map[string]any{
+			"owner":   "team-a",
+			"purpose": "x",
+			"status":  "stable",
+			"tier":    1,
+		}},
+	)
+	out, err := Run(context.Background(), validateRulesStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-rules: %v", err)
+	}
+	rep := out.Meta.RulesReport
+	if rep == nil || rep.ErrorCount != 4 || rep.WarningCount != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	want := []RuleViolation{
+		{Locator: "docs/intro.md", Rule: "docs", Key: "audience", Severity: "warning", Message: "missing required key 'audience'"},
+		{Locator: "src/a.go", Rule: "go-files", Key: "purpose", Severity: "error", Message: "missing required key 'purpose'"},
+		{Locator: "src/a.go", Rule: "go-files", Key: "legacy", Severity: "error", Message: "forbidden key 'legacy' is present"},
+		{Locator: "src/a.go", Rule: "go-files", Key: "status", Severity: "error", Message: "key 'status' value wip is not one of [draft, stable]"},
+		{Locator: "src/a.go", Rule: "go-files", Key: "owner", Severity: "error", Message: "key 'owner' value bob does not match /^team-/"},
+	}
+	if len(rep.Violations) != len(want) {
+		t.Fatalf("unexpected violations: %+v", rep.Violations)
+	}
+	for i := range want {
+		if rep.Violations[i] != want[i] {
+			t.Fatalf("violation[%d]=%+v want %+v", i, rep.Violations[i], want[i])
+		}
+	}
+	if len(out.Errors) != 4 {
+		t.Fatalf("expected only error-severity violations as errors, got %+v", out.Errors)
+	}
+	if out.Records[0].Error != nil || out.Records[2].Error != nil {
+		t.Fatalf("expected warning-only and clean records to stay valid")
+	}
+	if out.Records[1].Error == nil || out.Records[1].Error.Message != "rule go-files: missing required key 'purpose'" {
+		t.Fatalf("unexpected record error: %+v", out.Records[1].Error)
+	}
+}
+
+func TestValidateRules_FailFastOnFirstErrorViolation(t *testing.T) {
+	in := rulesEnvelope("fail-fast", sampleRules(),
+		Record{Locator: "docs/intro.md", Meta: map[string]any{}},
+		Record{Locator: "src/a.go", Meta: map[string]any{"purpose": "x"}},
+	)
+	_, err := Run(context.Background(), validateRulesStage, in, Deps{})
+	if err == nil || err.Error() != "validate-rules: rule go-files: missing required key 'owner' (src/a.go)" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_ParsesRules(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n" +
+		"  rules: [{ name: \"go\", match: \"**/*.go\", required: [\"owner\"], enum: { tier: [1, 2] }, regex: { owner: \"^team-\" } }, { match: \"docs/**\", severity: \"warning\" }]\n}\n"
+	out, err := runValidateConfigWithContent(t, "rules_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if len(out.Meta.Rules) != 2 {
+		t.Fatalf("unexpected rules: %+v", out.Meta.Rules)
+	}
+	if out.Meta.Rules[0].Name != "go" || out.Meta.Rules[0].Severity != "error" {
+		t.Fatalf("unexpected first rule: %+v", out.Meta.Rules[0])
+	}
+	if out.Meta.Rules[1].Name != "rules[1]" || out.Meta.Rules[1].Severity != "warning" {
+		t.Fatalf("unexpected second rule: %+v", out.Meta.Rules[1])
+	}
+	if !enumContains(out.Meta.Rules[0].Enum["tier"], 2) {
+		t.Fatalf("expected numeric enum to match YAML int: %+v", out.Meta.Rules[0].Enum)
+	}
+}
+
+func TestValidateConfig_RejectsInvalidRules(t *testing.T) {
+	cases := []struct {
+		name, rules, action, want string
+	}{
+		{"severity", `[{ match: "**", severity: "fatal" }]`, "validate", "invalid rules[0].severity: must be 'error' or 'warning'"},
+		{"match", `[{ required: ["a"] }]`, "validate", "invalid rules[0].match: must be non-empty glob"},
+		{"regex", `[{ match: "**", regex: { a: "(" } }]`, "validate", "invalid rules[0].regex.a: error parsing regexp: missing closing ): `(`"},
+		{"regex_many", `[{ match: "**", regex: { z: "(", b: "[", m: "(" } }]`, "validate", "invalid rules[0].regex.b: error parsing regexp: missing closing ]: `[`"},
+		{"enum_many", `[{ match: "**", enum: { z: 1, a: [1], c: "x" } }]`, "validate", "invalid rules[0].enum.c: must be list"},
+		{"action", `[{ match: "**" }]`, "create-meta", "invalid rules: only supported for actions 'validate' and 'diff-meta'"},
+	}
+	for _, c := range cases {
+		content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"" + c.action + "\"\n  rules: " + c.rules + "\n}\n"
+		_, err := runValidateConfigWithContent(t, "rules_bad_"+c.name+"_validate_test.cue", content)
+		if err == nil || err.Error() != c.want {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// File Guide for dev/ai agents:
+// Purpose: Carry soft findings (warnings) alongside envelope errors without affecting error-mode or exit semantics.
+// Responsibilities:
+// - Provide the concurrency-safe collector that Lua `thoth.warn` and other helpers append to during one stage run.
+// - Attach a fresh collector (and the Lua debug collector from lua_debug.go) to a shallow meta copy for each stage and drain it into envelope warnings afterwards.
+// - Keep envelope warnings sanitized and sorted exactly like envelope errors.
+// Architecture notes:
+// - Warnings reuse the `Error` shape so reports and tooling can treat both channels uniformly; only `errors.failOn="warning"` turns them into failures.
+// - The collector rides on an unexported Meta field instead of new function parameters so parallel record workers can warn without changing every stage signature.
+package stage
+
+import (
+	"context"
+	"sync"
+)
+
+type warningCollector struct {
+	mu    sync.Mutex
+	items []Error
+}
+
+func (c *warningCollector) add(e Error) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.items = append(c.items, e)
+	c.mu.Unlock()
+}
+
+func (c *warningCollector) drain() []Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := c.items
+	c.items = nil
+	return out
+}
+
+// addWarning records a warning for the stage currently running with meta.
+// It is a no-op when the stage runs outside Run (e.g. direct helper calls).
+func addWarning(meta *Meta, stageName, locator, msg string) {
+	if meta == nil {
+		return
+	}
+	meta.warnings.add(Error{Stage: stageName, Locator: locator, Message: msg})
+}
+
+func appendWarnings(out *Envelope, warns []Error) {
+	if len(warns) == 0 {
+		return
+	}
+	for _, w := range warns {
+		out.Warnings = append(out.Warnings, sanitizedError(w))
+	}
+	sortErrorList(out.Warnings)
+}
+
+// runWithWarnings runs r with per-run warning and Lua debug collectors and
+// appends whatever was collected to the returned envelope.
+func runWithWarnings(ctx context.Context, r Runner, in Envelope, deps Deps) (Envelope, error) {
+	if in.Meta == nil {
+		return r(ctx, in, deps)
+	}
+	c := &warningCollector{}
+	d := &debugCollector{}
+	m := *in.Meta
+	m.warnings = c
+	m.debug = d
+	in.Meta = &m
+	out, err := r(ctx, in, deps)
+	if err != nil {
+		flushLuaDebug(nil, &m, d, deps.Stderr)
+		return out, err
+	}
+	if out.Meta != nil {
+		out.Meta.warnings = nil
+		out.Meta.debug = nil
+	}
+	appendWarnings(&out, c.drain())
+	flushLuaDebug(&out, &m, d, deps.Stderr)
+	return out, nil
+}
@@ -0,0 +1,77 @@
+package stage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func TestLuaMap_ThothWarnCollectsSortedWarnings(t *testing.T) {
+	in := Envelope{
+		Records: []Record{
+			{Locator: "b", Meta: map[string]any{}},
+			{Locator: "a", Meta: map[string]any{}},
+		},
+		Meta: &Meta{Lua: &LuaMeta{MapInline: "thoth.warn('check ' .. locator); return locator"}},
+	}
+	out, err := Run(context.Background(), luaMapStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("lua-map: %v", err)
+	}
+	if len(out.Errors) != 0 {
+		t.Fatalf("warnings must not become errors: %+v", out.Errors)
+	}
+	want := []Error{
+		{Stage: luaMapStage, Locator: "a", Message: "check a"},
+		{Stage: luaMapStage, Locator: "b", Message: "check b"},
+	}
+	if len(out.Warnings) != len(want) {
+		t.Fatalf("unexpected warnings: %+v", out.Warnings)
+	}
+	for i := range want {
+		if out.Warnings[i] != want[i] {
+			t.Fatalf("warning[%d]=%+v want %+v", i, out.Warnings[i], want[i])
+		}
+	}
+	if out.Meta.warnings != nil || in.Meta.warnings != nil {
+		t.Fatalf("warning collector must not leak past the stage run")
+	}
+}
+
+func TestThothWarn_NoSinkIsNoop(t *testing.T) {
+	L := newLuaStateWithThothLib(t)
+	if err := L.DoString("thoth.warn('ignored')"); err != nil {
+		t.Fatalf("thoth.warn without sink: %v", err)
+	}
+}
+
+func TestValidateRules_WarningsUseWarningChannel(t *testing.T) {
+	in := rulesEnvelope("keep-going", sampleRules(),
+		Record{Locator: "docs/intro.md", Meta: map[string]any{}},
+	)
+	out, err := Run(context.Background(), validateRulesStage, in, Deps{})
+	if err != nil {
+		t.Fatalf("validate-rules: %v", err)
+	}
+	if len(out.Errors) != 0 || len(out.Warnings) != 1 ||
+		out.Warnings[0].Message != "rule docs: missing required key 'audience'" {
+		t.Fatalf("unexpected errors=%+v warnings=%+v", out.Errors, out.Warnings)
+	}
+}
+
+func TestValidateConfig_ErrorsFailOn(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  errors: { failOn: \"warning\" }\n}\n"
+	out, err := runValidateConfigWithContent(t, "errors_fail_on_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Errors == nil || out.Meta.Errors.FailOn != "warning" {
+		t.Fatalf("unexpected errors meta: %+v", out.Meta.Errors)
+	}
+	bad := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  errors: { failOn: \"info\" }\n}\n"
+	_, err = runValidateConfigWithContent(t, "errors_fail_on_bad_validate_test.cue", bad)
+	if err == nil || err.Error() != "invalid errors.failOn: must be 'error' or 'warning'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
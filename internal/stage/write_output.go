@@ -1,11 +1,11 @@
 // File Guide for dev/ai agents:
 // Purpose: Serialize the final envelope or record stream into deterministic JSON output for files or stdout.
 // Responsibilities:
-// - Read output settings from metadata and choose aggregate JSON or line-delimited JSON mode.
+// - Read output settings from metadata and choose aggregate JSON, line-delimited JSON, or a report format.
 // - Strip embedded record errors when the envelope contract says they should not be serialized.
 // - Open the destination writer, encode JSON deterministically, and write the final bytes.
 // Architecture notes:
-// - Output is JSON-only by design here; other stages shape the envelope first so this writer can stay small and deterministic.
+// - The envelope JSON is the primary contract; SARIF and JUnit reports are derived views rendered by sibling write_output_* files.
 // - Streaming NDJSON support is kept separate from aggregate envelope encoding so large pipelines can avoid buffering without changing the output contract.
 package stage
 
@@ -33,6 +33,13 @@ func getOutputSettings(meta *Meta) (outPath string, pretty bool, lines bool) {
 	return
 }
 
+func getOutputFormat(meta *Meta) string {
+	if meta != nil && meta.Output != nil && meta.Output.Format != "" {
+		return meta.Output.Format
+	}
+	return "json"
+}
+
 func stripErrorsIfNeeded(env *Envelope) {
 	if env.Meta != nil && env.Meta.Errors != nil && !env.Meta.Errors.EmbedErrors {
 		for i := range env.Records {
@@ -135,8 +142,30 @@ func writeOutputRunner(_ctx context.Context, in Envelope, deps Deps) (Envelope,
 	}
 	env.Meta.ContractVersion = "1"
 	SortEnvelopeErrors(&env)
+	SortEnvelopeWarnings(&env)
 	stripErrorsIfNeeded(&env)
 
+	switch getOutputFormat(env.Meta) {
+	case "sarif":
+		data, err := encodeSARIFReport(env, pretty)
+		if err != nil {
+			return Envelope{}, err
+		}
+		if err := writeTo(outPath, data); err != nil {
+			return Envelope{}, err
+		}
+		return in, nil
+	case "junit":
+		data, err := encodeJUnitReport(env)
+		if err != nil {
+			return Envelope{}, err
+		}
+		if err := writeTo(outPath, data); err != nil {
+			return Envelope{}, err
+		}
+		return in, nil
+	}
+
 	if lines {
 		if deps.RecordStream != nil {
 			_, err := writeLinesFromStream(outPath, env.Meta, deps.RecordStream)
@@ -0,0 +1,97 @@
+// File Guide for dev/ai agents:
+// Purpose: Flatten envelope errors, warnings, and diff-meta drift into one ordered finding list shared by the SARIF and JUnit writers.
+// Responsibilities:
+// - Convert envelope errors into error-level findings keyed by stage and locator.
+// - Convert envelope warnings (rule warnings, thoth.warn) into warning-level findings.
+// - Convert changed diff details and orphan sidecars into drift findings located at the sidecar file.
+// - Summarize diff detail changes into one compact human-readable message.
+// Architecture notes:
+// - Report writers consume findings instead of raw envelopes so both formats agree on what counts as a problem.
+// - Ordering follows the already-sorted envelope errors and diff report, which keeps report bytes deterministic without extra sorting here.
+package stage
+
+import "strings"
+
+type reportFinding struct {
+	ruleID  string
+	level   string
+	locator string
+	uri     string
+	message string
+}
+
+func collectReportFindings(env Envelope) []reportFinding {
+	findings := make([]reportFinding, 0, len(env.Errors))
+	for _, e := range env.Errors {
+		findings = append(findings, reportFinding{
+			ruleID:  e.Stage,
+			level:   "error",
+			locator: e.Locator,
+			uri:     e.Locator,
+			message: sanitizeErrorMessage(e.Message),
+		})
+	}
+	for _, w := range env.Warnings {
+		findings = append(findings, reportFinding{
+			ruleID:  w.Stage,
+			level:   "warning",
+			locator: w.Locator,
+			uri:     w.Locator,
+			message: sanitizeErrorMessage(w.Message),
+		})
+	}
+	if env.Meta == nil || env.Meta.Diff == nil {
+		return findings
+	}
+	for _, d := range env.Meta.Diff.Details {
+		if !detailHasChanges(d) {
+			continue
+		}
+		findings = append(findings, reportFinding{
+			ruleID:  computeMetaDiffStage,
+			level:   "warning",
+			locator: d.Locator,
+			uri:     d.MetaFile,
+			message: diffDetailMessage(d),
+		})
+	}
+	for _, orphan := range env.Meta.Diff.OrphanMetaFiles {
+		findings = append(findings, reportFinding{
+			ruleID:  computeMetaDiffStage,
+			level:   "warning",
+			locator: orphan,
+			uri:     orphan,
+			message: "orphan meta file: no matching input file",
+		})
+	}
+	return findings
+}
+
+func diffDetailMessage(d DiffDetail) string {
+	parts := []string{}
+	if len(d.AddedKeys) > 0 {
+		parts = append(parts, "added "+strings.Join(d.AddedKeys, ", "))
+	}
+	if len(d.RemovedKeys) > 0 {
+		parts = append(parts, "removed "+strings.Join(d.RemovedKeys, ", "))
+	}
+	if len(d.ChangedKeys) > 0 {
+		parts = append(parts, "changed "+strings.Join(d.ChangedKeys, ", "))
+	}
+	if len(d.TypeChangedKeys) > 0 {
+		parts = append(parts, "type changed "+strings.Join(d.TypeChangedKeys, ", "))
+	}
+	arrays := []string{}
+	for _, a := range d.Arrays {
+		if arrayDiffHasChanges(a) {
+			arrays = append(arrays, a.Path)
+		}
+	}
+	if len(arrays) > 0 {
+		parts = append(parts, "array changes "+strings.Join(arrays, ", "))
+	}
+	if len(parts) == 0 {
+		return "meta drift"
+	}
+	return "meta drift: " + strings.Join(parts, "; ")
+}
@@ -0,0 +1,109 @@
+// File Guide for dev/ai agents:
+// Purpose: Render envelope records and findings as a JUnit XML report for test dashboards.
+// Responsibilities:
+// - Emit one testcase per locator seen in records, envelope errors, or the diff report.
+// - Fold every finding for a locator into a single failure element.
+// - Produce indented XML with a fixed header so report bytes are deterministic.
+// Architecture notes:
+// - Locator-less envelope errors become testcases named after their stage so run-level failures remain visible.
+// - Testcases are sorted by name; failure bodies keep finding order, which already follows the sorted envelope errors.
+package stage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+func buildJUnitReport(env Envelope) junitTestSuites {
+	suiteName := "thoth"
+	if env.Meta != nil && env.Meta.Config != nil && env.Meta.Config.Action != "" {
+		suiteName = env.Meta.Config.Action
+	}
+	byName := map[string][]reportFinding{}
+	for _, r := range env.Records {
+		if r.Locator != "" {
+			byName[r.Locator] = byName[r.Locator]
+		}
+	}
+	if env.Meta != nil && env.Meta.Diff != nil {
+		for _, d := range env.Meta.Diff.Details {
+			byName[d.Locator] = byName[d.Locator]
+		}
+	}
+	for _, f := range collectReportFindings(env) {
+		name := f.locator
+		if name == "" {
+			name = f.ruleID
+		}
+		byName[name] = append(byName[name], f)
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	suite := junitTestSuite{Name: suiteName, TestCases: make([]junitTestCase, 0, len(names))}
+	for _, name := range names {
+		tc := junitTestCase{Name: name, ClassName: "thoth." + suiteName}
+		if findings := byName[name]; len(findings) > 0 {
+			lines := make([]string, 0, len(findings))
+			for _, f := range findings {
+				lines = append(lines, fmt.Sprintf("[%s] %s", f.ruleID, f.message))
+			}
+			tc.Failure = &junitFailure{
+				Message: findings[0].message,
+				Type:    findings[0].ruleID,
+				Body:    strings.Join(lines, "\n"),
+			}
+			suite.Failures++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+	return junitTestSuites{
+		Name:     "thoth",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+}
+
+func encodeJUnitReport(env Envelope) ([]byte, error) {
+	b, err := xml.MarshalIndent(buildJUnitReport(env), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	out := append([]byte(xml.Header), b...)
+	out = append(out, '\n')
+	return out, nil
+}
@@ -0,0 +1,134 @@
+package stage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/flarebyte/thoth-ostraca/internal/config"
+)
+
+func reportTestEnvelope() Envelope {
+	return Envelope{
+		Records: []Record{{Locator: "a.go"}, {Locator: "b.go"}},
+		Meta: &Meta{
+			Config: &ConfigMeta{Action: "diff-meta"},
+			Diff: &DiffReport{
+				OrphanMetaFiles: []string{"gone.go.thoth.yaml"},
+				Details: []DiffDetail{
+					{Locator: "a.go", MetaFile: "a.go.thoth.yaml"},
+					{Locator: "b.go", MetaFile: "b.go.thoth.yaml", AddedKeys: []string{"owner"}, ChangedKeys: []string{"tags"}},
+				},
+			},
+		},
+		Errors: []Error{
+			{Stage: "parse-validate-yaml", Locator: "c.go.thoth.yaml", Message: "invalid YAML: bad"},
+			{Stage: "validate-config", Message: "boom"},
+		},
+	}
+}
+
+func TestEncodeSARIFReport_MapsErrorsAndDrift(t *testing.T) {
+	got, err := encodeSARIFReport(reportTestEnvelope(), false)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	want := `{"$schema":"https://json.schemastore.org/sarif-2.1.0.json","version":"2.1.0","runs":[{"tool":{"driver":{"name":"thoth","rules":[{"id":"compute-meta-diff"},{"id":"parse-validate-yaml"},{"id":"validate-config"}]}},"results":[` +
+		`{"ruleId":"parse-validate-yaml","level":"error","message":{"text":"invalid YAML: bad"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"c.go.thoth.yaml"}}}]},` +
+		`{"ruleId":"validate-config","level":"error","message":{"text":"boom"}},` +
+		`{"ruleId":"compute-meta-diff","level":"warning","message":{"text":"meta drift: added owner; changed tags"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"b.go.thoth.yaml"}}}]},` +
+		`{"ruleId":"compute-meta-diff","level":"warning","message":{"text":"orphan meta file: no matching input file"},"locations":[{"physicalLocation":{"artifactLocation":{"uri":"gone.go.thoth.yaml"}}}]}]}]}` + "\n"
+	if string(got) != want {
+		t.Fatalf("unexpected sarif:\n%s", got)
+	}
+}
+
+func TestEncodeSARIFReport_EmptyResultsNotNull(t *testing.T) {
+	got, err := encodeSARIFReport(Envelope{Records: []Record{}, Meta: &Meta{}}, false)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !strings.Contains(string(got), `"rules":[]`) || !strings.Contains(string(got), `"results":[]`) {
+		t.Fatalf("expected empty arrays, got: %s", got)
+	}
+}
+
+func TestEncodeJUnitReport_TestcasePerLocator(t *testing.T) {
+	got, err := encodeJUnitReport(reportTestEnvelope())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="thoth" tests="5" failures="4">
+  <testsuite name="diff-meta" tests="5" failures="4">
+    <testcase name="a.go" classname="thoth.diff-meta"></testcase>
+    <testcase name="b.go" classname="thoth.diff-meta">
+      <failure message="meta drift: added owner; changed tags" type="compute-meta-diff">[compute-meta-diff] meta drift: added owner; changed tags</failure>
+    </testcase>
+    <testcase name="c.go.thoth.yaml" classname="thoth.diff-meta">
+      <failure message="invalid YAML: bad" type="parse-validate-yaml">[parse-validate-yaml] invalid YAML: bad</failure>
+    </testcase>
+    <testcase name="gone.go.thoth.yaml" classname="thoth.diff-meta">
+      <failure message="orphan meta file: no matching input file" type="compute-meta-diff">[compute-meta-diff] orphan meta file: no matching input file</failure>
+    </testcase>
+    <testcase name="validate-config" classname="thoth.diff-meta">
+      <failure message="boom" type="validate-config">[validate-config] boom</failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if string(got) != want {
+		t.Fatalf("unexpected junit:\n%s", got)
+	}
+}
+
+func TestWriteOutput_SARIFFormatIsDeterministic(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "report.sarif")
+	env := reportTestEnvelope()
+	env.Meta.Output = &OutputMeta{Out: outPath, Format: "sarif"}
+	var first []byte
+	for i := 0; i < 3; i++ {
+		if _, err := Run(context.Background(), writeOutputStage, env, Deps{}); err != nil {
+			t.Fatalf("write-output: %v", err)
+		}
+		b := mustRead(t, outPath)
+		if first == nil {
+			first = b
+			continue
+		}
+		if string(b) != string(first) {
+			t.Fatalf("sarif output drift at run %d", i)
+		}
+	}
+	if !strings.HasPrefix(string(first), `{"$schema":`) {
+		t.Fatalf("expected sarif log, got: %s", first)
+	}
+}
+
+func TestValidateConfig_RejectsUnknownOutputFormat(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  output: { format: \"xml\" }\n}\n"
+	_, err := runValidateConfigWithContent(t, "output_format_bad_validate_test.cue", content)
+	if err == nil || err.Error() != "invalid output.format: must be 'json', 'sarif', or 'junit'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_RejectsReportFormatWithLines(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  output: { format: \"junit\", lines: true }\n}\n"
+	_, err := runValidateConfigWithContent(t, "output_format_lines_validate_test.cue", content)
+	if err == nil || err.Error() != "invalid output.lines: only supported when output.format='json'" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateConfig_ExposesOutputFormat(t *testing.T) {
+	content := "{\n  configVersion: \"" + config.CurrentConfigVersion + "\"\n  action: \"validate\"\n  output: { format: \"sarif\" }\n}\n"
+	out, err := runValidateConfigWithContent(t, "output_format_validate_test.cue", content)
+	if err != nil {
+		t.Fatalf("validate-config: %v", err)
+	}
+	if out.Meta.Output == nil || out.Meta.Output.Format != "sarif" {
+		t.Fatalf("expected output.format=sarif, got: %+v", out.Meta.Output)
+	}
+}
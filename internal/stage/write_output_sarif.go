@@ -0,0 +1,109 @@
+// File Guide for dev/ai agents:
+// Purpose: Render envelope findings as a SARIF 2.1.0 log for code-scanning dashboards.
+// Responsibilities:
+// - Map each finding to a SARIF result whose rule id is the producing stage.
+// - Locate results at the sidecar or source file when a locator is known.
+// - Emit a sorted rule table so the driver section stays byte-stable.
+// Architecture notes:
+// - The SARIF shape is built from small structs rather than maps so JSON field order is fixed by declaration.
+// - Locations are the raw locators, relative to discovery.root; the writer does not guess repository-relative paths.
+package stage
+
+import "sort"
+
+const sarifSchemaURI = "https://json.schemastore.org/sarif-2.1.0.json"
+const sarifVersion = "2.1.0"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func buildSARIFLog(env Envelope) sarifLog {
+	findings := collectReportFindings(env)
+	ruleSet := map[string]struct{}{}
+	results := make([]sarifResult, 0, len(findings))
+	for _, f := range findings {
+		ruleSet[f.ruleID] = struct{}{}
+		r := sarifResult{
+			RuleID:  f.ruleID,
+			Level:   f.level,
+			Message: sarifMessage{Text: f.message},
+		}
+		if f.uri != "" {
+			r.Locations = []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.uri},
+				},
+			}}
+		}
+		results = append(results, r)
+	}
+	ruleIDs := make([]string, 0, len(ruleSet))
+	for id := range ruleSet {
+		ruleIDs = append(ruleIDs, id)
+	}
+	sort.Strings(ruleIDs)
+	rules := make([]sarifRule, 0, len(ruleIDs))
+	for _, id := range ruleIDs {
+		rules = append(rules, sarifRule{ID: id})
+	}
+	return sarifLog{
+		Schema:  sarifSchemaURI,
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "thoth", Rules: rules}},
+			Results: results,
+		}},
+	}
+}
+
+func encodeSARIFReport(env Envelope, pretty bool) ([]byte, error) {
+	log := buildSARIFLog(env)
+	if pretty {
+		return encodeJSONPretty(log)
+	}
+	return encodeJSONCompact(log)
+}